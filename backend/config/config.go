@@ -0,0 +1,340 @@
+// Package config holds the server settings. Values come from built-in
+// defaults, then an optional JSON config file, then environment variables
+// (including those loaded from .env), and are validated before startup.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // so TimeZone resolves on hosts without zoneinfo
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// DefaultJWTSecret is only acceptable outside production.
+	DefaultJWTSecret = "default-secret"
+)
+
+// Duration is a time.Duration that reads from JSON as a string like "15m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type Config struct {
+	Env        string `json:"env"`
+	ListenAddr string `json:"listen_addr"`
+	CORSOrigin string `json:"cors_origin"`
+	JWTSecret  string `json:"jwt_secret"`
+
+	// EmailDomains may sign up without an invite; subdomains match too, so
+	// "ufl.edu" also allows "cise.ufl.edu".
+	EmailDomains []string `json:"email_domains"`
+
+	PasswordResetURL string `json:"password_reset_url"`
+	EmailVerifyURL   string `json:"email_verify_url"`
+
+	AccessTokenTTL  Duration `json:"access_token_ttl"`
+	RefreshTokenTTL Duration `json:"refresh_token_ttl"`
+
+	// TimeZone is used for sighting dates and times that carry no zone.
+	TimeZone string `json:"time_zone"`
+	// ExifMismatchMeters is how far a sighting's pin may be from the photo's
+	// GPS position before it is flagged.
+	ExifMismatchMeters float64 `json:"exif_mismatch_meters"`
+
+	DefaultPageSize     int     `json:"default_page_size"`
+	MaxPageSize         int     `json:"max_page_size"`
+	DefaultNearbyRadius float64 `json:"default_nearby_radius_m"`
+	MaxNearbyRadius     float64 `json:"max_nearby_radius_m"`
+	// MaxViewportResults caps the sightings returned for one map viewport.
+	MaxViewportResults int `json:"max_viewport_results"`
+	// MapCacheBytes is the memory budget for cached map tiles and heatmaps,
+	// counting each entry's key and bookkeeping as well as its body.
+	MapCacheBytes int `json:"map_cache_bytes"`
+
+	// StorageDriver is "local" (files under UploadDir, served from
+	// UploadBaseURL) or "s3" (any S3-compatible bucket).
+	StorageDriver  string `json:"storage_driver"`
+	MaxUploadBytes int    `json:"max_upload_bytes"`
+	// MaxMediaUploadBytes applies to audio and video uploads.
+	MaxMediaUploadBytes int    `json:"max_media_upload_bytes"`
+	ImageWorkers        int    `json:"image_workers"`
+	UploadDir           string `json:"upload_dir"`
+	UploadBaseURL       string `json:"upload_base_url"`
+	S3Endpoint          string `json:"s3_endpoint"`
+	S3Region            string `json:"s3_region"`
+	S3Bucket            string `json:"s3_bucket"`
+	S3AccessKey         string `json:"s3_access_key"`
+	S3SecretKey         string `json:"s3_secret_key"`
+	S3PublicURL         string `json:"s3_public_url"`
+
+	AllowAnonymousReads      bool     `json:"allow_anonymous_reads"`
+	RequireEmailVerification bool     `json:"require_email_verification"`
+	TrustProxyHeaders        bool     `json:"trust_proxy_headers"`
+	BootstrapAdminEmails     []string `json:"bootstrap_admin_emails"`
+}
+
+// Default returns the settings used for local development.
+func Default() Config {
+	return Config{
+		Env:        EnvDevelopment,
+		ListenAddr: ":8080",
+		CORSOrigin: "http://localhost:4200",
+		JWTSecret:  DefaultJWTSecret,
+
+		EmailDomains: []string{"ufl.edu"},
+
+		PasswordResetURL: "http://localhost:4200/reset-password",
+		EmailVerifyURL:   "http://localhost:4200/verify-email",
+
+		AccessTokenTTL:  Duration(15 * time.Minute),
+		RefreshTokenTTL: Duration(30 * 24 * time.Hour),
+
+		TimeZone:           "America/New_York",
+		ExifMismatchMeters: 500,
+
+		DefaultPageSize:     20,
+		MaxPageSize:         100,
+		DefaultNearbyRadius: 1000,
+		MaxNearbyRadius:     10000,
+		MaxViewportResults:  500,
+		MapCacheBytes:       64 << 20,
+
+		StorageDriver:       "local",
+		MaxUploadBytes:      10 << 20,
+		MaxMediaUploadBytes: 50 << 20,
+		ImageWorkers:        2,
+		UploadDir:           "uploads",
+		UploadBaseURL:       "http://localhost:8080/uploads",
+		S3Region:            "us-east-1",
+
+		AllowAnonymousReads:      true,
+		RequireEmailVerification: true,
+	}
+}
+
+// Load builds the configuration from defaults, the JSON file at path (skipped
+// when path is empty) and the environment, then validates it.
+func Load(path string) (Config, error) {
+	c := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c, fmt.Errorf("reading config file: %w", err)
+		}
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return c, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	if err := c.applyEnv(); err != nil {
+		return c, err
+	}
+	c.EmailDomains = normalizeDomains(c.EmailDomains)
+	return c, c.Validate()
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	str := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			*dst = v
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = b
+		}
+	}
+	integer := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = n
+		}
+	}
+	float := func(name string, dst *float64) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = f
+		}
+	}
+	list := func(name string, dst *[]string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = nil
+			for _, e := range strings.Split(v, ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					*dst = append(*dst, e)
+				}
+			}
+		}
+	}
+	duration := func(name string, dst *Duration) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = Duration(d)
+		}
+	}
+
+	str("APP_ENV", &c.Env)
+	str("LISTEN_ADDR", &c.ListenAddr)
+	str("CORS_ALLOWED_ORIGIN", &c.CORSOrigin)
+	str("JWT_SECRET", &c.JWTSecret)
+	str("PASSWORD_RESET_URL", &c.PasswordResetURL)
+	str("EMAIL_VERIFY_URL", &c.EmailVerifyURL)
+	duration("ACCESS_TOKEN_TTL", &c.AccessTokenTTL)
+	duration("REFRESH_TOKEN_TTL", &c.RefreshTokenTTL)
+	str("TIME_ZONE", &c.TimeZone)
+	float("EXIF_MISMATCH_METERS", &c.ExifMismatchMeters)
+	integer("DEFAULT_PAGE_SIZE", &c.DefaultPageSize)
+	integer("MAX_PAGE_SIZE", &c.MaxPageSize)
+	float("DEFAULT_NEARBY_RADIUS_M", &c.DefaultNearbyRadius)
+	float("MAX_NEARBY_RADIUS_M", &c.MaxNearbyRadius)
+	integer("MAX_VIEWPORT_RESULTS", &c.MaxViewportResults)
+	integer("MAP_CACHE_BYTES", &c.MapCacheBytes)
+	str("STORAGE_DRIVER", &c.StorageDriver)
+	integer("MAX_UPLOAD_BYTES", &c.MaxUploadBytes)
+	integer("MAX_MEDIA_UPLOAD_BYTES", &c.MaxMediaUploadBytes)
+	integer("IMAGE_WORKERS", &c.ImageWorkers)
+	str("UPLOAD_DIR", &c.UploadDir)
+	str("UPLOAD_BASE_URL", &c.UploadBaseURL)
+	str("S3_ENDPOINT", &c.S3Endpoint)
+	str("S3_REGION", &c.S3Region)
+	str("S3_BUCKET", &c.S3Bucket)
+	str("S3_ACCESS_KEY", &c.S3AccessKey)
+	str("S3_SECRET_KEY", &c.S3SecretKey)
+	str("S3_PUBLIC_URL", &c.S3PublicURL)
+	boolean("ALLOW_ANONYMOUS_READS", &c.AllowAnonymousReads)
+	boolean("REQUIRE_EMAIL_VERIFICATION", &c.RequireEmailVerification)
+	boolean("TRUST_PROXY_HEADERS", &c.TrustProxyHeaders)
+	list("ALLOWED_EMAIL_DOMAINS", &c.EmailDomains)
+	list("BOOTSTRAP_ADMIN_EMAILS", &c.BootstrapAdminEmails)
+
+	return errors.Join(errs...)
+}
+
+// normalizeDomains lower-cases the domains and strips a leading "@" so both
+// "@ufl.edu" and "UFL.edu" are accepted.
+func normalizeDomains(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		out = append(out, strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@"))
+	}
+	return out
+}
+
+// Location returns the TimeZone location, falling back to UTC.
+func (c Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("env must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr is required"))
+	}
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("jwt_secret is required"))
+	}
+	if c.Env == EnvProduction && c.JWTSecret == DefaultJWTSecret {
+		errs = append(errs, errors.New("refusing to start in production with the default JWT secret; set JWT_SECRET"))
+	}
+	if len(c.EmailDomains) == 0 {
+		errs = append(errs, errors.New("email_domains must list at least one domain"))
+	}
+	for _, d := range c.EmailDomains {
+		if !strings.Contains(d, ".") || strings.ContainsAny(d, "@ ") || strings.HasPrefix(d, ".") {
+			errs = append(errs, fmt.Errorf("email_domains entry %q must look like \"ufl.edu\"", d))
+		}
+	}
+	if c.AccessTokenTTL <= 0 || c.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("token lifetimes must be positive"))
+	} else if c.AccessTokenTTL >= c.RefreshTokenTTL {
+		errs = append(errs, errors.New("access_token_ttl must be shorter than refresh_token_ttl"))
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil || c.TimeZone == "" {
+		errs = append(errs, fmt.Errorf("time_zone %q is not a known IANA zone", c.TimeZone))
+	}
+	if c.ExifMismatchMeters <= 0 {
+		errs = append(errs, errors.New("exif_mismatch_meters must be positive"))
+	}
+	if c.DefaultPageSize <= 0 || c.MaxPageSize <= 0 || c.DefaultPageSize > c.MaxPageSize {
+		errs = append(errs, errors.New("page sizes must be positive and default_page_size must not exceed max_page_size"))
+	}
+	switch c.StorageDriver {
+	case "local":
+		if c.UploadDir == "" || c.UploadBaseURL == "" {
+			errs = append(errs, errors.New("upload_dir and upload_base_url are required for the local storage driver"))
+		}
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKey == "" || c.S3SecretKey == "" {
+			errs = append(errs, errors.New("s3_endpoint, s3_bucket, s3_access_key and s3_secret_key are required for the s3 storage driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage_driver must be \"local\" or \"s3\", got %q", c.StorageDriver))
+	}
+	if c.MaxUploadBytes <= 0 || c.MaxMediaUploadBytes <= 0 {
+		errs = append(errs, errors.New("max_upload_bytes and max_media_upload_bytes must be positive"))
+	}
+	if c.ImageWorkers < 1 {
+		errs = append(errs, errors.New("image_workers must be at least 1"))
+	}
+	if c.DefaultNearbyRadius <= 0 || c.MaxNearbyRadius <= 0 || c.DefaultNearbyRadius > c.MaxNearbyRadius {
+		errs = append(errs, errors.New("nearby radii must be positive and default_nearby_radius_m must not exceed max_nearby_radius_m"))
+	}
+	if c.MaxViewportResults < 1 {
+		errs = append(errs, errors.New("max_viewport_results must be at least 1"))
+	}
+	if c.MapCacheBytes < 1 {
+		errs = append(errs, errors.New("map_cache_bytes must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefault_IsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("expected defaults to validate, got %v", err)
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("LISTEN_ADDR", ":9090")
+	t.Setenv("ACCESS_TOKEN_TTL", "5m")
+	t.Setenv("MAX_PAGE_SIZE", "50")
+	t.Setenv("ALLOW_ANONYMOUS_READS", "false")
+	t.Setenv("BOOTSTRAP_ADMIN_EMAILS", "a@ufl.edu, ,b@ufl.edu")
+
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.ListenAddr != ":9090" || time.Duration(c.AccessTokenTTL) != 5*time.Minute || c.MaxPageSize != 50 {
+		t.Errorf("env overrides not applied: %+v", c)
+	}
+	if c.AllowAnonymousReads {
+		t.Error("expected anonymous reads to be disabled")
+	}
+	if len(c.BootstrapAdminEmails) != 2 {
+		t.Errorf("expected 2 bootstrap admins, got %v", c.BootstrapAdminEmails)
+	}
+}
+
+func TestLoad_EmailDomainsNormalized(t *testing.T) {
+	t.Setenv("ALLOWED_EMAIL_DOMAINS", "@UFL.edu, fsu.edu")
+	c, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(c.EmailDomains) != 2 || c.EmailDomains[0] != "ufl.edu" || c.EmailDomains[1] != "fsu.edu" {
+		t.Errorf("unexpected domains: %v", c.EmailDomains)
+	}
+}
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"cors_origin": "https://wildlife.example", "refresh_token_ttl": "72h", "max_nearby_radius_m": 5000}`), 0o644)
+	t.Setenv("CORS_ALLOWED_ORIGIN", "https://override.example")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.CORSOrigin != "https://override.example" {
+		t.Errorf("expected env to win over file, got %s", c.CORSOrigin)
+	}
+	if time.Duration(c.RefreshTokenTTL) != 72*time.Hour || c.MaxNearbyRadius != 5000 {
+		t.Errorf("file values not applied: %+v", c)
+	}
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"listen_adr": ":1"}`), 0o644)
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for unknown config key")
+	}
+}
+
+func TestLoad_BadEnvValue(t *testing.T) {
+	t.Setenv("DEFAULT_PAGE_SIZE", "lots")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "DEFAULT_PAGE_SIZE") {
+		t.Errorf("expected error naming DEFAULT_PAGE_SIZE, got %v", err)
+	}
+}
+
+func TestValidate_ProductionDefaultSecret(t *testing.T) {
+	c := Default()
+	c.Env = EnvProduction
+	if err := c.Validate(); err == nil {
+		t.Error("expected production with the default secret to be rejected")
+	}
+	c.JWTSecret = "a-real-secret"
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected production with a real secret to validate, got %v", err)
+	}
+}
+
+func TestValidate_Limits(t *testing.T) {
+	c := Default()
+	c.DefaultPageSize = 200
+	c.AccessTokenTTL = c.RefreshTokenTTL
+	c.EmailDomains = []string{"ufl.edu", "not a domain"}
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"default_page_size", "access_token_ttl", "email_domains"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestValidate_TimeZone(t *testing.T) {
+	c := Default()
+	if c.Location().String() != "America/New_York" {
+		t.Errorf("expected default zone America/New_York, got %s", c.Location())
+	}
+	c.TimeZone = "Mars/Olympus_Mons"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "time_zone") {
+		t.Errorf("expected unknown zone to be rejected, got %v", err)
+	}
+}
+
+func TestValidate_S3RequiresCredentials(t *testing.T) {
+	c := Default()
+	c.StorageDriver = "s3"
+	c.S3Endpoint = "http://localhost:9000"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "s3_bucket") {
+		t.Errorf("expected missing s3 settings to be reported, got %v", err)
+	}
+	c.S3Bucket, c.S3AccessKey, c.S3SecretKey = "wildlife", "key", "secret"
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected complete s3 settings to validate, got %v", err)
+	}
+}
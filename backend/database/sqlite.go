@@ -37,6 +37,8 @@ func createTables() {
 		username TEXT UNIQUE NOT NULL,
 		email TEXT UNIQUE NOT NULL,
 		password TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'user',
+		email_verified BOOLEAN NOT NULL DEFAULT FALSE,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
 
@@ -216,6 +218,248 @@ func createTables() {
 		log.Fatal("Error creating area_messages table:", err)
 	}
 
+	passwordResetsTable := `
+	CREATE TABLE IF NOT EXISTS password_resets (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	_, err = DB.Exec(passwordResetsTable)
+	if err != nil {
+		log.Fatal("Error creating password_resets table:", err)
+	}
+
+	emailVerificationsTable := `
+	CREATE TABLE IF NOT EXISTS email_verifications (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	_, err = DB.Exec(emailVerificationsTable)
+	if err != nil {
+		log.Fatal("Error creating email_verifications table:", err)
+	}
+
+	refreshTokensTable := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	_, err = DB.Exec(refreshTokensTable)
+	if err != nil {
+		log.Fatal("Error creating refresh_tokens table:", err)
+	}
+
+	authLockoutsTable := `
+	CREATE TABLE IF NOT EXISTS auth_lockouts (
+		id SERIAL PRIMARY KEY,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		failures INTEGER NOT NULL,
+		locked_until TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = DB.Exec(authLockoutsTable)
+	if err != nil {
+		log.Fatal("Error creating auth_lockouts table:", err)
+	}
+
+	institutionsTable := `
+	CREATE TABLE IF NOT EXISTS institutions (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		domain TEXT UNIQUE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = DB.Exec(institutionsTable)
+	if err != nil {
+		log.Fatal("Error creating institutions table:", err)
+	}
+
+	_, err = DB.Exec("INSERT INTO institutions (name, domain) VALUES ('University of Florida', 'ufl.edu') ON CONFLICT (domain) DO NOTHING")
+	if err != nil {
+		log.Fatal("Error seeding institutions table:", err)
+	}
+
+	inviteCodesTable := `
+	CREATE TABLE IF NOT EXISTS invite_codes (
+		id SERIAL PRIMARY KEY,
+		code_hash TEXT UNIQUE NOT NULL,
+		email TEXT,
+		institution_id INTEGER,
+		max_uses INTEGER NOT NULL DEFAULT 1,
+		uses INTEGER NOT NULL DEFAULT 0,
+		created_by INTEGER NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (institution_id) REFERENCES institutions(id),
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(inviteCodesTable)
+	if err != nil {
+		log.Fatal("Error creating invite_codes table:", err)
+	}
+
+	uploadsTable := `
+	CREATE TABLE IF NOT EXISTS uploads (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		sighting_id INTEGER,
+		storage_key TEXT UNIQUE NOT NULL,
+		url TEXT UNIQUE NOT NULL,
+		content_type TEXT NOT NULL,
+		kind TEXT NOT NULL DEFAULT 'image',
+		size_bytes INTEGER NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		duration_ms INTEGER,
+		exif_taken_at TEXT,
+		exif_offset TEXT,
+		exif_latitude DOUBLE PRECISION,
+		exif_longitude DOUBLE PRECISION,
+		variants_status TEXT NOT NULL DEFAULT 'pending',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (sighting_id) REFERENCES animals(id) ON DELETE SET NULL
+	);`
+
+	_, err = DB.Exec(uploadsTable)
+	if err != nil {
+		log.Fatal("Error creating uploads table:", err)
+	}
+
+	imageVariantsTable := `
+	CREATE TABLE IF NOT EXISTS image_variants (
+		id SERIAL PRIMARY KEY,
+		upload_id INTEGER NOT NULL,
+		variant TEXT NOT NULL,
+		format TEXT NOT NULL,
+		storage_key TEXT UNIQUE NOT NULL,
+		url TEXT NOT NULL,
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (upload_id, variant, format),
+		FOREIGN KEY (upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+	);`
+
+	_, err = DB.Exec(imageVariantsTable)
+	if err != nil {
+		log.Fatal("Error creating image_variants table:", err)
+	}
+
+	sightingMediaTable := `
+	CREATE TABLE IF NOT EXISTS sighting_media (
+		id SERIAL PRIMARY KEY,
+		sighting_id INTEGER NOT NULL,
+		upload_id INTEGER,
+		kind TEXT NOT NULL,
+		url TEXT NOT NULL,
+		mime_type TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER,
+		position INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (sighting_id, upload_id),
+		FOREIGN KEY (sighting_id) REFERENCES animals(id) ON DELETE CASCADE,
+		FOREIGN KEY (upload_id) REFERENCES uploads(id)
+	);`
+
+	_, err = DB.Exec(sightingMediaTable)
+	if err != nil {
+		log.Fatal("Error creating sighting_media table:", err)
+	}
+
+	taxaTable := `
+	CREATE TABLE IF NOT EXISTS taxa (
+		id SERIAL PRIMARY KEY,
+		scientific_name TEXT UNIQUE NOT NULL,
+		common_name TEXT NOT NULL DEFAULT '',
+		rank TEXT NOT NULL,
+		parent_id INTEGER,
+		category TEXT NOT NULL,
+		FOREIGN KEY (parent_id) REFERENCES taxa(id)
+	);`
+
+	_, err = DB.Exec(taxaTable)
+	if err != nil {
+		log.Fatal("Error creating taxa table:", err)
+	}
+
+	// Every scientific and common name a taxon answers to, with the
+	// taxonomy.Normalize form used for lookups.
+	taxonNamesTable := `
+	CREATE TABLE IF NOT EXISTS taxon_names (
+		id SERIAL PRIMARY KEY,
+		taxon_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		normalized TEXT NOT NULL,
+		UNIQUE (taxon_id, normalized),
+		FOREIGN KEY (taxon_id) REFERENCES taxa(id) ON DELETE CASCADE
+	);`
+
+	_, err = DB.Exec(taxonNamesTable)
+	if err != nil {
+		log.Fatal("Error creating taxon_names table:", err)
+	}
+
+	_, err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_taxon_names_normalized ON taxon_names (normalized)")
+	if err != nil {
+		log.Fatal("Error creating taxon_names index:", err)
+	}
+
+	categoriesTable := `
+	CREATE TABLE IF NOT EXISTS categories (
+		id SERIAL PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,
+		display_name TEXT NOT NULL,
+		icon TEXT NOT NULL DEFAULT '',
+		color TEXT NOT NULL DEFAULT '#757575',
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = DB.Exec(categoriesTable)
+	if err != nil {
+		log.Fatal("Error creating categories table:", err)
+	}
+
+	// The categories the frontend has always offered. Admins may edit them
+	// afterwards, so existing rows are left alone.
+	_, err = DB.Exec(`
+	INSERT INTO categories (name, display_name, icon, color, sort_order) VALUES
+		('Mammal', 'Mammals', '🦌', '#E53935', 10),
+		('Bird', 'Birds', '🐦', '#1E88E5', 20),
+		('Reptile', 'Reptiles', '🐊', '#43A047', 30),
+		('Amphibian', 'Amphibians', '🐸', '#8E24AA', 40),
+		('Fish', 'Fish', '🐟', '#00ACC1', 50),
+		('Insect', 'Insects', '🦋', '#FFB300', 60),
+		('Other', 'Other', '🐾', '#757575', 1000)
+	ON CONFLICT (name) DO NOTHING`)
+	if err != nil {
+		log.Fatal("Error seeding categories table:", err)
+	}
+
 	log.Println("Database tables created successfully")
 
 	// Add missing columns to existing animals table (safe to run repeatedly)
@@ -228,10 +472,82 @@ func createTables() {
 		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS date TEXT",
 		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS time TEXT",
 		"ALTER TABLE messages ADD COLUMN IF NOT EXISTS sighting_id INTEGER",
+		// Distance between the pin and the photo's EXIF position when it exceeded the limit.
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS location_mismatch_m DOUBLE PRECISION",
+		// When the animal was seen; filled from date and time on startup.
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS observed_at TIMESTAMPTZ",
+		"ALTER TABLE animals ALTER COLUMN observed_at SET DEFAULT CURRENT_TIMESTAMP",
+		"CREATE INDEX IF NOT EXISTS idx_animals_observed_at ON animals (observed_at)",
+		// Nearby and bounding-box searches filter on this before measuring distances.
+		"CREATE INDEX IF NOT EXISTS idx_animals_location ON animals USING GIST (point(longitude, latitude))",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'",
+		// Accounts created before verification existed are treated as verified.
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT TRUE",
+		"ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT FALSE",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS institution_id INTEGER REFERENCES institutions(id)",
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_id INTEGER REFERENCES invite_codes(id)",
+		// Attach existing accounts to the institution that owns their email domain.
+		`UPDATE users u SET institution_id = i.id FROM institutions i
+		 WHERE u.institution_id IS NULL
+		   AND (LOWER(u.email) LIKE '%@' || i.domain OR LOWER(u.email) LIKE '%.' || i.domain)`,
+		// Free-text species stay in animals.species; taxon_id is set when the
+		// name matches the checklist.
+		"ALTER TABLE animals ADD COLUMN IF NOT EXISTS taxon_id INTEGER REFERENCES taxa(id) ON DELETE SET NULL",
+		"ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS taxon_id INTEGER REFERENCES taxa(id) ON DELETE SET NULL",
+		// Full-text search vectors, kept up to date by Postgres.
+		`ALTER TABLE animals ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', COALESCE(species, '')), 'A') ||
+			setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+			setweight(to_tsvector('english', COALESCE(address, '')), 'C')
+		) STORED`,
+		"CREATE INDEX IF NOT EXISTS idx_animals_search ON animals USING GIN (search_vector)",
+		"ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED",
+		"CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector)",
+		"ALTER TABLE area_messages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED",
+		"CREATE INDEX IF NOT EXISTS idx_area_messages_search ON area_messages USING GIN (search_vector)",
+		"ALTER TABLE uploads ADD COLUMN IF NOT EXISTS variants_status TEXT NOT NULL DEFAULT 'pending'",
+		"ALTER TABLE uploads ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'image'",
+		"ALTER TABLE uploads ADD COLUMN IF NOT EXISTS duration_ms INTEGER",
+		// Give sightings that only have an image_url a first media item.
+		`INSERT INTO sighting_media (sighting_id, upload_id, kind, url, mime_type, position)
+		 SELECT a.id, u.id, 'image', a.image_url, COALESCE(u.content_type, ''), 0
+		 FROM animals a LEFT JOIN uploads u ON u.url = a.image_url
+		 WHERE COALESCE(a.image_url, '') <> ''
+		   AND NOT EXISTS (SELECT 1 FROM sighting_media m WHERE m.sighting_id = a.id)`,
 	}
 	for _, stmt := range alterStmts {
 		if _, err := DB.Exec(stmt); err != nil {
 			log.Printf("Warning: %s — %v", stmt, err)
 		}
 	}
+
+	if err := seedTaxa(); err != nil {
+		log.Fatal("Error seeding taxa:", err)
+	}
+
+	// Map free-text categories onto the categories table, then hold
+	// animals.category to it. Runs after seedTaxa so a sighting's taxon can
+	// supply the category when the text matches nothing.
+	categoryStmts := []string{
+		`UPDATE animals a SET category = c.name FROM categories c
+		 WHERE a.category IS DISTINCT FROM c.name
+		   AND LOWER(TRIM(a.category)) IN (LOWER(c.name), LOWER(c.display_name), LOWER(c.name) || 's')`,
+		`UPDATE animals a SET category = t.category FROM taxa t
+		 WHERE a.taxon_id = t.id
+		   AND t.category IN (SELECT name FROM categories)
+		   AND (a.category IS NULL OR a.category NOT IN (SELECT name FROM categories))`,
+		"UPDATE animals SET category = 'Other' WHERE category IS NULL OR category NOT IN (SELECT name FROM categories)",
+		"ALTER TABLE animals ALTER COLUMN category SET DEFAULT 'Other'",
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'animals_category_fkey') THEN
+				ALTER TABLE animals ADD CONSTRAINT animals_category_fkey
+					FOREIGN KEY (category) REFERENCES categories(name) ON UPDATE CASCADE;
+			END IF;
+		END $$`,
+	}
+	for _, stmt := range categoryStmts {
+		if _, err := DB.Exec(stmt); err != nil {
+			log.Printf("Warning: %s — %v", stmt, err)
+		}
+	}
 }
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"parkinGator-backend/taxonomy"
+)
+
+// seedTaxa loads the bundled checklist into taxa and taxon_names, then links
+// existing sightings and species subscriptions whose free-text name matches
+// exactly one taxon.
+func seedTaxa() error {
+	checklist, err := taxonomy.Checklist()
+	if err != nil {
+		return err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make(map[string]int, len(checklist))
+	for _, t := range checklist {
+		var parentID sql.NullInt64
+		if t.Parent != "" {
+			parentID = sql.NullInt64{Int64: int64(ids[t.Parent]), Valid: true}
+		}
+		var id int
+		err := tx.QueryRow(`
+			INSERT INTO taxa (scientific_name, common_name, rank, parent_id, category)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (scientific_name) DO UPDATE
+			SET common_name = EXCLUDED.common_name, rank = EXCLUDED.rank,
+			    parent_id = EXCLUDED.parent_id, category = EXCLUDED.category
+			RETURNING id`,
+			t.ScientificName, t.CommonName(), t.Rank, parentID, t.Category,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("inserting %s: %w", t.ScientificName, err)
+		}
+		ids[t.ScientificName] = id
+
+		for _, name := range append([]string{t.ScientificName}, t.CommonNames...) {
+			if _, err := tx.Exec(
+				"INSERT INTO taxon_names (taxon_id, name, normalized) VALUES ($1, $2, $3) ON CONFLICT (taxon_id, normalized) DO NOTHING",
+				id, name, taxonomy.Normalize(name),
+			); err != nil {
+				return fmt.Errorf("inserting name %s: %w", name, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return linkTaxa()
+}
+
+// linkTaxa sets taxon_id on sightings and species subscriptions that were
+// saved before their name could be matched.
+func linkTaxa() error {
+	rows, err := DB.Query("SELECT normalized, taxon_id FROM taxon_names")
+	if err != nil {
+		return err
+	}
+	byName := map[string]int{}
+	for rows.Next() {
+		var name string
+		var id int
+		if err := rows.Scan(&name, &id); err != nil {
+			rows.Close()
+			return err
+		}
+		// A name shared by several taxa is ambiguous and links to none.
+		if prev, ok := byName[name]; ok && prev != id {
+			byName[name] = 0
+			continue
+		}
+		byName[name] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	link := func(query, update string) error {
+		rows, err := DB.Query(query)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err == nil {
+				names = append(names, name)
+			}
+		}
+		rows.Close()
+		for _, name := range names {
+			if id := byName[taxonomy.Normalize(name)]; id != 0 {
+				if _, err := DB.Exec(update, id, name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := link(
+		"SELECT DISTINCT species FROM animals WHERE taxon_id IS NULL AND species <> ''",
+		"UPDATE animals SET taxon_id = $1 WHERE taxon_id IS NULL AND species = $2",
+	); err != nil {
+		return fmt.Errorf("linking sightings: %w", err)
+	}
+	if err := link(
+		"SELECT DISTINCT value FROM subscriptions WHERE type = 'species' AND taxon_id IS NULL",
+		"UPDATE subscriptions SET taxon_id = $1 WHERE type = 'species' AND taxon_id IS NULL AND value = $2",
+	); err != nil {
+		return fmt.Errorf("linking subscriptions: %w", err)
+	}
+	return nil
+}
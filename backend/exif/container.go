@@ -0,0 +1,284 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+)
+
+func isJPEG(b []byte) bool { return len(b) > 3 && b[0] == 0xFF && b[1] == 0xD8 }
+func isPNG(b []byte) bool  { return bytes.HasPrefix(b, pngSignature) }
+func isWebP(b []byte) bool {
+	return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == "WEBP"
+}
+
+// Strip removes EXIF, XMP, IPTC and text metadata (including any GPS
+// position) from a JPEG, PNG or WebP file. A non-default orientation is
+// written back so the image still displays correctly.
+func Strip(data []byte) ([]byte, error) {
+	info, _ := Read(data)
+	orientation := 0
+	if info.Orientation > 1 && info.Orientation <= 8 {
+		orientation = info.Orientation
+	}
+
+	switch {
+	case isJPEG(data):
+		return stripJPEG(data, orientation)
+	case isPNG(data):
+		return stripPNG(data, orientation)
+	case isWebP(data):
+		return stripWebP(data, orientation)
+	}
+	return nil, ErrFormat
+}
+
+// ---------- JPEG ----------
+
+type jpegSegment struct {
+	marker  byte
+	payload []byte // without the length field
+}
+
+// jpegSegments returns the segments before the scan data and the offset of
+// the start-of-scan marker.
+func jpegSegments(data []byte) ([]jpegSegment, int, error) {
+	var segs []jpegSegment
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, 0, ErrMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return segs, i, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return nil, 0, ErrMalformed
+		}
+		segs = append(segs, jpegSegment{marker: marker, payload: data[i+4 : i+2+n]})
+		i += 2 + n
+	}
+}
+
+func jpegExif(data []byte) ([]byte, error) {
+	segs, _, err := jpegSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range segs {
+		if s.marker == 0xE1 && bytes.HasPrefix(s.payload, exifHeader) {
+			return s.payload[len(exifHeader):], nil
+		}
+	}
+	return nil, nil
+}
+
+func stripJPEG(data []byte, orientation int) ([]byte, error) {
+	segs, sos, err := jpegSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:2])
+	inserted := orientation == 0
+	for _, s := range segs {
+		// APP1 (EXIF/XMP), APP13 (IPTC) and comments may carry personal data.
+		if s.marker == 0xE1 || s.marker == 0xED || s.marker == 0xFE {
+			continue
+		}
+		if !inserted && s.marker != 0xE0 {
+			writeJPEGSegment(&out, 0xE1, append(append([]byte{}, exifHeader...), orientationTIFF(orientation)...))
+			inserted = true
+		}
+		writeJPEGSegment(&out, s.marker, s.payload)
+	}
+	if !inserted {
+		writeJPEGSegment(&out, 0xE1, append(append([]byte{}, exifHeader...), orientationTIFF(orientation)...))
+	}
+	out.Write(data[sos:])
+	return out.Bytes(), nil
+}
+
+func writeJPEGSegment(w *bytes.Buffer, marker byte, payload []byte) {
+	w.Write([]byte{0xFF, marker})
+	binary.Write(w, binary.BigEndian, uint16(len(payload)+2))
+	w.Write(payload)
+}
+
+// ---------- PNG ----------
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func pngChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+12 > len(data) {
+			return nil, ErrMalformed
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		if n < 0 || i+12+n > len(data) {
+			return nil, ErrMalformed
+		}
+		c := pngChunk{typ: string(data[i+4 : i+8]), data: data[i+8 : i+8+n]}
+		chunks = append(chunks, c)
+		i += 12 + n
+		if c.typ == "IEND" {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+func pngExif(data []byte) ([]byte, error) {
+	chunks, err := pngChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		if c.typ == "eXIf" {
+			return bytes.TrimPrefix(c.data, exifHeader), nil
+		}
+	}
+	return nil, nil
+}
+
+func stripPNG(data []byte, orientation int) ([]byte, error) {
+	chunks, err := pngChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	for _, c := range chunks {
+		switch c.typ {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+			continue
+		}
+		writePNGChunk(&out, c.typ, c.data)
+		if c.typ == "IHDR" && orientation != 0 {
+			writePNGChunk(&out, "eXIf", orientationTIFF(orientation))
+		}
+	}
+	return out.Bytes(), nil
+}
+
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	w.WriteString(typ)
+	w.Write(data)
+	binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+}
+
+// ---------- WebP ----------
+
+type riffChunk struct {
+	fourcc string
+	data   []byte
+}
+
+func webpChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if n < 0 || i+8+n > len(data) {
+			return nil, ErrMalformed
+		}
+		chunks = append(chunks, riffChunk{fourcc: string(data[i : i+4]), data: data[i+8 : i+8+n]})
+		i += 8 + n + n%2
+	}
+	return chunks, nil
+}
+
+func webpExif(data []byte) ([]byte, error) {
+	chunks, err := webpChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		if c.fourcc == "EXIF" {
+			return bytes.TrimPrefix(c.data, exifHeader), nil
+		}
+	}
+	return nil, nil
+}
+
+// VP8X feature flags.
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+func stripWebP(data []byte, orientation int) ([]byte, error) {
+	chunks, err := webpChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only extended (VP8X) files can carry metadata.
+	hasVP8X := len(chunks) > 0 && chunks[0].fourcc == "VP8X" && len(chunks[0].data) >= 10
+	if !hasVP8X {
+		return data, nil
+	}
+
+	var body bytes.Buffer
+	for _, c := range chunks {
+		switch c.fourcc {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			flags := append([]byte{}, c.data...)
+			flags[0] &^= webpFlagXMP | webpFlagEXIF
+			if orientation != 0 {
+				flags[0] |= webpFlagEXIF
+			}
+			writeRIFFChunk(&body, c.fourcc, flags)
+			continue
+		}
+		writeRIFFChunk(&body, c.fourcc, c.data)
+	}
+	// EXIF belongs after the image data.
+	if orientation != 0 {
+		writeRIFFChunk(&body, "EXIF", orientationTIFF(orientation))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(4+body.Len()))
+	out.WriteString("WEBP")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+func writeRIFFChunk(w *bytes.Buffer, fourcc string, data []byte) {
+	w.WriteString(fourcc)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
@@ -0,0 +1,247 @@
+// Package exif reads the few EXIF fields the app cares about (orientation,
+// capture time and GPS position) from JPEG, PNG and WebP files, and strips
+// metadata from those files before they are stored.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoExif    = errors.New("exif: no metadata")
+	ErrMalformed = errors.New("exif: malformed data")
+	ErrFormat    = errors.New("exif: unsupported image format")
+)
+
+// Info holds the metadata read from a photo.
+type Info struct {
+	Orientation int // 1-8; 0 when absent
+
+	// DateTimeOriginal is the capture time as written by the camera
+	// ("2006:01:02 15:04:05"), in the camera's local time.
+	DateTimeOriginal string
+	// OffsetTimeOriginal is the UTC offset of DateTimeOriginal ("-04:00"), if recorded.
+	OffsetTimeOriginal string
+
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+}
+
+// TakenAt parses DateTimeOriginal, using OffsetTimeOriginal when present and
+// loc otherwise.
+func (i Info) TakenAt(loc *time.Location) (time.Time, bool) {
+	if i.DateTimeOriginal == "" {
+		return time.Time{}, false
+	}
+	if i.OffsetTimeOriginal != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", i.DateTimeOriginal+i.OffsetTimeOriginal); err == nil {
+			return t, true
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", i.DateTimeOriginal, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Read extracts Info from a JPEG, PNG or WebP file.
+func Read(data []byte) (Info, error) {
+	var tiff []byte
+	var err error
+	switch {
+	case isJPEG(data):
+		tiff, err = jpegExif(data)
+	case isPNG(data):
+		tiff, err = pngExif(data)
+	case isWebP(data):
+		tiff, err = webpExif(data)
+	default:
+		return Info{}, ErrFormat
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	if tiff == nil {
+		return Info{}, ErrNoExif
+	}
+	return parseTIFF(tiff)
+}
+
+// Tags read from the TIFF structure.
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagGPSLatitudeRef     = 0x0001
+	tagGPSLatitude        = 0x0002
+	tagGPSLongitudeRef    = 0x0003
+	tagGPSLongitude       = 0x0004
+)
+
+// typeSizes maps TIFF field types to their size in bytes.
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+type entry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(b []byte) (Info, error) {
+	if len(b) < 8 {
+		return Info{}, ErrMalformed
+	}
+	t := tiffReader{data: b}
+	switch string(b[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return Info{}, ErrMalformed
+	}
+	if t.order.Uint16(b[2:]) != 42 {
+		return Info{}, ErrMalformed
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(b[4:]))
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	if e, ok := ifd0[tagOrientation]; ok {
+		info.Orientation = int(t.uint(e))
+	}
+
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if sub, err := t.readIFD(uint32(t.uint(e))); err == nil {
+			info.DateTimeOriginal = t.ascii(sub[tagDateTimeOriginal])
+			info.OffsetTimeOriginal = t.ascii(sub[tagOffsetTimeOriginal])
+		}
+	}
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(uint32(t.uint(e))); err == nil {
+			lat, okLat := t.degrees(gps[tagGPSLatitude])
+			lng, okLng := t.degrees(gps[tagGPSLongitude])
+			if okLat && okLng && lat <= 90 && lng <= 180 {
+				if strings.EqualFold(t.ascii(gps[tagGPSLatitudeRef]), "S") {
+					lat = -lat
+				}
+				if strings.EqualFold(t.ascii(gps[tagGPSLongitudeRef]), "W") {
+					lng = -lng
+				}
+				info.HasGPS = true
+				info.Latitude = lat
+				info.Longitude = lng
+			}
+		}
+	}
+
+	return info, nil
+}
+
+func (t tiffReader) readIFD(offset uint32) (map[uint16]entry, error) {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil, ErrMalformed
+	}
+	n := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+n*12 > len(t.data) {
+		return nil, ErrMalformed
+	}
+
+	entries := make(map[uint16]entry, n)
+	for i := 0; i < n; i++ {
+		raw := t.data[start+i*12 : start+i*12+12]
+		tag := t.order.Uint16(raw)
+		typ := t.order.Uint16(raw[2:])
+		count := t.order.Uint32(raw[4:])
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		total := int64(size) * int64(count)
+		var value []byte
+		if total <= 4 {
+			value = raw[8 : 8+total]
+		} else {
+			off := int64(t.order.Uint32(raw[8:]))
+			if off+total > int64(len(t.data)) {
+				continue
+			}
+			value = t.data[off : off+total]
+		}
+		entries[tag] = entry{typ: typ, count: count, value: value}
+	}
+	return entries, nil
+}
+
+// uint returns the first value of a SHORT or LONG entry.
+func (t tiffReader) uint(e entry) uint32 {
+	switch {
+	case e.typ == 3 && len(e.value) >= 2:
+		return uint32(t.order.Uint16(e.value))
+	case (e.typ == 4 || e.typ == 9) && len(e.value) >= 4:
+		return t.order.Uint32(e.value)
+	}
+	return 0
+}
+
+func (t tiffReader) ascii(e entry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
+
+// degrees converts a GPS degrees/minutes/seconds triple of RATIONALs.
+func (t tiffReader) degrees(e entry) (float64, bool) {
+	if e.typ != 5 || e.count < 3 || len(e.value) < 24 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num := t.order.Uint32(e.value[i*8:])
+		den := t.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	v := parts[0] + parts[1]/60 + parts[2]/3600
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// orientationTIFF builds a minimal big-endian TIFF block holding only the
+// Orientation tag, so stripped photos still display the right way up.
+func orientationTIFF(orientation int) []byte {
+	var b bytes.Buffer
+	b.WriteString("MM\x00\x2a\x00\x00\x00\x08") // header, IFD0 at offset 8
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	binary.Write(&b, binary.BigEndian, uint16(tagOrientation))
+	binary.Write(&b, binary.BigEndian, uint16(3))
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, uint16(orientation))
+	binary.Write(&b, binary.BigEndian, uint16(0))
+	binary.Write(&b, binary.BigEndian, uint32(0)) // no next IFD
+	return b.Bytes()
+}
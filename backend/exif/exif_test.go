@@ -0,0 +1,235 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// buildTIFF returns a little-endian TIFF block with orientation 6, a capture
+// time of 2026:03:14 09:26:53 -04:00 and GPS 29°38'37"N 82°21'18"W.
+func buildTIFF() []byte {
+	le := binary.LittleEndian
+	b := make([]byte, 210)
+	copy(b, "II")
+	le.PutUint16(b[2:], 42)
+	le.PutUint32(b[4:], 8)
+
+	put := func(at int, tag, typ uint16, count, value uint32) {
+		le.PutUint16(b[at:], tag)
+		le.PutUint16(b[at+2:], typ)
+		le.PutUint32(b[at+4:], count)
+		le.PutUint32(b[at+8:], value)
+	}
+
+	// IFD0 at 8
+	le.PutUint16(b[8:], 3)
+	put(10, tagOrientation, 3, 1, 6)
+	put(22, tagExifIFD, 4, 1, 50)
+	put(34, tagGPSIFD, 4, 1, 80)
+
+	// Exif IFD at 50
+	le.PutUint16(b[50:], 2)
+	put(52, tagDateTimeOriginal, 2, 20, 134)
+	put(64, tagOffsetTimeOriginal, 2, 7, 154)
+
+	// GPS IFD at 80
+	le.PutUint16(b[80:], 4)
+	put(82, tagGPSLatitudeRef, 2, 2, uint32('N'))
+	put(94, tagGPSLatitude, 5, 3, 162)
+	put(106, tagGPSLongitudeRef, 2, 2, uint32('W'))
+	put(118, tagGPSLongitude, 5, 3, 186)
+
+	copy(b[134:], "2026:03:14 09:26:53\x00")
+	copy(b[154:], "-04:00\x00")
+	for i, v := range []uint32{29, 1, 38, 1, 37, 1, 82, 1, 21, 1, 18, 1} {
+		le.PutUint32(b[162+i*4:], v)
+	}
+	return b
+}
+
+func testJPEG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+
+	var out bytes.Buffer
+	out.Write(plain[:2])
+	writeJPEGSegment(&out, 0xE1, append(append([]byte{}, exifHeader...), tiff...))
+	writeJPEGSegment(&out, 0xFE, []byte("shot at my house"))
+	out.Write(plain[2:])
+	return out.Bytes()
+}
+
+func testPNG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	for _, c := range chunks {
+		writePNGChunk(&out, c.typ, c.data)
+		if c.typ == "IHDR" {
+			writePNGChunk(&out, "eXIf", tiff)
+			writePNGChunk(&out, "tEXt", []byte("Comment\x00secret"))
+		}
+	}
+	return out.Bytes()
+}
+
+func checkInfo(t *testing.T, info Info) {
+	t.Helper()
+	if info.Orientation != 6 {
+		t.Errorf("expected orientation 6, got %d", info.Orientation)
+	}
+	if info.DateTimeOriginal != "2026:03:14 09:26:53" || info.OffsetTimeOriginal != "-04:00" {
+		t.Errorf("unexpected capture time: %q %q", info.DateTimeOriginal, info.OffsetTimeOriginal)
+	}
+	if !info.HasGPS || math.Abs(info.Latitude-29.64361) > 1e-4 || math.Abs(info.Longitude+82.355) > 1e-4 {
+		t.Errorf("unexpected GPS: %v %f %f", info.HasGPS, info.Latitude, info.Longitude)
+	}
+}
+
+func TestRead_JPEG(t *testing.T) {
+	info, err := Read(testJPEG(t, buildTIFF()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	checkInfo(t, info)
+}
+
+func TestRead_PNG(t *testing.T) {
+	info, err := Read(testPNG(t, buildTIFF()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	checkInfo(t, info)
+}
+
+func TestRead_NoExif(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+	if _, err := Read(buf.Bytes()); err != ErrNoExif {
+		t.Errorf("expected ErrNoExif, got %v", err)
+	}
+	if _, err := Read([]byte("GIF89a")); err != ErrFormat {
+		t.Errorf("expected ErrFormat, got %v", err)
+	}
+}
+
+func TestRead_TruncatedTIFF(t *testing.T) {
+	tiff := buildTIFF()[:60]
+	if _, err := Read(testJPEG(t, tiff)); err != nil && err != ErrMalformed {
+		t.Errorf("expected truncated data to be handled, got %v", err)
+	}
+}
+
+func TestStrip_JPEG(t *testing.T) {
+	stripped, err := Strip(testJPEG(t, buildTIFF()))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	info, err := Read(stripped)
+	if err != nil {
+		t.Fatalf("expected orientation to be kept, got %v", err)
+	}
+	if info.Orientation != 6 || info.HasGPS || info.DateTimeOriginal != "" {
+		t.Errorf("expected only orientation to survive, got %+v", info)
+	}
+	if bytes.Contains(stripped, []byte("my house")) {
+		t.Error("expected comment to be removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped JPEG does not decode: %v", err)
+	}
+}
+
+func TestStrip_PNG(t *testing.T) {
+	tiff := buildTIFF()
+	binary.LittleEndian.PutUint32(tiff[18:], 1) // orientation 1: nothing to keep
+	stripped, err := Strip(testPNG(t, tiff))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := Read(stripped); err != ErrNoExif {
+		t.Errorf("expected all metadata removed, got %v", err)
+	}
+	if bytes.Contains(stripped, []byte("secret")) {
+		t.Error("expected text chunk to be removed")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped PNG does not decode: %v", err)
+	}
+}
+
+func TestStrip_WebP(t *testing.T) {
+	var body bytes.Buffer
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagEXIF | webpFlagXMP
+	writeRIFFChunk(&body, "VP8X", vp8x)
+	writeRIFFChunk(&body, "VP8L", []byte{0x2f, 1, 2, 3, 4})
+	writeRIFFChunk(&body, "EXIF", buildTIFF())
+	writeRIFFChunk(&body, "XMP ", []byte("<x:xmpmeta/>"))
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+body.Len()))
+	file.WriteString("WEBP")
+	file.Write(body.Bytes())
+
+	info, err := Read(file.Bytes())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	checkInfo(t, info)
+
+	stripped, err := Strip(file.Bytes())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if int(binary.LittleEndian.Uint32(stripped[4:])) != len(stripped)-8 {
+		t.Error("expected RIFF size to match the new length")
+	}
+	if bytes.Contains(stripped, []byte("xmpmeta")) {
+		t.Error("expected XMP to be removed")
+	}
+	info, _ = Read(stripped)
+	if info.Orientation != 6 || info.HasGPS {
+		t.Errorf("expected only orientation to survive, got %+v", info)
+	}
+	if stripped[20]&webpFlagXMP != 0 || stripped[20]&webpFlagEXIF == 0 {
+		t.Errorf("unexpected VP8X flags %08b", stripped[20])
+	}
+}
+
+func TestInfo_TakenAt(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	info := Info{DateTimeOriginal: "2026:03:14 09:26:53", OffsetTimeOriginal: "-04:00"}
+	got, ok := info.TakenAt(ny)
+	if !ok || !got.Equal(time.Date(2026, 3, 14, 13, 26, 53, 0, time.UTC)) {
+		t.Errorf("unexpected time with offset: %v", got)
+	}
+
+	info.OffsetTimeOriginal = ""
+	got, ok = info.TakenAt(ny)
+	if !ok || got.Location() != ny || got.Hour() != 9 {
+		t.Errorf("expected local time in the fallback zone, got %v", got)
+	}
+
+	if _, ok := (Info{}).TakenAt(ny); ok {
+		t.Error("expected no time when DateTimeOriginal is missing")
+	}
+}
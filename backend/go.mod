@@ -3,9 +3,11 @@ module parkinGator-backend
 go 1.25.6
 
 require (
+	github.com/chai2010/webp v1.4.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/jackc/pgx/v5 v5.8.0
 	golang.org/x/crypto v0.31.0
+	golang.org/x/image v0.31.0
 )
 
 require (
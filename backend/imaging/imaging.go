@@ -0,0 +1,141 @@
+// Package imaging builds the resized variants served for sighting photos.
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Variant is a named size, bounded by the length of its longest edge.
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+// DefaultVariants are generated for every uploaded photo.
+var DefaultVariants = []Variant{
+	{Name: "thumb", MaxSize: 320},
+	{Name: "medium", MaxSize: 800},
+	{Name: "full", MaxSize: 1600},
+}
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// Output is one encoded variant.
+type Output struct {
+	Variant     string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+const (
+	jpegQuality = 82
+	webpQuality = 80
+)
+
+// Generate decodes data, applies the EXIF orientation (1-8, 0 for none) and
+// encodes every variant as JPEG, plus WebP when WebPSupported. Images are
+// never upscaled, so small photos give variants at their original size.
+func Generate(data []byte, orientation int, variants []Variant) ([]Output, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	src = Orient(src, orientation)
+
+	var outs []Output
+	for _, v := range variants {
+		img := Fit(src, v.MaxSize)
+		b := img.Bounds()
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		outs = append(outs, Output{
+			Variant: v.Name, Format: FormatJPEG, ContentType: "image/jpeg",
+			Width: b.Dx(), Height: b.Dy(), Data: buf.Bytes(),
+		})
+
+		if WebPSupported {
+			webpData, err := encodeWebP(img, webpQuality)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, Output{
+				Variant: v.Name, Format: FormatWebP, ContentType: "image/webp",
+				Width: b.Dx(), Height: b.Dy(), Data: webpData,
+			})
+		}
+	}
+	return outs, nil
+}
+
+// Fit scales src down so its longest edge is at most maxSize.
+func Fit(src image.Image, maxSize int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return src
+	}
+	if w >= h {
+		h = max(1, h*maxSize/w)
+		w = maxSize
+	} else {
+		w = max(1, w*maxSize/h)
+		h = maxSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// Orient returns src transformed so an image with the given EXIF
+// orientation displays upright without the tag.
+func Orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5-8 swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirror horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,92 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255}) // top-left marker
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFit(t *testing.T) {
+	cases := []struct{ w, h, max, wantW, wantH int }{
+		{2000, 1000, 800, 800, 400},
+		{1000, 2000, 800, 400, 800},
+		{300, 200, 800, 300, 200}, // never upscaled
+		{5000, 1, 100, 100, 1},
+	}
+	for _, c := range cases {
+		b := Fit(testImage(c.w, c.h), c.max).Bounds()
+		if b.Dx() != c.wantW || b.Dy() != c.wantH {
+			t.Errorf("Fit(%dx%d, %d) = %dx%d, want %dx%d", c.w, c.h, c.max, b.Dx(), b.Dy(), c.wantW, c.wantH)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(4, 2)
+
+	rotated := Orient(src, 6)
+	if b := rotated.Bounds(); b.Dx() != 2 || b.Dy() != 4 {
+		t.Fatalf("expected 2x4 after rotating, got %dx%d", b.Dx(), b.Dy())
+	}
+	// Rotating 90° clockwise moves the top-left pixel to the top-right.
+	if r, _, _, _ := rotated.At(1, 0).RGBA(); r == 0 {
+		t.Error("expected marker at top-right after orientation 6")
+	}
+
+	if r, _, _, _ := Orient(src, 3).At(3, 1).RGBA(); r == 0 {
+		t.Error("expected marker at bottom-right after orientation 3")
+	}
+	if Orient(src, 1) != src {
+		t.Error("expected orientation 1 to return the source unchanged")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	outs, err := Generate(encodePNG(t, testImage(1200, 900)), 0, DefaultVariants)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	perVariant := 1
+	if WebPSupported {
+		perVariant = 2
+	}
+	if len(outs) != len(DefaultVariants)*perVariant {
+		t.Fatalf("expected %d outputs, got %d", len(DefaultVariants)*perVariant, len(outs))
+	}
+
+	sizes := map[string]int{}
+	for _, o := range outs {
+		if got := http.DetectContentType(o.Data); got != o.ContentType {
+			t.Errorf("%s/%s: data sniffs as %s, want %s", o.Variant, o.Format, got, o.ContentType)
+		}
+		sizes[o.Variant] = o.Width
+	}
+	if sizes["thumb"] != 320 || sizes["medium"] != 800 || sizes["full"] != 1200 {
+		t.Errorf("unexpected variant widths: %v", sizes)
+	}
+}
+
+func TestGenerate_InvalidImage(t *testing.T) {
+	if _, err := Generate([]byte("not an image"), 0, DefaultVariants); err == nil {
+		t.Error("expected error for undecodable data")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build cgo
+
+package imaging
+
+import (
+	"image"
+
+	"github.com/chai2010/webp"
+)
+
+// WebPSupported reports whether WebP variants can be encoded. Encoding uses
+// libwebp, so it needs a cgo build.
+const WebPSupported = true
+
+func encodeWebP(img image.Image, quality float32) ([]byte, error) {
+	return webp.EncodeRGBA(img, quality)
+}
@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package imaging
+
+import (
+	"errors"
+	"image"
+)
+
+// WebPSupported reports whether WebP variants can be encoded. Encoding uses
+// libwebp, so it needs a cgo build.
+const WebPSupported = false
+
+func encodeWebP(img image.Image, quality float32) ([]byte, error) {
+	return nil, errors.New("imaging: WebP encoding requires cgo")
+}
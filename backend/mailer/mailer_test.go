@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutboxMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	m := &OutboxMailer{Dir: dir}
+	if err := m.Send(Message{To: "gator@ufl.edu", Subject: "Hello", Body: "Chomp"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 outbox file, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "Subject: Hello") || !strings.Contains(string(data), "Chomp") {
+		t.Errorf("unexpected outbox content: %s", data)
+	}
+}
+
+func TestOutboxMailer_SanitizesFileName(t *testing.T) {
+	dir := t.TempDir()
+	m := &OutboxMailer{Dir: dir}
+	if err := m.Send(Message{To: "../../evil@ufl.edu", Subject: "x", Body: "y"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if len(files) != 1 {
+		t.Errorf("expected message to stay inside the outbox directory, got %v", files)
+	}
+}
+
+func TestFromEnv_DefaultsToOutbox(t *testing.T) {
+	os.Unsetenv("MAIL_DRIVER")
+	if _, ok := FromEnv().(*OutboxMailer); !ok {
+		t.Error("expected outbox mailer by default")
+	}
+}
+
+func TestFromEnv_SMTP(t *testing.T) {
+	os.Setenv("MAIL_DRIVER", "smtp")
+	defer os.Unsetenv("MAIL_DRIVER")
+	m, ok := FromEnv().(*SMTPMailer)
+	if !ok {
+		t.Fatal("expected SMTP mailer")
+	}
+	if m.Port != "587" {
+		t.Errorf("expected default port 587, got %s", m.Port)
+	}
+}
+
+func TestRecorder_Last(t *testing.T) {
+	m := &Recorder{}
+	if _, ok := m.Last(); ok {
+		t.Error("expected no message before Send")
+	}
+	m.Send(Message{To: "a@ufl.edu"})
+	m.Send(Message{To: "b@ufl.edu"})
+	if msg, _ := m.Last(); msg.To != "b@ufl.edu" {
+		t.Errorf("expected last message to b@ufl.edu, got %s", msg.To)
+	}
+}
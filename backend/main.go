@@ -2,22 +2,58 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"parkinGator-backend/config"
 	"parkinGator-backend/database"
+	"parkinGator-backend/exif"
+	"parkinGator-backend/imaging"
+	"parkinGator-backend/mailer"
+	"parkinGator-backend/mapcache"
 	"parkinGator-backend/models"
+	"parkinGator-backend/mvt"
+	"parkinGator-backend/probe"
+	"parkinGator-backend/ratelimit"
+	"parkinGator-backend/storage"
+	"parkinGator-backend/taxonomy"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	_ "golang.org/x/image/webp"
 )
 
+// cfg holds the server settings. main replaces it with config.Load's result;
+// the defaults keep handlers usable in tests.
+var cfg = config.Default()
+
 func loadEnv(filename string) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -41,7 +77,7 @@ func loadEnv(filename string) {
 // corsMiddleware wraps a handler with CORS headers for the Angular frontend.
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:4200")
+		w.Header().Set("Access-Control-Allow-Origin", cfg.CORSOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
@@ -54,20 +90,184 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func generateJWT(userID int, email string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default-secret"
+type contextKey string
+
+const authUserKey contextKey = "authUser"
+
+// authUser is the identity carried by a verified JWT.
+type authUser struct {
+	ID    int
+	Email string
+	Role  string
+}
+
+// authMiddleware validates the "Authorization: Bearer" token and stores the
+// authenticated user in the request context. Requests without a token are
+// rejected unless they are read-only and anonymous reads are allowed.
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			if r.Method != http.MethodGet || !cfg.AllowAnonymousReads {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		tokenStr, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authorization header must use the Bearer scheme"})
+			return
+		}
+
+		user, err := parseJWT(strings.TrimSpace(tokenStr))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), authUserKey, user)))
 	}
+}
+
+func jwtSecret() []byte {
+	return []byte(cfg.JWTSecret)
+}
 
+// accessTokenTTL is kept short because access tokens cannot be revoked;
+// clients renew them with a refresh token (see handleRefreshToken).
+func accessTokenTTL() time.Duration {
+	return time.Duration(cfg.AccessTokenTTL)
+}
+
+func generateJWT(userID int, email, role string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"role":    role,
+		"exp":     time.Now().Add(accessTokenTTL()).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString(jwtSecret())
+}
+
+// parseJWT verifies a token produced by generateJWT and returns its user.
+func parseJWT(tokenStr string) (authUser, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
+		return jwtSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return authUser{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return authUser{}, errors.New("unexpected claims type")
+	}
+	id, ok := claims["user_id"].(float64)
+	if !ok || id <= 0 {
+		return authUser{}, errors.New("token is missing user_id")
+	}
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+	if !validRole(role) {
+		role = models.RoleUser
+	}
+
+	return authUser{ID: int(id), Email: email, Role: role}, nil
+}
+
+// currentUser returns the user attached to the request by authMiddleware.
+func currentUser(r *http.Request) (authUser, bool) {
+	user, ok := r.Context().Value(authUserKey).(authUser)
+	return user, ok
+}
+
+// actingUserID resolves the user a request acts as. The identity always comes
+// from the token; a client-supplied ID (0 when absent) must match it. It writes
+// a 401 or 403 response and returns false when the request may not proceed.
+func actingUserID(w http.ResponseWriter, r *http.Request, claimedID int) (int, bool) {
+	user, ok := currentUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return 0, false
+	}
+	if claimedID != 0 && claimedID != user.ID {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "User ID does not match the authenticated user"})
+		return 0, false
+	}
+	return user.ID, true
+}
+
+func validRole(role string) bool {
+	return role == models.RoleUser || role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// storedRole reads the user's role from users.role. It is a variable so
+// tests can run without a database.
+var storedRole = func(userID int) (string, error) {
+	var role string
+	err := database.DB.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	return role, err
+}
+
+// hasRole reports whether the user holds one of the given roles. A token
+// that doesn't claim one is refused outright; otherwise the role is read
+// again from the database, so a demotion takes effect immediately instead
+// of when the access token expires.
+func hasRole(user authUser, roles ...string) bool {
+	if !slices.Contains(roles, user.Role) {
+		return false
+	}
+	role, err := storedRole(user.ID)
+	return err == nil && slices.Contains(roles, role)
+}
+
+// requireRole allows the request only when the authenticated user holds one of
+// the given roles, and otherwise writes a 401 or 403.
+func requireRole(w http.ResponseWriter, r *http.Request, roles ...string) bool {
+	user, ok := currentUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return false
+	}
+	if hasRole(user, roles...) {
+		return true
+	}
+	writeJSON(w, http.StatusForbidden, map[string]string{"error": "Insufficient permissions"})
+	return false
+}
+
+// authorizeOwner allows the request when the authenticated user owns the
+// resource or is a moderator/admin, and otherwise writes a 401 or 403.
+func authorizeOwner(w http.ResponseWriter, r *http.Request, ownerID int) bool {
+	user, ok := currentUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return false
+	}
+	if user.ID == ownerID || hasRole(user, models.RoleModerator, models.RoleAdmin) {
+		return true
+	}
+	writeJSON(w, http.StatusForbidden, map[string]string{"error": "You do not have permission to modify this resource"})
+	return false
+}
+
+// queryActingUserID is actingUserID for the optional ?user_id= query parameter.
+func queryActingUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	claimedID := 0
+	if s := r.URL.Query().Get("user_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "valid user_id is required"})
+			return 0, false
+		}
+		claimedID = id
+	}
+	return actingUserID(w, r, claimedID)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) {
@@ -93,8 +293,14 @@ func handleSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.HasSuffix(req.Email, "@ufl.edu") {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Only @ufl.edu email addresses are allowed"})
+	if emailDomain(req.Email) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+		return
+	}
+
+	req.InviteCode = strings.TrimSpace(req.InviteCode)
+	if req.InviteCode == "" && !emailDomainAllowed(req.Email) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Only " + allowedDomainsText() + " email addresses are allowed without an invite code"})
 		return
 	}
 
@@ -103,17 +309,69 @@ func handleSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+	if retryAfter := lockedFor(signupLimiter, "signup_ip", ip); retryAfter > 0 {
+		writeTooManyAttempts(w, retryAfter)
+		return
+	}
+	recordFailure(signupLimiter, "signup_ip", ip)
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
 		return
 	}
 
+	role := models.RoleUser
+	if isBootstrapAdmin(req.Email) {
+		role = models.RoleAdmin
+	}
+
+	// The invite is redeemed in the same transaction so a failed insert
+	// (e.g. a taken username) does not use it up.
+	tx, err := database.DB.Begin()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
+		return
+	}
+	defer tx.Rollback()
+
+	var inviteID, inviteInstitutionID *int
+	if req.InviteCode != "" {
+		err = tx.QueryRow(`
+			UPDATE invite_codes SET uses = uses + 1
+			WHERE code_hash = $1 AND revoked_at IS NULL AND expires_at > NOW() AND uses < max_uses
+			  AND (email IS NULL OR LOWER(email) = LOWER($2))
+			RETURNING id, institution_id`,
+			hashToken(normalizeInviteCode(req.InviteCode)), req.Email,
+		).Scan(&inviteID, &inviteInstitutionID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired invite code"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to redeem invite code"})
+			return
+		}
+	}
+
+	// An invite's institution wins; otherwise use the most specific domain match.
+	// Without required verification the address counts as verified, so
+	// turning the requirement on later doesn't lock these accounts out.
 	var userID int
-	err = database.DB.QueryRow(
-		"INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id",
-		req.Username, req.Email, string(hashedPassword),
-	).Scan(&userID)
+	var institutionID *int
+	var emailVerified bool
+	err = tx.QueryRow(`
+		INSERT INTO users (username, email, password, role, institution_id, invite_id, email_verified)
+		VALUES ($1, $2, $3, $4, COALESCE($5, (
+			SELECT id FROM institutions
+			WHERE domain = $6 OR RIGHT($6, LENGTH(domain) + 1) = '.' || domain
+			ORDER BY LENGTH(domain) DESC LIMIT 1
+		)), $7, $8)
+		RETURNING id, institution_id, email_verified`,
+		req.Username, req.Email, string(hashedPassword), role, inviteInstitutionID, emailDomain(req.Email), inviteID,
+		!cfg.RequireEmailVerification,
+	).Scan(&userID, &institutionID, &emailVerified)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
 			writeJSON(w, http.StatusConflict, map[string]string{"error": "Username or email already exists"})
@@ -122,20 +380,46 @@ func handleSignup(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
+		return
+	}
+
+	user := map[string]any{
+		"id":             userID,
+		"username":       req.Username,
+		"email":          req.Email,
+		"role":           role,
+		"email_verified": emailVerified,
+		"institution_id": institutionID,
+	}
+
+	// The account is created even if the mail fails; the user can ask for a resend.
+	if !emailVerified {
+		if err := issueVerification(userID, req.Email); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", req.Email, err)
+		}
+	}
+
+	// No token until the address is confirmed, since login would refuse it anyway.
+	if cfg.RequireEmailVerification {
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"user":    user,
+			"message": "Check your inbox to verify your email before logging in",
+		})
+		return
+	}
 
-	token, err := generateJWT(userID, req.Email)
+	token, refreshToken, err := issueTokens(userID, req.Email, role)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, map[string]any{
-		"token": token,
-		"user": map[string]any{
-			"id":       userID,
-			"username": req.Username,
-			"email":    req.Email,
-		},
+		"token":         token,
+		"refresh_token": refreshToken,
+		"user":          user,
 	})
 }
 
@@ -156,17 +440,27 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.HasSuffix(req.Email, "@ufl.edu") {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Only @ufl.edu email addresses are allowed"})
+	if emailDomain(req.Email) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+		return
+	}
+
+	ip := clientIP(r)
+	account := strings.ToLower(req.Email)
+	retryAfter := max(lockedFor(ipLimiter, "ip", ip), lockedFor(accountLimiter, "account", account))
+	if retryAfter > 0 {
+		writeTooManyAttempts(w, retryAfter)
 		return
 	}
 
 	var user models.User
 	err := database.DB.QueryRow(
-		"SELECT id, username, email, password FROM users WHERE email = $1",
+		"SELECT id, username, email, password, role, email_verified, institution_id, invite_id FROM users WHERE email = $1",
 		req.Email,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.Password)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role, &user.EmailVerified, &user.InstitutionID, &user.InviteID)
 	if err == sql.ErrNoRows {
+		recordFailure(ipLimiter, "ip", ip)
+		recordFailure(accountLimiter, "account", account)
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
 		return
 	}
@@ -176,26 +470,241 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordFailure(ipLimiter, "ip", ip)
+		recordFailure(accountLimiter, "account", account)
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid email or password"})
 		return
 	}
+	accountLimiter.Reset("account:" + account)
+
+	// Invited accounts keep working if their domain is later dropped from the allow-list.
+	if user.InviteID == nil && !emailDomainAllowed(user.Email) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Accounts from this email domain are no longer allowed"})
+		return
+	}
+
+	if cfg.RequireEmailVerification && !user.EmailVerified {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Please verify your email address before logging in"})
+		return
+	}
 
-	token, err := generateJWT(user.ID, user.Email)
+	token, refreshToken, err := issueTokens(user.ID, user.Email, user.Role)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
 		"user": map[string]any{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"role":           user.Role,
+			"institution_id": user.InstitutionID,
 		},
 	})
 }
 
+// ---------- Brute-force protection ----------
+
+var (
+	// Failed logins per account and per client IP.
+	accountLimiter = ratelimit.New(5, 30*time.Second, 15*time.Minute, 15*time.Minute)
+	ipLimiter      = ratelimit.New(20, time.Minute, time.Hour, 15*time.Minute)
+	// Every signup attempt counts against the client IP.
+	signupLimiter = ratelimit.New(10, time.Minute, time.Hour, time.Hour)
+	// Every verification resend counts against the client IP and the email.
+	resendLimiter = ratelimit.New(5, 5*time.Minute, time.Hour, time.Hour)
+)
+
+// clientIP returns the caller's address. X-Forwarded-For is only honoured when
+// TrustProxyHeaders is set, which is safe behind a proxy that sets it itself.
+func clientIP(r *http.Request) string {
+	if cfg.TrustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeTooManyAttempts responds 429 with a Retry-After header in whole seconds.
+func writeTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	secs := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"error":       "Too many attempts, please try again later",
+		"retry_after": secs,
+	})
+}
+
+// lockedFor reports how long the kind/key pair is locked out of l.
+func lockedFor(l *ratelimit.Limiter, kind, key string) time.Duration {
+	return l.RetryAfter(kind + ":" + key)
+}
+
+// recordFailure counts a failed attempt and writes an audit row if it
+// triggered a lockout.
+func recordFailure(l *ratelimit.Limiter, kind, key string) {
+	lockout, failures := l.Fail(kind + ":" + key)
+	if lockout == 0 {
+		return
+	}
+	log.Printf("Locked out %s %s for %v after %d failures", kind, key, lockout, failures)
+	if _, err := database.DB.Exec(
+		"INSERT INTO auth_lockouts (kind, key, failures, locked_until) VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')",
+		kind, key, failures, int(lockout.Seconds()),
+	); err != nil {
+		log.Printf("Warning: failed to record lockout of %s %s — %v", kind, key, err)
+	}
+}
+
+// ---------- Pagination ----------
+
+// keyset describes the order an endpoint returns rows in for cursor
+// pagination: by Key (a timestamp expression, a number expression if
+// Numeric is set, or "" for none) and then by ID, which breaks ties. Name
+// is baked into the endpoint's cursors so one can't be replayed against
+// another endpoint or sort order.
+type keyset struct {
+	Name    string
+	Key     string
+	Numeric bool
+	ID      string
+	Desc    bool
+}
+
+func (k keyset) orderBy() string {
+	dir := " ASC"
+	if k.Desc {
+		dir = " DESC"
+	}
+	if k.Key == "" {
+		return k.ID + dir
+	}
+	return k.Key + dir + ", " + k.ID + dir
+}
+
+// pageCursor is the position of the last row of a page. Clients only ever
+// see it base64-encoded. A timestamp Key decodes as its RFC 3339 text,
+// which Postgres reads back as the same timestamp.
+type pageCursor struct {
+	Name string `json:"n"`
+	Key  any    `json:"k,omitempty"`
+	ID   int    `json:"i"`
+}
+
+func (c pageCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// after returns the condition selecting rows that come after c, with %d
+// verbs for its placeholders as sightingFilter.add expects, and its args.
+func (k keyset) after(c pageCursor) (string, []interface{}) {
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+	if k.Key == "" {
+		return fmt.Sprintf("%s %s $%%d", k.ID, op), []interface{}{c.ID}
+	}
+	return fmt.Sprintf("(%s, %s) %s ($%%d, $%%d)", k.Key, k.ID, op), []interface{}{c.Key, c.ID}
+}
+
+// cursorRequest is a parsed request for one page. After is nil for the
+// first page.
+type cursorRequest struct {
+	Limit int
+	After *pageCursor
+}
+
+// parse reads the cursor and limit parameters. A missing or empty cursor
+// asks for the first page. limit defaults to DEFAULT_PAGE_SIZE and is
+// capped at MAX_PAGE_SIZE.
+func (k keyset) parse(q url.Values) (*cursorRequest, string) {
+	req := &cursorRequest{Limit: cfg.DefaultPageSize}
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 {
+			return nil, "limit must be a positive integer"
+		}
+		req.Limit = min(l, cfg.MaxPageSize)
+	}
+	if s := q.Get("cursor"); s != "" {
+		var c pageCursor
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || json.Unmarshal(b, &c) != nil || c.Name != k.Name || c.ID <= 0 || !k.validKey(c.Key) {
+			return nil, "Invalid cursor"
+		}
+		req.After = &c
+	}
+	return req, ""
+}
+
+// validKey reports whether key, as decoded from a cursor's JSON, has the
+// type of k's Key, so it is never handed to Postgres as something else.
+func (k keyset) validKey(key any) bool {
+	switch {
+	case k.Key == "":
+		return key == nil
+	case k.Numeric:
+		_, ok := key.(float64)
+		return ok
+	default:
+		s, ok := key.(string)
+		if !ok {
+			return false
+		}
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	}
+}
+
+// paginate finishes a query whose WHERE clause has been written: it adds
+// the cursor condition, the ORDER BY and a LIMIT one past the page size so
+// writeCursorPage can tell whether another page follows.
+func (k keyset) paginate(query string, args []interface{}, req *cursorRequest) (string, []interface{}) {
+	if req.After != nil {
+		cond, condArgs := k.after(*req.After)
+		idx := make([]interface{}, len(condArgs))
+		for i := range condArgs {
+			idx[i] = len(args) + i + 1
+		}
+		query += " AND " + fmt.Sprintf(cond, idx...)
+		args = append(args, condArgs...)
+	}
+	args = append(args, req.Limit+1)
+	return query + " ORDER BY " + k.orderBy() + fmt.Sprintf(" LIMIT $%d", len(args)), args
+}
+
+// writeCursorPage writes a page fetched with paginate as
+// {data, next_cursor, limit}. next_cursor is null on the last page. pos
+// returns a row's sort key and id.
+func writeCursorPage[T any](w http.ResponseWriter, k keyset, req *cursorRequest, rows []T, pos func(T) (any, int)) {
+	var next *string
+	if len(rows) > req.Limit {
+		rows = rows[:req.Limit]
+		key, id := pos(rows[len(rows)-1])
+		if k.Key == "" {
+			key = nil
+		}
+		s := pageCursor{Name: k.Name, Key: key, ID: id}.encode()
+		next = &s
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":        rows,
+		"next_cursor": next,
+		"limit":       req.Limit,
+	})
+}
+
 // ---------- Sightings CRUD ----------
 
 func handleGetSightings(w http.ResponseWriter, r *http.Request) {
@@ -204,42 +713,91 @@ func handleGetSightings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	category := r.URL.Query().Get("category")
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	keys, ok := sightingKeysets[r.URL.Query().Get("sort")]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be one of: created_at, -created_at, observed_at, -observed_at"})
+		return
+	}
+
+	filter, msg := parseSightingFilter(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
 
-	usePagination := pageStr != "" || limitStr != ""
+	// Offset paging is kept for older clients and only used when page is sent.
+	if r.URL.Query().Has("page") {
+		getSightingsPage(w, r, keys, filter)
+		return
+	}
 
-	page := 1
-	limit := 20
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	req, msg := keys.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
 	}
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-			if limit > 100 {
-				limit = 100
-			}
+	if req.After != nil {
+		cond, args := keys.after(*req.After)
+		filter.add(cond, args...)
+	}
+	args := append(filter.args, req.Limit+1)
+	query := sightingListQuery + " " + filter.where() + " ORDER BY " + keys.orderBy() + fmt.Sprintf(" LIMIT $%d", len(args))
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings"})
+		return
+	}
+	defer rows.Close()
+
+	writeCursorPage(w, keys, req, scanSightings(rows), func(a models.Animals) (any, int) {
+		if keys.Key == observedAtKey {
+			return a.ObservedAt, a.ID
 		}
+		return a.CreateTime, a.ID
+	})
+}
+
+// getSightingsPage answers GET /api/sightings?page=N with the offset page
+// envelope {data, total, page, limit, total_pages}.
+func getSightingsPage(w http.ResponseWriter, r *http.Request, keys keyset, filter *sightingFilter) {
+	page := 1
+	limit := cfg.DefaultPageSize
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, cfg.MaxPageSize)
 	}
 	offset := (page - 1) * limit
 
-	baseWhere := ""
-	var countArgs []interface{}
-	var queryArgs []interface{}
-	argIdx := 1
+	baseWhere := filter.where()
+	argIdx := len(filter.args) + 1
+	query := sightingListQuery + " " + baseWhere + " ORDER BY " + keys.orderBy() +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	rows, err := database.DB.Query(query, append(append([]interface{}{}, filter.args...), limit, offset)...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings"})
+		return
+	}
+	defer rows.Close()
+	sightings := scanSightings(rows)
 
-	if category != "" {
-		baseWhere = fmt.Sprintf("WHERE a.category = $%d", argIdx)
-		countArgs = append(countArgs, category)
-		queryArgs = append(queryArgs, category)
-		argIdx++
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM animals a "+baseWhere, filter.args...).Scan(&total); err != nil {
+		total = 0
 	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":        sightings,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + limit - 1) / limit,
+	})
+}
 
-	baseQuery := `
+// sightingListQuery selects sightings, aliased a, in the columns
+// scanSightings reads. Callers append the WHERE and ORDER BY clauses.
+const sightingListQuery = `
 		SELECT a.id, a.species, COALESCE(a.image_url,''), a.latitude, a.longitude,
 		       COALESCE(a.address,''), COALESCE(a.category,''), COALESCE(a.quantity,1),
 		       COALESCE(a.behavior,''), COALESCE(a.description,''),
@@ -247,119 +805,355 @@ func handleGetSightings(w http.ResponseWriter, r *http.Request) {
 		       COALESCE(a.user_id,0),
 		       COALESCE(NULLIF(a.username,''), u.username, ''),
 		       a.created_at,
-		       COALESCE(lc.cnt, 0) AS like_count
+		       COALESCE(lc.cnt, 0) AS like_count,
+		       a.taxon_id, COALESCE(t.scientific_name,''),
+		       a.observed_at
 		FROM animals a
 		LEFT JOIN users u ON a.user_id = u.id
+		LEFT JOIN taxa t ON t.id = a.taxon_id
 		LEFT JOIN (SELECT sighting_id, COUNT(*) AS cnt FROM sighting_likes GROUP BY sighting_id) lc
-		       ON lc.sighting_id = a.id
-		` + baseWhere + ` ORDER BY a.created_at DESC`
-
-	if usePagination {
-		baseQuery += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-		queryArgs = append(queryArgs, limit, offset)
-	}
-
-	rows, err := database.DB.Query(baseQuery, queryArgs...)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings"})
-		return
-	}
-	defer rows.Close()
+		       ON lc.sighting_id = a.id`
 
+// scanSightings reads the rows of a sightingListQuery and loads each
+// sighting's image variants and media.
+func scanSightings(rows *sql.Rows) []models.Animals {
 	sightings := []models.Animals{}
 	for rows.Next() {
 		var a models.Animals
 		if err := rows.Scan(&a.ID, &a.Species, &a.ImageURL, &a.Latitude, &a.Longitude,
 			&a.Address, &a.Category, &a.Quantity, &a.Behavior, &a.Description,
-			&a.Date, &a.Time, &a.UserID, &a.Username, &a.CreateTime, &a.LikeCount); err != nil {
+			&a.Date, &a.Time, &a.UserID, &a.Username, &a.CreateTime, &a.LikeCount,
+			&a.TaxonID, &a.ScientificName, &a.ObservedAt); err != nil {
 			continue
 		}
 		sightings = append(sightings, a)
 	}
+	loadImageVariants(sightings)
+	loadSightingMedia(sightings)
+	return sightings
+}
 
-	if usePagination {
-		countQuery := "SELECT COUNT(*) FROM animals a " + baseWhere
-		var total int
-		if err := database.DB.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
-			total = 0
-		}
-		totalPages := (total + limit - 1) / limit
-		writeJSON(w, http.StatusOK, map[string]any{
-			"data":        sightings,
-			"total":       total,
-			"page":        page,
-			"limit":       limit,
-			"total_pages": totalPages,
-		})
-		return
-	}
+const observedAtKey = "a.observed_at"
 
-	writeJSON(w, http.StatusOK, sightings)
+// sightingKeysets maps the sort parameter to the order of the list; a
+// leading "-" sorts newest first.
+var sightingKeysets = map[string]keyset{
+	"":             {Name: "sightings:-created_at", Key: "a.created_at", ID: "a.id", Desc: true},
+	"created_at":   {Name: "sightings:created_at", Key: "a.created_at", ID: "a.id"},
+	"-created_at":  {Name: "sightings:-created_at", Key: "a.created_at", ID: "a.id", Desc: true},
+	"observed_at":  {Name: "sightings:observed_at", Key: observedAtKey, ID: "a.id"},
+	"-observed_at": {Name: "sightings:-observed_at", Key: observedAtKey, ID: "a.id", Desc: true},
 }
 
-func handleCreateSighting(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
+// sightingFilter collects the WHERE conditions for GET /api/sightings with
+// their arguments, numbered from $1.
+type sightingFilter struct {
+	conds []string
+	args  []interface{}
+}
+
+// add appends a condition whose %d verbs (or %[n]d to reuse one) are
+// replaced by the placeholders of args.
+func (f *sightingFilter) add(cond string, args ...interface{}) {
+	idx := make([]interface{}, len(args))
+	for i, arg := range args {
+		f.args = append(f.args, arg)
+		idx[i] = len(f.args)
 	}
+	f.conds = append(f.conds, fmt.Sprintf(cond, idx...))
+}
 
-	var req models.CreateSightingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
-		return
+// cacheKey identifies the filter's conditions and values, so requests that
+// filter alike share cached map responses whatever else their query
+// strings hold. It is a hash, so long values don't make long keys.
+func (f *sightingFilter) cacheKey() string {
+	h := sha256.New()
+	for _, c := range f.conds {
+		fmt.Fprintf(h, "%s\x00", c)
 	}
+	for _, a := range f.args {
+		fmt.Fprintf(h, "%T:%v\x00", a, a)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
 
-	if req.Species == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Species is required"})
-		return
+func (f *sightingFilter) where() string {
+	if len(f.conds) == 0 {
+		return ""
 	}
-	if len(req.Species) > 200 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Species name too long (max 200 characters)"})
-		return
+	return "WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// likeEscaper escapes the ILIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// parseSightingFilter reads the filters of GET /api/sightings. Every filter
+// is optional and they combine with AND. It returns a message naming the
+// first invalid parameter, or "".
+func parseSightingFilter(q url.Values) (*sightingFilter, string) {
+	f := &sightingFilter{}
+
+	if v := q.Get("category"); v != "" {
+		f.add("a.category = $%d", v)
 	}
-	if len(req.Description) > 2000 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Description too long (max 2000 characters)"})
-		return
+	if v := strings.TrimSpace(q.Get("species")); v != "" {
+		if len(v) > 200 {
+			return nil, "species too long (max 200 characters)"
+		}
+		f.add("LOWER(a.species) = LOWER($%d)", v)
 	}
-	if req.Latitude < -90 || req.Latitude > 90 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Latitude must be between -90 and 90"})
-		return
+	if v := q.Get("taxon_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, "taxon_id must be a positive integer"
+		}
+		// The taxon and everything below it, e.g. a family and its species.
+		f.add(`a.taxon_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM taxa WHERE id = $%d
+				UNION ALL
+				SELECT t.id FROM taxa t JOIN subtree s ON t.parent_id = s.id
+			) SELECT id FROM subtree)`, id)
+	}
+	if v := q.Get("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, "user_id must be a positive integer"
+		}
+		f.add("a.user_id = $%d", id)
 	}
-	if req.Longitude < -180 || req.Longitude > 180 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Longitude must be between -180 and 180"})
-		return
+
+	var from, to time.Time
+	if v := q.Get("observed_from"); v != "" {
+		t, _, err := parseObservationBound(v)
+		if err != nil {
+			return nil, "observed_from must be YYYY-MM-DD or an RFC 3339 timestamp"
+		}
+		from = t
+		f.add("a.observed_at >= $%d", from)
 	}
-	if req.Quantity <= 0 {
-		req.Quantity = 1
+	if v := q.Get("observed_to"); v != "" {
+		t, dateOnly, err := parseObservationBound(v)
+		if err != nil {
+			return nil, "observed_to must be YYYY-MM-DD or an RFC 3339 timestamp"
+		}
+		to = t
+		if dateOnly {
+			// A date includes the whole day.
+			f.add("a.observed_at < $%d", to.AddDate(0, 0, 1))
+		} else {
+			f.add("a.observed_at <= $%d", to)
+		}
 	}
-	if req.Quantity > 9999 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Quantity too large (max 9999)"})
-		return
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, "observed_from must not be after observed_to"
 	}
 
-	// Convert UserID string to nullable int for the FK column
-	var userIDArg interface{}
-	if uid, err := strconv.Atoi(req.UserID); err == nil && uid > 0 {
-		userIDArg = uid
+	if v := q.Get("bbox"); v != "" {
+		box, msg := parseBBox(v)
+		if msg != "" {
+			return nil, msg
+		}
+		cond, args := box.cond()
+		f.add(cond, args...)
 	}
-
-	var id int
+	if v := q.Get("min_quantity"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 9999 {
+			return nil, "min_quantity must be an integer between 1 and 9999"
+		}
+		f.add("COALESCE(a.quantity,1) >= $%d", n)
+	}
+	if v := strings.TrimSpace(q.Get("behavior")); v != "" {
+		f.add("LOWER(a.behavior) = LOWER($%d)", v)
+	}
+	if v := q.Get("has_photo"); v != "" {
+		has, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, "has_photo must be true or false"
+		}
+		if has {
+			f.conds = append(f.conds, "COALESCE(a.image_url,'') <> ''")
+		} else {
+			f.conds = append(f.conds, "COALESCE(a.image_url,'') = ''")
+		}
+	}
+	if v := strings.TrimSpace(q.Get("q")); v != "" {
+		if len(v) > 100 {
+			return nil, "q too long (max 100 characters)"
+		}
+		f.add("(a.description ILIKE $%[1]d OR a.address ILIKE $%[1]d)", "%"+likeEscaper.Replace(v)+"%")
+	}
+	return f, ""
+}
+
+// bbox is a bounding box in degrees. minLng > maxLng means it crosses the
+// antimeridian.
+type bbox struct {
+	minLng, minLat, maxLng, maxLat float64
+}
+
+// parseBBox reads "minLng,minLat,maxLng,maxLat" and returns a message
+// describing the problem, or "".
+func parseBBox(v string) (bbox, string) {
+	const usage = "bbox must be minLng,minLat,maxLng,maxLat"
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return bbox{}, usage
+	}
+	var n [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return bbox{}, usage
+		}
+		n[i] = f
+	}
+	b := bbox{minLng: n[0], minLat: n[1], maxLng: n[2], maxLat: n[3]}
+	inRange := func(v, limit float64) bool { return v >= -limit && v <= limit }
+	if !inRange(b.minLat, 90) || !inRange(b.maxLat, 90) || !inRange(b.minLng, 180) || !inRange(b.maxLng, 180) {
+		return bbox{}, "bbox latitudes must be within -90..90 and longitudes within -180..180"
+	}
+	if b.minLat > b.maxLat {
+		return bbox{}, "bbox minLat must not be greater than maxLat"
+	}
+	return b, ""
+}
+
+// parseObservationBound reads an observed_from/observed_to value: an RFC
+// 3339 timestamp, or a date in the configured zone (dateOnly is then true).
+func parseObservationBound(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", v, cfg.Location())
+	return t, err == nil, err
+}
+
+func handleCreateSighting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	var req models.CreateSightingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Species == "" && req.TaxonID == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Species is required"})
+		return
+	}
+	if req.TaxonID != nil && *req.TaxonID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid taxon_id"})
+		return
+	}
+	if len(req.Species) > 200 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Species name too long (max 200 characters)"})
+		return
+	}
+	if len(req.Description) > 2000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Description too long (max 2000 characters)"})
+		return
+	}
+	if len(req.Category) > maxCategoryName {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Category too long (max %d characters)", maxCategoryName)})
+		return
+	}
+	if req.Latitude < -90 || req.Latitude > 90 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Latitude must be between -90 and 90"})
+		return
+	}
+	if req.Longitude < -180 || req.Longitude > 180 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Longitude must be between -180 and 180"})
+		return
+	}
+	if req.Quantity <= 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity > 9999 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Quantity too large (max 9999)"})
+		return
+	}
+	observedAt, given, msg := observationTime(&req, time.Now())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	// The client may still send userId; it must agree with the token.
+	claimedID, _ := strconv.Atoi(req.UserID)
+	userID, ok := actingUserID(w, r, claimedID)
+	if !ok {
+		return
+	}
+
+	taxonID, ok := sightingTaxon(w, &req)
+	if !ok {
+		return
+	}
+	if !sightingCategory(w, &req, taxonID) {
+		return
+	}
+
+	// Fill in what the client left out from the photo's EXIF data.
+	var defaulted []string
+	var mismatch *float64
+	if req.ImageURL != "" {
+		if suggestion, ok := uploadSuggestion(req.ImageURL, userID); ok {
+			var distance float64
+			defaulted, distance = applyPhotoSuggestion(&req, suggestion)
+			if distance > cfg.ExifMismatchMeters {
+				mismatch = &distance
+			}
+		}
+	}
+	if slices.Contains(defaulted, "date") || slices.Contains(defaulted, "time") {
+		// The photo filled in the observation time, or part of it.
+		if observedAt, given, msg = observationTime(&req, time.Now()); msg != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+			return
+		}
+	}
+	if !uploadAvailable(w, req.ImageURL, userID, 0) {
+		return
+	}
+	if !given {
+		local := observedAt.In(cfg.Location())
+		req.Date, req.Time = local.Format("2006-01-02"), local.Format("15:04")
+	}
+
+	var id int
 	err := database.DB.QueryRow(`
-		INSERT INTO animals (species, image_url, latitude, longitude, address, category, quantity, behavior, description, date, time, username, user_id)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		INSERT INTO animals (species, image_url, latitude, longitude, address, category, quantity, behavior, description, date, time, username, user_id, location_mismatch_m, taxon_id, observed_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,(SELECT username FROM users WHERE id = $12),$12,$13,$14,$15)
 		RETURNING id`,
 		req.Species, req.ImageURL, req.Latitude, req.Longitude,
 		req.Address, req.Category, req.Quantity, req.Behavior,
-		req.Description, req.Date, req.Time, req.Username, userIDArg,
+		req.Description, req.Date, req.Time, userID, mismatch, taxonID, observedAt,
 	).Scan(&id)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create sighting: " + err.Error()})
 		return
 	}
 
-	go triggerNotifications(id, req.Species, req.Category, req.Latitude, req.Longitude)
+	sightingsChanged()
+	attachUpload(id, userID, req.ImageURL)
+	go triggerNotifications(id, req.Species, taxonID, req.Category, req.Latitude, req.Longitude)
 
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+	resp := map[string]any{"id": id, "taxon_id": taxonID, "observed_at": observedAt}
+	if len(defaulted) > 0 {
+		resp["defaulted_from_photo"] = defaulted
+	}
+	if mismatch != nil {
+		resp["location_warning"] = map[string]any{
+			"distance_meters": math.Round(*mismatch),
+			"message":         fmt.Sprintf("The pin is %.0f m from where the photo was taken", *mismatch),
+		}
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 func handleUpdateSighting(w http.ResponseWriter, r *http.Request) {
@@ -380,15 +1174,54 @@ func handleUpdateSighting(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
+	// Without a date the stored observation time and its text are kept.
+	var observedAt *time.Time
+	var date, clock *string
+	t, given, msg := observationTime(&req, time.Now())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	if given {
+		observedAt, date, clock = &t, &req.Date, &req.Time
+	}
+
+	if _, ok := currentUser(r); !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return
+	}
+	ownerID, err := sightingOwner(id)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if !authorizeOwner(w, r, ownerID) {
+		return
+	}
+	taxonID, ok := sightingTaxon(w, &req)
+	if !ok {
+		return
+	}
+	if !sightingCategory(w, &req, taxonID) {
+		return
+	}
+	if !uploadAvailable(w, req.ImageURL, ownerID, id) {
+		return
+	}
 
 	result, err := database.DB.Exec(`
 		UPDATE animals SET species=$1, image_url=$2, latitude=$3, longitude=$4,
 		       address=$5, category=$6, quantity=$7, behavior=$8,
-		       description=$9, date=$10, time=$11
-		WHERE id=$12`,
+		       description=$9, date=COALESCE($10, date), time=COALESCE($11, time), taxon_id=$12,
+		       observed_at=COALESCE($13, observed_at)
+		WHERE id=$14`,
 		req.Species, req.ImageURL, req.Latitude, req.Longitude,
 		req.Address, req.Category, req.Quantity, req.Behavior,
-		req.Description, req.Date, req.Time, id,
+		req.Description, date, clock, taxonID, observedAt, id,
 	)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update sighting"})
@@ -399,6 +1232,9 @@ func handleUpdateSighting(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
 		return
 	}
+	sightingsChanged()
+	attachUpload(id, ownerID, req.ImageURL)
+	setCoverImage(id, req.ImageURL)
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
@@ -416,6 +1252,23 @@ func handleDeleteSighting(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := currentUser(r); !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return
+	}
+	ownerID, err := sightingOwner(id)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if !authorizeOwner(w, r, ownerID) {
+		return
+	}
+
 	result, err := database.DB.Exec("DELETE FROM animals WHERE id=$1", id)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete sighting"})
@@ -426,1625 +1279,5036 @@ func handleDeleteSighting(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
 		return
 	}
+	sightingsChanged()
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
-// ---------- Stats ----------
+// sightingOwner returns the user_id of a sighting, or 0 if it has no owner.
+func sightingOwner(id int) (int, error) {
+	var ownerID int
+	err := database.DB.QueryRow("SELECT COALESCE(user_id,0) FROM animals WHERE id=$1", id).Scan(&ownerID)
+	return ownerID, err
+}
 
-func handleStats(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
-	}
+// ---------- Categories ----------
 
-	var totalSightings int
-	if err := database.DB.QueryRow("SELECT COUNT(*) FROM animals").Scan(&totalSightings); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings count"})
-		return
-	}
+// fallbackCategory is given to sightings with no category and no taxon, so
+// it cannot be renamed or deleted.
+const fallbackCategory = "Other"
 
-	var totalUsers int
-	if err := database.DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&totalUsers); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query users count"})
-		return
-	}
+const maxCategoryName = 40
 
-	catRows, err := database.DB.Query("SELECT COALESCE(category,'Unknown'), COUNT(*) FROM animals GROUP BY category")
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query category stats"})
-		return
+var categoryColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// validateCategory trims the fields of c, fills in defaults and returns a
+// message describing the first invalid field, or "".
+func validateCategory(c *models.Category) string {
+	c.Name = strings.TrimSpace(c.Name)
+	c.DisplayName = strings.TrimSpace(c.DisplayName)
+	c.Icon = strings.TrimSpace(c.Icon)
+	c.Color = strings.TrimSpace(c.Color)
+	if c.Name == "" {
+		return "name is required"
 	}
-	defer catRows.Close()
+	if len(c.Name) > maxCategoryName {
+		return fmt.Sprintf("name too long (max %d characters)", maxCategoryName)
+	}
+	if c.DisplayName == "" {
+		c.DisplayName = c.Name
+	}
+	if len(c.DisplayName) > 60 {
+		return "display_name too long (max 60 characters)"
+	}
+	if len(c.Icon) > 32 {
+		return "icon too long (max 32 characters)"
+	}
+	if c.Color == "" {
+		c.Color = "#757575"
+	}
+	if !categoryColor.MatchString(c.Color) {
+		return "color must be a hex color like #1E88E5"
+	}
+	return ""
+}
 
-	byCategory := map[string]int{}
-	for catRows.Next() {
-		var cat string
-		var count int
-		if err := catRows.Scan(&cat, &count); err != nil {
-			continue
+// sightingCategory replaces req.Category with the matching category's name,
+// accepting the name or display name in any case. An empty category is
+// taken from the sighting's taxon, or else set to fallbackCategory. It
+// writes the error response and returns false on failure.
+func sightingCategory(w http.ResponseWriter, req *models.CreateSightingRequest, taxonID *int) bool {
+	value := strings.TrimSpace(req.Category)
+	if value == "" {
+		req.Category = fallbackCategory
+		if taxonID != nil {
+			var name string
+			err := database.DB.QueryRow(
+				"SELECT c.name FROM taxa t JOIN categories c ON c.name = t.category WHERE t.id = $1", *taxonID,
+			).Scan(&name)
+			if err == nil {
+				req.Category = name
+			}
 		}
-		byCategory[cat] = count
+		return true
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
-		"total_sightings": totalSightings,
-		"total_users":     totalUsers,
-		"by_category":     byCategory,
-	})
+	err := database.DB.QueryRow(`
+		SELECT name FROM categories
+		WHERE LOWER(name) = LOWER($1) OR LOWER(display_name) = LOWER($1)
+		ORDER BY LOWER(name) = LOWER($1) DESC LIMIT 1`, value,
+	).Scan(&req.Category)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Unknown category %q; see GET /api/categories", value)})
+		return false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return false
+	}
+	return true
 }
 
-// ---------- Comments ----------
+func handleCategoriesRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/categories")
+	path = strings.Trim(path, "/")
 
-func handleGetComments(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetCategories(w, r)
+		case http.MethodPost:
+			handleCreateCategory(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
 		return
 	}
 
-	sightingIDStr := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sightings/"), "/")[0]
-	sightingID, err := strconv.Atoi(sightingIDStr)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+	id, err := strconv.Atoi(path)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid category ID"})
 		return
 	}
+	switch r.Method {
+	case http.MethodPut:
+		handleUpdateCategory(w, r, id)
+	case http.MethodDelete:
+		handleDeleteCategory(w, r, id)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	}
+}
 
+// GET /api/categories
+func handleGetCategories(w http.ResponseWriter, r *http.Request) {
 	rows, err := database.DB.Query(
-		"SELECT id, COALESCE(sighting_id,0), sender_id, sender, content, created_at FROM messages WHERE sighting_id = $1 ORDER BY created_at ASC",
-		sightingID,
+		"SELECT id, name, display_name, icon, color, sort_order FROM categories ORDER BY sort_order, display_name",
 	)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query comments"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch categories"})
 		return
 	}
 	defer rows.Close()
 
-	comments := []models.Message{}
+	categories := []models.Category{}
 	for rows.Next() {
-		var m models.Message
-		if err := rows.Scan(&m.ID, &m.SightingID, &m.SenderID, &m.Sender, &m.Content, &m.CreateTime); err != nil {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.DisplayName, &c.Icon, &c.Color, &c.SortOrder); err != nil {
 			continue
 		}
-		comments = append(comments, m)
+		categories = append(categories, c)
 	}
-
-	writeJSON(w, http.StatusOK, comments)
+	writeJSON(w, http.StatusOK, categories)
 }
 
-func handleCreateComment(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// POST /api/categories  body: {name, display_name?, icon?, color?, sort_order?}  — admin only
+func handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req models.Category
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if msg := validateCategory(&req); msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	if !requireRole(w, r, models.RoleAdmin) {
 		return
 	}
 
-	sightingIDStr := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sightings/"), "/")[0]
-	sightingID, err := strconv.Atoi(sightingIDStr)
+	err := database.DB.QueryRow(
+		"INSERT INTO categories (name, display_name, icon, color, sort_order) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		req.Name, req.DisplayName, req.Icon, req.Color, req.SortOrder,
+	).Scan(&req.ID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "A category with that name already exists"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create category"})
 		return
 	}
+	writeJSON(w, http.StatusCreated, req)
+}
 
-	var req models.CreateCommentRequest
+// PUT /api/categories/{id}  body: {name, display_name?, icon?, color?, sort_order?}  — admin only
+// Renaming a category renames it on every sighting and subscription.
+func handleUpdateCategory(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.Category
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	if req.Content == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Content is required"})
+	if msg := validateCategory(&req); msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
 		return
 	}
-
-	var id int
-	err = database.DB.QueryRow(
-		"INSERT INTO messages (sighting_id, sender_id, sender, content) VALUES ($1, $2, $3, $4) RETURNING id",
-		sightingID, req.SenderID, req.Sender, req.Content,
-	).Scan(&id)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create comment"})
+	if !requireRole(w, r, models.RoleAdmin) {
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
-}
-
-func handleDeleteComment(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	tx, err := database.DB.Begin()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
+	defer tx.Rollback()
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/messages/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid comment ID"})
+	var oldName string
+	err = tx.QueryRow("SELECT name FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&oldName)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Category not found"})
 		return
 	}
-
-	result, err := database.DB.Exec("DELETE FROM messages WHERE id=$1", id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete comment"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+	if oldName == fallbackCategory && req.Name != oldName {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "The fallback category cannot be renamed"})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
-}
+	// animals.category follows through ON UPDATE CASCADE.
+	_, err = tx.Exec(
+		"UPDATE categories SET name = $1, display_name = $2, icon = $3, color = $4, sort_order = $5 WHERE id = $6",
+		req.Name, req.DisplayName, req.Icon, req.Color, req.SortOrder, id,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "A category with that name already exists"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update category"})
+		return
+	}
+	if req.Name != oldName {
+		if _, err := tx.Exec(
+			"UPDATE subscriptions SET value = $1 WHERE type = 'category' AND value = $2", req.Name, oldName,
+		); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update category"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update category"})
+		return
+	}
+	if req.Name != oldName {
+		sightingsChanged()
+	}
 
-// ---------- Likes ----------
+	req.ID = id
+	writeJSON(w, http.StatusOK, req)
+}
 
-// parseSightingIDFromLikePath extracts {id} from /api/sightings/{id}/like(s)
-func parseSightingIDFromLikePath(path string) (int, error) {
-	trimmed := strings.TrimPrefix(path, "/api/sightings/")
-	parts := strings.Split(trimmed, "/")
-	if len(parts) < 2 {
-		return 0, strconv.ErrSyntax
+// DELETE /api/categories/{id}?reassign_to=Other  — admin only
+// A category still used by sightings is only deleted when reassign_to names
+// the category to move them to.
+func handleDeleteCategory(w http.ResponseWriter, r *http.Request, id int) {
+	if !requireRole(w, r, models.RoleAdmin) {
+		return
 	}
-	return strconv.Atoi(parts[0])
-}
+	reassignTo := strings.TrimSpace(r.URL.Query().Get("reassign_to"))
 
-func handleToggleLike(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	tx, err := database.DB.Begin()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
+	defer tx.Rollback()
 
-	sightingID, err := parseSightingIDFromLikePath(r.URL.Path)
+	var name string
+	err = tx.QueryRow("SELECT name FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&name)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Category not found"})
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if name == fallbackCategory {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "The fallback category cannot be deleted"})
 		return
 	}
 
-	var req struct {
-		UserID int `json:"user_id"`
+	var inUse int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM animals WHERE category = $1", name).Scan(&inUse); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	if inUse > 0 {
+		if reassignTo == "" {
+			writeJSON(w, http.StatusConflict, map[string]string{
+				"error": fmt.Sprintf("Category is used by %d sightings; pass reassign_to to move them", inUse),
+			})
+			return
+		}
+		var target string
+		err := tx.QueryRow("SELECT name FROM categories WHERE LOWER(name) = LOWER($1) AND id <> $2", reassignTo, id).Scan(&target)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "reassign_to must name another category"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+			return
+		}
+		if _, err := tx.Exec("UPDATE animals SET category = $1 WHERE category = $2", target, name); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to reassign sightings"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM subscriptions WHERE type = 'category' AND value = $1", name); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete category"})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM categories WHERE id = $1", id); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete category"})
 		return
 	}
-	if req.UserID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete category"})
 		return
 	}
+	if inUse > 0 {
+		sightingsChanged()
+	}
 
-	// Check existing like
-	var exists int
-	err = database.DB.QueryRow(
-		"SELECT 1 FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
-		req.UserID, sightingID,
-	).Scan(&exists)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "deleted", "reassigned": inUse})
+}
 
-	liked := false
+// ---------- Taxonomy ----------
+
+// matchTaxon returns the taxon whose scientific or common name matches name,
+// ignoring case and punctuation. A name shared by several taxa matches none.
+func matchTaxon(name string) (int, bool) {
+	key := taxonomy.Normalize(name)
+	if key == "" {
+		return 0, false
+	}
+	var id, matches int
+	err := database.DB.QueryRow(
+		"SELECT COALESCE(MIN(taxon_id),0), COUNT(DISTINCT taxon_id) FROM taxon_names WHERE normalized = $1",
+		key,
+	).Scan(&id, &matches)
+	if err != nil {
+		log.Printf("Failed to look up taxon for %q: %v", name, err)
+		return 0, false
+	}
+	return id, matches == 1
+}
+
+// sightingTaxon picks the taxon for a sighting: the client's taxon_id when
+// given, otherwise the taxon matching the free-text species, if any. A
+// sighting sent with only a taxon_id takes the taxon's common name as its
+// species. It writes the error response and returns false on failure.
+func sightingTaxon(w http.ResponseWriter, req *models.CreateSightingRequest) (*int, bool) {
+	if req.TaxonID == nil {
+		if id, ok := matchTaxon(req.Species); ok {
+			return &id, true
+		}
+		return nil, true
+	}
+
+	var name string
+	err := database.DB.QueryRow("SELECT common_name FROM taxa WHERE id = $1", *req.TaxonID).Scan(&name)
 	if err == sql.ErrNoRows {
-		// Insert
-		if _, err := database.DB.Exec(
-			"INSERT INTO sighting_likes (user_id, sighting_id) VALUES ($1, $2)",
-			req.UserID, sightingID,
-		); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to like sighting"})
-			return
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Unknown taxon_id"})
+		return nil, false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return nil, false
+	}
+	if req.Species == "" {
+		req.Species = name
+	}
+	return req.TaxonID, true
+}
+
+// speciesTaxon is what a suggestion shows about a taxonomy entry.
+type speciesTaxon struct {
+	id                       int
+	scientificName, category string
+}
+
+// speciesIndex caches the names species suggestions are matched against,
+// with their sighting counts, so a keystroke does not reload every name.
+// It is rebuilt on the first suggestion after the sightings change.
+type speciesIndex struct {
+	mu         sync.Mutex
+	gen        uint64
+	built      bool
+	builtGen   uint64
+	candidates []taxonomy.Candidate
+	taxa       map[string]speciesTaxon
+}
+
+var speciesCandidates speciesIndex
+
+// invalidate marks the cached names stale, including a rebuild under way.
+func (ix *speciesIndex) invalidate() {
+	ix.mu.Lock()
+	ix.gen++
+	ix.mu.Unlock()
+}
+
+// get returns the candidates, rebuilding them if the sightings changed.
+func (ix *speciesIndex) get() ([]taxonomy.Candidate, map[string]speciesTaxon, error) {
+	ix.mu.Lock()
+	if ix.built && ix.builtGen == ix.gen {
+		defer ix.mu.Unlock()
+		return ix.candidates, ix.taxa, nil
+	}
+	gen := ix.gen
+	ix.mu.Unlock()
+
+	candidates, taxa, err := loadSpeciesCandidates()
+	if err != nil {
+		return nil, nil, err
+	}
+	ix.mu.Lock()
+	if gen == ix.gen {
+		ix.built, ix.builtGen, ix.candidates, ix.taxa = true, gen, candidates, taxa
+	}
+	ix.mu.Unlock()
+	return candidates, taxa, nil
+}
+
+// loadSpeciesCandidates reads every taxonomy name and every free-text
+// species, each with its number of sightings.
+func loadSpeciesCandidates() ([]taxonomy.Candidate, map[string]speciesTaxon, error) {
+	var candidates []taxonomy.Candidate
+	taxa := map[string]speciesTaxon{}
+
+	// Every name in the taxonomy, counted by sightings of its taxon.
+	rows, err := database.DB.Query(`
+		SELECT n.name, t.id, t.common_name, t.scientific_name, t.category, COALESCE(c.cnt, 0)
+		FROM taxon_names n
+		JOIN taxa t ON t.id = n.taxon_id
+		LEFT JOIN (SELECT taxon_id, COUNT(*) AS cnt FROM animals WHERE taxon_id IS NOT NULL GROUP BY taxon_id) c
+		       ON c.taxon_id = t.id`)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var c taxonomy.Candidate
+		var t speciesTaxon
+		if err := rows.Scan(&c.Name, &t.id, &c.Canonical, &t.scientificName, &t.category, &c.Sightings); err != nil {
+			continue
 		}
-		liked = true
-	} else if err == nil {
-		// Delete
-		if _, err := database.DB.Exec(
-			"DELETE FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
-			req.UserID, sightingID,
-		); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to unlike sighting"})
+		c.Key = "taxon:" + strconv.Itoa(t.id)
+		taxa[c.Key] = t
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+
+	// Free-text species outside the taxonomy. Spelling variants share one
+	// entry named after the most used spelling.
+	rows, err = database.DB.Query(`
+		SELECT species, COUNT(*) FROM animals
+		WHERE taxon_id IS NULL AND species <> ''
+		GROUP BY species ORDER BY COUNT(*) DESC, species`)
+	if err != nil {
+		return nil, nil, err
+	}
+	canonical := map[string]string{}
+	total := map[string]int{}
+	var freeText []taxonomy.Candidate
+	for rows.Next() {
+		var c taxonomy.Candidate
+		if err := rows.Scan(&c.Name, &c.Sightings); err != nil {
+			continue
+		}
+		c.Key = "name:" + taxonomy.Normalize(c.Name)
+		if _, ok := canonical[c.Key]; !ok {
+			canonical[c.Key] = c.Name
+		}
+		total[c.Key] += c.Sightings
+		freeText = append(freeText, c)
+	}
+	rows.Close()
+	for _, c := range freeText {
+		c.Canonical, c.Sightings = canonical[c.Key], total[c.Key]
+		candidates = append(candidates, c)
+	}
+	return candidates, taxa, nil
+}
+
+// GET /api/species/suggest?q=sandhil&limit=10
+func handleSpeciesSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if taxonomy.Normalize(q) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+	if len(q) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is too long (max 100 characters)"})
+		return
+	}
+	limit := 10
+	if s := r.URL.Query().Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 || l > 25 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 25"})
 			return
 		}
-		liked = false
-	} else {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		limit = l
+	}
+
+	candidates, taxa, err := speciesCandidates.get()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query species"})
 		return
 	}
 
-	var count int
-	if err := database.DB.QueryRow(
-		"SELECT COUNT(*) FROM sighting_likes WHERE sighting_id=$1", sightingID,
-	).Scan(&count); err != nil {
-		count = 0
+	type suggestion struct {
+		Name           string `json:"name"`
+		ScientificName string `json:"scientific_name,omitempty"`
+		TaxonID        *int   `json:"taxon_id"`
+		Category       string `json:"category,omitempty"`
+		Matched        string `json:"matched"`
+		Sightings      int    `json:"sightings"`
+	}
+	out := []suggestion{}
+	for _, s := range taxonomy.Suggest(q, candidates, limit) {
+		item := suggestion{Name: s.Canonical, Matched: s.Matched, Sightings: s.Sightings}
+		if t, ok := taxa[s.Key]; ok {
+			id := t.id
+			item.TaxonID, item.ScientificName, item.Category = &id, t.scientificName, t.category
+		}
+		out = append(out, item)
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
-		"liked": liked,
-		"count": count,
-	})
+	writeJSON(w, http.StatusOK, out)
 }
 
-func handleGetLikes(w http.ResponseWriter, r *http.Request) {
+// ---------- Stats ----------
+
+func handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	sightingID, err := parseSightingIDFromLikePath(r.URL.Path)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+	var totalSightings int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM animals").Scan(&totalSightings); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings count"})
 		return
 	}
 
-	var count int
-	if err := database.DB.QueryRow(
-		"SELECT COUNT(*) FROM sighting_likes WHERE sighting_id=$1", sightingID,
-	).Scan(&count); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query likes"})
+	var totalUsers int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&totalUsers); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query users count"})
 		return
 	}
 
-	likedByMe := false
-	if uidStr := r.URL.Query().Get("user_id"); uidStr != "" {
-		if uid, err := strconv.Atoi(uidStr); err == nil && uid > 0 {
-			var one int
-			err := database.DB.QueryRow(
-				"SELECT 1 FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
-				uid, sightingID,
-			).Scan(&one)
-			likedByMe = (err == nil)
+	// Every managed category is listed, including those with no sightings.
+	catRows, err := database.DB.Query(
+		"SELECT c.name, COUNT(a.id) FROM categories c LEFT JOIN animals a ON a.category = c.name GROUP BY c.name",
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query category stats"})
+		return
+	}
+	defer catRows.Close()
+
+	byCategory := map[string]int{}
+	for catRows.Next() {
+		var cat string
+		var count int
+		if err := catRows.Scan(&cat, &count); err != nil {
+			continue
 		}
+		byCategory[cat] = count
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"count":        count,
-		"liked_by_me":  likedByMe,
+		"total_sightings": totalSightings,
+		"total_users":     totalUsers,
+		"by_category":     byCategory,
 	})
 }
 
-// ---------- Nearby search ----------
+// ---------- Search ----------
+
+// searchTypes maps each searchable result type to the query that finds it.
+// Every query selects the same aliased columns, since a UNION takes its
+// column names from whichever branch comes first; q is the parsed search
+// query from the enclosing WITH clause.
+var searchTypes = map[string]string{
+	"sighting": `
+		SELECT 'sighting' AS type, a.id AS id, a.id AS sighting_id, NULL::int AS channel_id,
+		       a.species AS title, COALESCE(NULLIF(a.username,''), u.username, '') AS author,
+		       concat_ws(' · ', NULLIF(a.description,''), NULLIF(a.address,'')) AS body,
+		       ts_rank_cd(a.search_vector, q.query) AS rank, a.created_at AS created_at
+		FROM animals a
+		LEFT JOIN users u ON u.id = a.user_id, q
+		WHERE a.search_vector @@ q.query`,
+	"comment": `
+		SELECT 'comment' AS type, m.id AS id, m.sighting_id AS sighting_id, NULL::int AS channel_id,
+		       a.species AS title, m.sender AS author, m.content AS body,
+		       ts_rank_cd(m.search_vector, q.query) AS rank, m.created_at AS created_at
+		FROM messages m
+		JOIN animals a ON a.id = m.sighting_id, q
+		WHERE m.search_vector @@ q.query`,
+	"channel_message": `
+		SELECT 'channel_message' AS type, m.id AS id, NULL::int AS sighting_id, m.channel_id AS channel_id,
+		       c.name AS title, u.username AS author, m.content AS body,
+		       ts_rank_cd(m.search_vector, q.query) AS rank, m.created_at AS created_at
+		FROM area_messages m
+		JOIN area_channels c ON c.id = m.channel_id
+		JOIN users u ON u.id = m.sender_id, q
+		WHERE m.search_vector @@ q.query`,
+}
 
-func handleGetNearbySightings(w http.ResponseWriter, r *http.Request) {
+// searchTypeOrder keeps the generated query stable.
+var searchTypeOrder = []string{"sighting", "comment", "channel_message"}
+
+const maxSearchQuery = 200
+
+// Matches are wrapped in control characters by ts_headline so the snippet
+// can be HTML-escaped before the <mark> tags are put in.
+const (
+	headlineStart   = "\x02"
+	headlineStop    = "\x03"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop +
+		", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// searchHighlight escapes a ts_headline snippet for HTML and marks the
+// matched words.
+func searchHighlight(s string) string {
+	return headlineMarks.Replace(html.EscapeString(s))
+}
+
+// parseSearchTypes reads the comma-separated type parameter; empty means
+// every type.
+func parseSearchTypes(s string) ([]string, bool) {
+	if strings.TrimSpace(s) == "" {
+		return searchTypeOrder, true
+	}
+	want := map[string]bool{}
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if _, ok := searchTypes[t]; !ok {
+			return nil, false
+		}
+		want[t] = true
+	}
+	var types []string
+	for _, t := range searchTypeOrder {
+		if want[t] {
+			types = append(types, t)
+		}
+	}
+	return types, true
+}
+
+// searchQueries builds the queries counting and fetching the matches of
+// the given types. Both take the search text as $1; the page query also
+// takes the headline options, limit and offset as $2-$4.
+func searchQueries(types []string) (count, page string) {
+	branches := make([]string, len(types))
+	for i, t := range types {
+		branches[i] = searchTypes[t]
+	}
+	const withQuery = "WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query) "
+	matches := strings.Join(branches, " UNION ALL ")
+
+	count = withQuery + "SELECT COUNT(*) FROM (" + matches + ") r"
+	// ts_headline is costly, so it only runs on the page being returned.
+	page = withQuery + `
+		SELECT r.type, r.id, r.sighting_id, r.channel_id, r.title, r.author,
+		       ts_headline('english', r.body, q.query, $2),
+		       r.rank, r.created_at
+		FROM (` + matches + `
+		      ORDER BY rank DESC, created_at DESC, id DESC
+		      LIMIT $3 OFFSET $4) r, q
+		ORDER BY r.rank DESC, r.created_at DESC, r.id DESC`
+	return count, page
+}
+
+// GET /api/search?q=&type=sighting,comment,channel_message&page=&limit=
+// Full-text search over sightings (species, description, address), sighting
+// comments and area channel messages. q accepts web search syntax: quoted
+// phrases, "or" and a leading "-" to exclude a word. Results are ordered by
+// relevance, newest first among equals.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	latStr := r.URL.Query().Get("lat")
-	lngStr := r.URL.Query().Get("lng")
-	if latStr == "" || lngStr == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "lat and lng query parameters are required"})
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
 		return
 	}
-
-	lat, err := strconv.ParseFloat(latStr, 64)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid lat value"})
+	if len(q) > maxSearchQuery {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("q is too long (max %d characters)", maxSearchQuery)})
 		return
 	}
-	lng, err := strconv.ParseFloat(lngStr, 64)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid lng value"})
+	types, ok := parseSearchTypes(r.URL.Query().Get("type"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be a comma-separated list of: " + strings.Join(searchTypeOrder, ", ")})
 		return
 	}
 
-	radius := 1000.0
-	if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
-		if r2, err := strconv.ParseFloat(radiusStr, 64); err == nil && r2 > 0 {
-			radius = r2
+	page := 1
+	limit := cfg.DefaultPageSize
+	if s := r.URL.Query().Get("page"); s != "" {
+		p, err := strconv.Atoi(s)
+		if err != nil || p <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "page must be a positive integer"})
+			return
 		}
+		page = p
 	}
-	if radius > 10000 {
-		radius = 10000
+	if s := r.URL.Query().Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(l, cfg.MaxPageSize)
 	}
 
-	rows, err := database.DB.Query(`
-		SELECT a.id, a.species, COALESCE(a.image_url,''), a.latitude, a.longitude,
-		       COALESCE(a.address,''), COALESCE(a.category,''), COALESCE(a.quantity,1),
-		       COALESCE(a.behavior,''), COALESCE(a.description,''),
-		       COALESCE(a.date,''), COALESCE(a.time,''),
-		       COALESCE(a.user_id,0),
-		       COALESCE(NULLIF(a.username,''), u.username, ''),
-		       a.created_at,
-		       COALESCE(lc.cnt, 0) AS like_count,
-		       (6371000 * acos(
-		           GREATEST(-1, LEAST(1,
-		               cos(radians($1)) * cos(radians(a.latitude)) *
-		               cos(radians(a.longitude) - radians($2)) +
-		               sin(radians($1)) * sin(radians(a.latitude))
-		           ))
-		       )) AS distance_meters
-		FROM animals a
-		LEFT JOIN users u ON a.user_id = u.id
-		LEFT JOIN (SELECT sighting_id, COUNT(*) AS cnt FROM sighting_likes GROUP BY sighting_id) lc
-		       ON lc.sighting_id = a.id
-		WHERE (6371000 * acos(
-		           GREATEST(-1, LEAST(1,
-		               cos(radians($1)) * cos(radians(a.latitude)) *
-		               cos(radians(a.longitude) - radians($2)) +
-		               sin(radians($1)) * sin(radians(a.latitude))
-		           ))
-		       )) <= $3
-		ORDER BY distance_meters ASC`, lat, lng, radius)
+	countQuery, pageQuery := searchQueries(types)
+	var total int
+	if err := database.DB.QueryRow(countQuery, q).Scan(&total); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to search"})
+		return
+	}
+
+	rows, err := database.DB.Query(pageQuery, q, headlineOptions, limit, (page-1)*limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query nearby sightings"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to search"})
 		return
 	}
 	defer rows.Close()
 
-	sightings := []models.Animals{}
+	results := []models.SearchResult{}
 	for rows.Next() {
-		var a models.Animals
-		if err := rows.Scan(&a.ID, &a.Species, &a.ImageURL, &a.Latitude, &a.Longitude,
-			&a.Address, &a.Category, &a.Quantity, &a.Behavior, &a.Description,
-			&a.Date, &a.Time, &a.UserID, &a.Username, &a.CreateTime, &a.LikeCount, &a.DistanceMeters); err != nil {
+		var res models.SearchResult
+		if err := rows.Scan(&res.Type, &res.ID, &res.SightingID, &res.ChannelID,
+			&res.Title, &res.Author, &res.Highlight, &res.Rank, &res.CreatedAt); err != nil {
 			continue
 		}
-		sightings = append(sightings, a)
+		res.Highlight = searchHighlight(res.Highlight)
+		results = append(results, res)
 	}
 
-	writeJSON(w, http.StatusOK, sightings)
-}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":        results,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + limit - 1) / limit,
+	})
+}
+
+// ---------- Comments ----------
+
+func handleGetComments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	sightingIDStr := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sightings/"), "/")[0]
+	sightingID, err := strconv.Atoi(sightingIDStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		return
+	}
+
+	page, msg := commentKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	query, args := commentKeyset.paginate(
+		"SELECT id, COALESCE(sighting_id,0), sender_id, sender, content, created_at FROM messages WHERE sighting_id = $1",
+		[]interface{}{sightingID}, page,
+	)
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query comments"})
+		return
+	}
+	defer rows.Close()
+
+	comments := []models.Message{}
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.SightingID, &m.SenderID, &m.Sender, &m.Content, &m.CreateTime); err != nil {
+			continue
+		}
+		comments = append(comments, m)
+	}
+
+	writeCursorPage(w, commentKeyset, page, comments, func(m models.Message) (any, int) {
+		return m.CreateTime, m.ID
+	})
+}
+
+var commentKeyset = keyset{Name: "comments", Key: "created_at", ID: "id"}
+
+func handleCreateComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	sightingIDStr := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sightings/"), "/")[0]
+	sightingID, err := strconv.Atoi(sightingIDStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Content == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Content is required"})
+		return
+	}
+
+	claimedID, _ := strconv.Atoi(req.SenderID)
+	senderID, ok := actingUserID(w, r, claimedID)
+	if !ok {
+		return
+	}
+
+	var id int
+	err = database.DB.QueryRow(
+		"INSERT INTO messages (sighting_id, sender_id, sender, content) VALUES ($1, $2, (SELECT username FROM users WHERE id = $3), $4) RETURNING id",
+		sightingID, strconv.Itoa(senderID), senderID, req.Content,
+	).Scan(&id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create comment"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+func handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/messages/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid comment ID"})
+		return
+	}
+
+	if _, ok := currentUser(r); !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return
+	}
+	// messages.sender_id is TEXT; non-numeric legacy values match no user.
+	var senderID string
+	err = database.DB.QueryRow("SELECT sender_id FROM messages WHERE id=$1", id).Scan(&senderID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	ownerID, _ := strconv.Atoi(senderID)
+	if !authorizeOwner(w, r, ownerID) {
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM messages WHERE id=$1", id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete comment"})
+		return
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// ---------- Likes ----------
+
+// parseSightingIDFromLikePath extracts {id} from /api/sightings/{id}/like(s)
+func parseSightingIDFromLikePath(path string) (int, error) {
+	trimmed := strings.TrimPrefix(path, "/api/sightings/")
+	parts := strings.Split(trimmed, "/")
+	if len(parts) < 2 {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(parts[0])
+}
+
+func handleToggleLike(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	sightingID, err := parseSightingIDFromLikePath(r.URL.Path)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		return
+	}
+
+	// The body is optional now that the user comes from the token.
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	userID, ok := actingUserID(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
+	// Check existing like
+	var exists int
+	err = database.DB.QueryRow(
+		"SELECT 1 FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
+		userID, sightingID,
+	).Scan(&exists)
+
+	liked := false
+	if err == sql.ErrNoRows {
+		// Insert
+		if _, err := database.DB.Exec(
+			"INSERT INTO sighting_likes (user_id, sighting_id) VALUES ($1, $2)",
+			userID, sightingID,
+		); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to like sighting"})
+			return
+		}
+		liked = true
+	} else if err == nil {
+		// Delete
+		if _, err := database.DB.Exec(
+			"DELETE FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
+			userID, sightingID,
+		); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to unlike sighting"})
+			return
+		}
+		liked = false
+	} else {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	likesChanged(sightingID)
+
+	var count int
+	if err := database.DB.QueryRow(
+		"SELECT COUNT(*) FROM sighting_likes WHERE sighting_id=$1", sightingID,
+	).Scan(&count); err != nil {
+		count = 0
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"liked": liked,
+		"count": count,
+	})
+}
+
+func handleGetLikes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	sightingID, err := parseSightingIDFromLikePath(r.URL.Path)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		return
+	}
+
+	var count int
+	if err := database.DB.QueryRow(
+		"SELECT COUNT(*) FROM sighting_likes WHERE sighting_id=$1", sightingID,
+	).Scan(&count); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query likes"})
+		return
+	}
+
+	likedByMe := false
+	uidStr := r.URL.Query().Get("user_id")
+	if user, ok := currentUser(r); ok {
+		uidStr = strconv.Itoa(user.ID)
+	}
+	if uidStr != "" {
+		if uid, err := strconv.Atoi(uidStr); err == nil && uid > 0 {
+			var one int
+			err := database.DB.QueryRow(
+				"SELECT 1 FROM sighting_likes WHERE user_id=$1 AND sighting_id=$2",
+				uid, sightingID,
+			).Scan(&one)
+			likedByMe = (err == nil)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":       count,
+		"liked_by_me": likedByMe,
+	})
+}
+
+// ---------- Nearby search ----------
+
+func handleGetNearbySightings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	latStr := r.URL.Query().Get("lat")
+	lngStr := r.URL.Query().Get("lng")
+	if latStr == "" || lngStr == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "lat and lng query parameters are required"})
+		return
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid lat value"})
+		return
+	}
+	lng, err := strconv.ParseFloat(lngStr, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid lng value"})
+		return
+	}
+
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "lat must be within -90..90 and lng within -180..180"})
+		return
+	}
+
+	radius := cfg.DefaultNearbyRadius
+	if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
+		if r2, err := strconv.ParseFloat(radiusStr, 64); err == nil && r2 > 0 {
+			radius = r2
+		}
+	}
+	if radius > cfg.MaxNearbyRadius {
+		radius = cfg.MaxNearbyRadius
+	}
+
+	page, msg := nearbyKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	// The box around the circle is matched against the location index;
+	// only the sightings inside it have their distance measured.
+	filter := &sightingFilter{args: []interface{}{lat, lng, radius}}
+	cond, args := nearbyBox(lat, lng, radius).cond()
+	filter.add(cond, args...)
+
+	query, queryArgs := nearbyKeyset.paginate(`
+		SELECT a.id, a.species, COALESCE(a.image_url,''), a.latitude, a.longitude,
+		       COALESCE(a.address,''), COALESCE(a.category,''), COALESCE(a.quantity,1),
+		       COALESCE(a.behavior,''), COALESCE(a.description,''),
+		       COALESCE(a.date,''), COALESCE(a.time,''),
+		       COALESCE(a.user_id,0),
+		       COALESCE(NULLIF(a.username,''), u.username, ''),
+		       a.created_at,
+		       COALESCE(lc.cnt, 0) AS like_count,
+		       a.taxon_id, COALESCE(t.scientific_name,''),
+		       a.observed_at,
+		       a.distance_meters
+		FROM (
+		    SELECT a.*, (6371000 * acos(
+		               GREATEST(-1, LEAST(1,
+		                   cos(radians($1)) * cos(radians(a.latitude)) *
+		                   cos(radians(a.longitude) - radians($2)) +
+		                   sin(radians($1)) * sin(radians(a.latitude))
+		               ))
+		           )) AS distance_meters
+		    FROM animals a
+		    `+filter.where()+`
+		) a
+		LEFT JOIN users u ON a.user_id = u.id
+		LEFT JOIN taxa t ON t.id = a.taxon_id
+		LEFT JOIN (SELECT sighting_id, COUNT(*) AS cnt FROM sighting_likes GROUP BY sighting_id) lc
+		       ON lc.sighting_id = a.id
+		WHERE a.distance_meters <= $3`, filter.args, page)
+	rows, err := database.DB.Query(query, queryArgs...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query nearby sightings"})
+		return
+	}
+	defer rows.Close()
+
+	sightings := []models.Animals{}
+	for rows.Next() {
+		var a models.Animals
+		if err := rows.Scan(&a.ID, &a.Species, &a.ImageURL, &a.Latitude, &a.Longitude,
+			&a.Address, &a.Category, &a.Quantity, &a.Behavior, &a.Description,
+			&a.Date, &a.Time, &a.UserID, &a.Username, &a.CreateTime, &a.LikeCount,
+			&a.TaxonID, &a.ScientificName, &a.ObservedAt, &a.DistanceMeters); err != nil {
+			continue
+		}
+		sightings = append(sightings, a)
+	}
+	loadImageVariants(sightings)
+	loadSightingMedia(sightings)
+
+	writeCursorPage(w, nearbyKeyset, page, sightings, func(a models.Animals) (any, int) {
+		return a.DistanceMeters, a.ID
+	})
+}
+
+// Nearby sightings are listed closest first.
+var nearbyKeyset = keyset{Name: "nearby", Key: "a.distance_meters", Numeric: true, ID: "a.id"}
+
+const earthRadiusMeters = 6371000
+
+// nearbyBox returns the smallest bounding box holding every point within
+// radius meters of lat,lng. Near a pole, or when the circle is wide enough,
+// it spans every longitude.
+func nearbyBox(lat, lng, radius float64) bbox {
+	const metersPerDegree = earthRadiusMeters * math.Pi / 180
+	dLat := radius / metersPerDegree
+	b := bbox{minLng: -180, minLat: max(lat-dLat, -90), maxLng: 180, maxLat: min(lat+dLat, 90)}
+	if b.minLat == -90 || b.maxLat == 90 {
+		return b
+	}
+	// The circle's widest point is not on lat itself but a little poleward.
+	s := math.Sin(radius/earthRadiusMeters) / math.Cos(lat*math.Pi/180)
+	if s >= 1 {
+		return b
+	}
+	dLng := math.Asin(s) * 180 / math.Pi
+	if dLng >= 180 {
+		return b
+	}
+	b.minLng, b.maxLng = lng-dLng, lng+dLng
+	if b.minLng < -180 {
+		b.minLng += 360
+	}
+	if b.maxLng > 180 {
+		b.maxLng -= 360
+	}
+	return b
+}
+
+// cond returns a condition matching sightings inside the box that the
+// location index can answer, with %d verbs as sightingFilter.add expects.
+func (b bbox) cond() (string, []interface{}) {
+	const inBox = "point(a.longitude, a.latitude) <@ box(point($%d, $%d), point($%d, $%d))"
+	if b.minLng <= b.maxLng {
+		return inBox, []interface{}{b.minLng, b.minLat, b.maxLng, b.maxLat}
+	}
+	// The box crosses the antimeridian: match both halves.
+	return "(" + inBox + " OR " + inBox + ")",
+		[]interface{}{b.minLng, b.minLat, 180.0, b.maxLat, -180.0, b.minLat, b.maxLng, b.maxLat}
+}
+
+// contains reports whether the point lies inside the box, as cond would.
+func (b bbox) contains(lat, lng float64) bool {
+	if lat < b.minLat || lat > b.maxLat {
+		return false
+	}
+	if b.minLng <= b.maxLng {
+		return lng >= b.minLng && lng <= b.maxLng
+	}
+	return lng >= b.minLng || lng <= b.maxLng
+}
+
+func handleSightings(w http.ResponseWriter, r *http.Request) {
+	// Route /api/sightings, /api/sightings/nearby, /api/sightings/bbox,
+	// /api/sightings/clusters, /api/sightings/{id}, /api/sightings/{id}/messages,
+	// /api/sightings/{id}/like(s), /api/sightings/{id}/media[/...]
+	path := strings.TrimPrefix(r.URL.Path, "/api/sightings")
+	path = strings.TrimPrefix(path, "/")
+
+	if path == "" {
+		// Collection routes
+		switch r.Method {
+		case http.MethodGet:
+			handleGetSightings(w, r)
+		case http.MethodPost:
+			handleCreateSighting(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	// /api/sightings/nearby
+	if path == "nearby" {
+		handleGetNearbySightings(w, r)
+		return
+	}
+
+	// /api/sightings/bbox
+	if path == "bbox" {
+		handleGetViewportSightings(w, r)
+		return
+	}
+
+	// /api/sightings/clusters
+	if path == "clusters" {
+		handleGetSightingClusters(w, r)
+		return
+	}
+
+	// /api/sightings/heatmap
+	if path == "heatmap" {
+		handleGetSightingHeatmap(w, r)
+		return
+	}
+
+	// Check sub-paths: {id}/messages, {id}/like, {id}/likes
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "messages":
+			switch r.Method {
+			case http.MethodGet:
+				handleGetComments(w, r)
+			case http.MethodPost:
+				handleCreateComment(w, r)
+			default:
+				writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+			}
+			return
+		case "like":
+			handleToggleLike(w, r)
+			return
+		case "likes":
+			handleGetLikes(w, r)
+			return
+		}
+		if parts[1] == "media" || strings.HasPrefix(parts[1], "media/") {
+			handleSightingMedia(w, r, parts[0], strings.TrimPrefix(strings.TrimPrefix(parts[1], "media"), "/"))
+			return
+		}
+	}
+
+	// Individual resource routes: /api/sightings/{id}
+	switch r.Method {
+	case http.MethodPut:
+		handleUpdateSighting(w, r)
+	case http.MethodDelete:
+		handleDeleteSighting(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	}
+}
+
+// ---------- Map viewport ----------
+
+// parseViewport reads the min_lat, min_lng, max_lat and max_lng parameters.
+// min_lng > max_lng means the viewport crosses the antimeridian.
+func parseViewport(q url.Values) (bbox, string) {
+	var n [4]float64
+	for i, name := range []string{"min_lng", "min_lat", "max_lng", "max_lat"} {
+		v := q.Get(name)
+		if v == "" {
+			return bbox{}, "min_lat, min_lng, max_lat and max_lng are required"
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return bbox{}, "Invalid " + name + " value"
+		}
+		n[i] = f
+	}
+	b := bbox{minLng: n[0], minLat: n[1], maxLng: n[2], maxLat: n[3]}
+	if b.minLat < -90 || b.maxLat > 90 || b.minLng < -180 || b.minLng > 180 || b.maxLng < -180 || b.maxLng > 180 {
+		return bbox{}, "Latitudes must be within -90..90 and longitudes within -180..180"
+	}
+	if b.minLat > b.maxLat {
+		return bbox{}, "min_lat must not be greater than max_lat"
+	}
+	return b, ""
+}
+
+// GET /api/sightings/bbox?min_lat=&min_lng=&max_lat=&max_lng=&limit=
+// Sightings inside the map viewport, taking the same filters and sort as
+// GET /api/sightings. At most limit (default and maximum
+// MAX_VIEWPORT_RESULTS) are returned; truncated says whether more matched.
+func handleGetViewportSightings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	box, msg := parseViewport(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	limit := cfg.MaxViewportResults
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(l, cfg.MaxViewportResults)
+	}
+	keys, ok := sightingKeysets[q.Get("sort")]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be one of: created_at, -created_at, observed_at, -observed_at"})
+		return
+	}
+	filter, msg := parseSightingFilter(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	sightings, truncated, err := viewportSightings(filter, box, keys, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":      sightings,
+		"truncated": truncated,
+		"limit":     limit,
+	})
+}
+
+// viewportSightings returns up to limit sightings matching filter inside
+// box, and whether more matched.
+func viewportSightings(filter *sightingFilter, box bbox, keys keyset, limit int) ([]models.Animals, bool, error) {
+	cond, args := box.cond()
+	filter.add(cond, args...)
+
+	// One row past the limit tells whether the viewport was truncated.
+	args = append(filter.args, limit+1)
+	rows, err := database.DB.Query(
+		sightingListQuery+" "+filter.where()+" ORDER BY "+keys.orderBy()+fmt.Sprintf(" LIMIT $%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	sightings := scanSightings(rows)
+	if len(sightings) > limit {
+		return sightings[:limit], true, nil
+	}
+	return sightings, false, nil
+}
+
+// ---------- Map clusters ----------
+
+const (
+	// clusterCellPx is the width of a cluster cell in screen pixels of a
+	// 256-pixel web map tile.
+	clusterCellPx = 64
+	// clusterMaxZoom is the closest zoom that clusters; beyond it the
+	// individual sightings are returned.
+	clusterMaxZoom = 16
+	maxZoom        = 22
+	// clusterTopSpecies is how many species each cluster lists.
+	clusterTopSpecies = 3
+	// mercatorMaxLat is the latitude where the web map ends.
+	mercatorMaxLat = 85.05112878
+)
+
+// gridCells is how many cells cellPx screen pixels wide span the world's
+// width at zoom.
+func gridCells(zoom, cellPx int) int {
+	return (1 << zoom) * 256 / cellPx
+}
+
+// mercatorCell returns SQL expressions for the grid cell holding a
+// sighting, given the placeholders for the grid width and mercatorMaxLat.
+// Cells are numbered like map tiles: x from the antimeridian eastwards and
+// y from the north edge southwards.
+func mercatorCell(nIdx, latIdx int) (x, y string) {
+	x = fmt.Sprintf("LEAST(floor((a.longitude + 180) / 360 * $%[1]d::float8), $%[1]d::float8 - 1)::bigint", nIdx)
+	y = fmt.Sprintf(`LEAST(floor((1 - asinh(tan(radians(GREATEST(-$%[2]d::float8, LEAST($%[2]d::float8, a.latitude))))) / pi()) / 2 * $%[1]d::float8), $%[1]d::float8 - 1)::bigint`, nIdx, latIdx)
+	return x, y
+}
+
+// cellBounds returns cell x,y of a grid n cells wide in Web Mercator as
+// [minLng, minLat, maxLng, maxLat].
+func cellBounds(x, y, n int) [4]float64 {
+	lng := func(x int) float64 { return float64(x)/float64(n)*360 - 180 }
+	lat := func(y int) float64 { return math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/float64(n)))) * 180 / math.Pi }
+	return [4]float64{lng(x), lat(y + 1), lng(x + 1), lat(y)}
+}
+
+// GET /api/sightings/clusters?min_lat=&min_lng=&max_lat=&max_lng=&zoom=
+// Groups the sightings in the viewport into grid cells of about 64 screen
+// pixels at the map's zoom, each with its count, centroid and top species.
+// Above clusterMaxZoom the sightings themselves are returned in points. It
+// takes the same filters as GET /api/sightings; at most
+// MAX_VIEWPORT_RESULTS clusters or points are returned, busiest first.
+func handleGetSightingClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	box, msg := parseViewport(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	zoom, err := strconv.Atoi(q.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxZoom {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("zoom must be an integer between 0 and %d", maxZoom)})
+		return
+	}
+	filter, msg := parseSightingFilter(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	limit := cfg.MaxViewportResults
+
+	if zoom > clusterMaxZoom {
+		points, truncated, err := viewportSightings(filter, box, sightingKeysets[""], limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query sightings"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"zoom":      zoom,
+			"clusters":  []models.Cluster{},
+			"points":    points,
+			"truncated": truncated,
+		})
+		return
+	}
+
+	clusters, truncated, err := sightingClusters(filter, box, zoom, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to cluster sightings"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"zoom":      zoom,
+		"clusters":  clusters,
+		"points":    []models.Animals{},
+		"truncated": truncated,
+	})
+}
+
+// sightingClusters groups the sightings matching filter inside box into
+// the cells of the grid for zoom. It returns the limit busiest cells and
+// whether there were more.
+func sightingClusters(filter *sightingFilter, box bbox, zoom, limit int) ([]models.Cluster, bool, error) {
+	cond, args := box.cond()
+	filter.add(cond, args...)
+	n := gridCells(zoom, clusterCellPx)
+	nIdx, latIdx, limitIdx := len(filter.args)+1, len(filter.args)+2, len(filter.args)+3
+	args = append(filter.args, n, mercatorMaxLat, limit+1)
+	cellX, cellY := mercatorCell(nIdx, latIdx)
+
+	rows, err := database.DB.Query(`
+		WITH pts AS (
+			SELECT a.id, a.latitude, a.longitude,
+			       `+speciesKey+` AS species_key,
+			       COALESCE(t.common_name, a.species) AS species,
+			       `+cellX+` AS cx, `+cellY+` AS cy
+			FROM animals a
+			LEFT JOIN taxa t ON t.id = a.taxon_id
+			`+filter.where()+`
+		),
+		cells AS (
+			SELECT cx, cy, COUNT(*) AS n, AVG(latitude) AS lat, AVG(longitude) AS lng, MIN(id) AS first_id
+			FROM pts
+			GROUP BY cx, cy
+			ORDER BY n DESC, cx, cy
+			LIMIT $`+strconv.Itoa(limitIdx)+`
+		),
+		species AS (
+			SELECT cx, cy, MIN(species) AS species, COUNT(*) AS n,
+			       ROW_NUMBER() OVER (PARTITION BY cx, cy ORDER BY COUNT(*) DESC, MIN(species)) AS rank
+			FROM pts
+			JOIN cells USING (cx, cy)
+			GROUP BY cx, cy, species_key
+		)
+		SELECT c.cx, c.cy, c.n, c.lat, c.lng, c.first_id, s.species, s.n
+		FROM cells c
+		JOIN species s USING (cx, cy)
+		WHERE s.rank <= `+strconv.Itoa(clusterTopSpecies)+`
+		ORDER BY c.n DESC, c.cx, c.cy, s.rank`,
+		args...,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	clusters := []models.Cluster{}
+	lastX, lastY := -1, -1
+	for rows.Next() {
+		var x, y, firstID int
+		var c models.Cluster
+		var sp models.SpeciesCount
+		if err := rows.Scan(&x, &y, &c.Count, &c.Latitude, &c.Longitude, &firstID, &sp.Species, &sp.Count); err != nil {
+			return nil, false, err
+		}
+		if x != lastX || y != lastY {
+			if c.Count == 1 {
+				c.SightingID = &firstID
+			}
+			c.Bounds = cellBounds(x, y, n)
+			clusters = append(clusters, c)
+			lastX, lastY = x, y
+		}
+		last := &clusters[len(clusters)-1]
+		last.TopSpecies = append(last.TopSpecies, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(clusters) > limit {
+		return clusters[:limit], true, nil
+	}
+	return clusters, false, nil
+}
+
+// ---------- Vector tiles ----------
+
+// mapCacheTTL bounds how stale a cached map response can be when a change
+// reaches the database without going through sightingsChanged.
+const mapCacheTTL = 10 * time.Minute
+
+// mapCache holds rendered tiles and heatmaps; main replaces it once the
+// configured budget is known.
+var mapCache = mapcache.New(cfg.MapCacheBytes, mapCacheTTL)
+
+// sightingsChanged drops cached map responses and species suggestion
+// counts after sightings or their categories change. Likes are left out of
+// both, so liking a sighting keeps the caches warm.
+func sightingsChanged() {
+	mapCache.Purge()
+	speciesCandidates.invalidate()
+}
+
+// sightingLocation reads where a sighting was made.
+var sightingLocation = func(sightingID int) (lat, lng float64, err error) {
+	err = database.DB.QueryRow(
+		"SELECT latitude, longitude FROM animals WHERE id = $1", sightingID,
+	).Scan(&lat, &lng)
+	return lat, lng, err
+}
+
+// likesChanged drops the cached tiles holding the sighting, as their
+// like_count properties are out of date. Heatmaps don't count likes, so
+// they stay cached.
+func likesChanged(sightingID int) {
+	lat, lng, err := sightingLocation(sightingID)
+	mapCache.PurgeFunc(func(key string) bool {
+		var z, x, y int
+		if n, _ := fmt.Sscanf(key, tileKeyFormat, &z, &x, &y); n != 3 {
+			return false
+		}
+		// Without the location every tile may be stale.
+		return err != nil || tileBox(z, x, y).contains(lat, lng)
+	})
+}
+
+const (
+	// tileLayer is the name of the layer holding the sightings.
+	tileLayer = "sightings"
+	// tileBuffer is how far past its edges, in tile units, a tile includes
+	// sightings so markers on the border are drawn on both sides.
+	tileBuffer = 64
+	// tileKeyFormat starts the cache key of tile z/x/y; the filter's
+	// cacheKey follows it.
+	tileKeyFormat = "tile:%d/%d/%d:"
+	// maxTileFeatures caps the sightings in one tile, newest first.
+	maxTileFeatures = 5000
+)
+
+// parseTilePath reads z, x and y from /api/tiles/{z}/{x}/{y}.mvt.
+func parseTilePath(p string) (z, x, y int, msg string) {
+	rest, ok := strings.CutSuffix(strings.TrimPrefix(p, "/api/tiles/"), ".mvt")
+	parts := strings.Split(rest, "/")
+	if !ok || len(parts) != 3 {
+		return 0, 0, 0, "Tile path must be /api/tiles/{z}/{x}/{y}.mvt"
+	}
+	var n [3]int
+	for i, s := range parts {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, 0, "Tile coordinates must be integers"
+		}
+		n[i] = v
+	}
+	z, x, y = n[0], n[1], n[2]
+	if z < 0 || z > maxZoom {
+		return 0, 0, 0, fmt.Sprintf("zoom must be between 0 and %d", maxZoom)
+	}
+	if x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return 0, 0, 0, fmt.Sprintf("x and y must be between 0 and %d at zoom %d", 1<<z-1, z)
+	}
+	return z, x, y, ""
+}
+
+// GET /api/tiles/{z}/{x}/{y}.mvt
+// The sightings in a web map tile as a Mapbox vector tile with one
+// "sightings" layer of points carrying id, species, category and
+// like_count. It takes the same filters as GET /api/sightings. Tiles are
+// cached until a sighting in them changes and carry an ETag for
+// conditional requests.
+func handleTile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	z, x, y, msg := parseTilePath(r.URL.Path)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	filter, msg := parseSightingFilter(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	key := fmt.Sprintf(tileKeyFormat+"%s", z, x, y, filter.cacheKey())
+	if e, ok := mapCache.Get(key); ok {
+		writeCached(w, r, mvt.ContentType, e)
+		return
+	}
+	gen := mapCache.Generation()
+	tile, err := renderTile(filter, z, x, y)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to render tile"})
+		return
+	}
+	e := mapcache.NewEntry(tile)
+	mapCache.Put(key, gen, e)
+	writeCached(w, r, mvt.ContentType, e)
+}
+
+// tileBox is the area whose sightings tile z/x/y holds: the tile plus its
+// buffer.
+func tileBox(z, x, y int) bbox {
+	minLng, minLat, maxLng, maxLat := mvt.TileBounds(z, x, y)
+	bufLng := (maxLng - minLng) * tileBuffer / mvt.DefaultExtent
+	bufLat := (maxLat - minLat) * tileBuffer / mvt.DefaultExtent
+	box := bbox{
+		minLng: max(-180, minLng-bufLng),
+		minLat: max(-mvt.MaxLatitude, minLat-bufLat),
+		maxLng: min(180, maxLng+bufLng),
+		maxLat: min(mvt.MaxLatitude, maxLat+bufLat),
+	}
+	// The northernmost and southernmost tiles also hold the sightings
+	// beyond the edge of the projection.
+	if y == 0 {
+		box.maxLat = 90
+	}
+	if y == 1<<z-1 {
+		box.minLat = -90
+	}
+	return box
+}
+
+// renderTile encodes the sightings matching filter in tile z/x/y.
+func renderTile(filter *sightingFilter, z, x, y int) ([]byte, error) {
+	cond, args := tileBox(z, x, y).cond()
+	filter.add(cond, args...)
+
+	args = append(filter.args, maxTileFeatures)
+	rows, err := database.DB.Query(`
+		SELECT a.id, a.species, COALESCE(a.category,''), COALESCE(lc.cnt,0) AS like_count,
+		       a.latitude, a.longitude
+		FROM animals a
+		LEFT JOIN (SELECT sighting_id, COUNT(*) AS cnt FROM sighting_likes GROUP BY sighting_id) lc
+		       ON lc.sighting_id = a.id
+		`+filter.where()+fmt.Sprintf(`
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $%d`, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	layer := mvt.NewLayer(tileLayer, mvt.DefaultExtent)
+	for rows.Next() {
+		var id, likes int
+		var species, category string
+		var lat, lng float64
+		if err := rows.Scan(&id, &species, &category, &likes, &lat, &lng); err != nil {
+			return nil, err
+		}
+		px, py := mvt.Project(lat, lng, z, x, y, mvt.DefaultExtent)
+		if err := layer.AddPoint(uint64(id), px, py,
+			mvt.Property{Key: "id", Value: id},
+			mvt.Property{Key: "species", Value: species},
+			mvt.Property{Key: "category", Value: category},
+			mvt.Property{Key: "like_count", Value: likes},
+		); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return mvt.Encode(layer), nil
+}
+
+// writeCached writes a cached map response, or 304 Not Modified when the
+// client already holds it. Clients revalidate every time so a change to a
+// sighting shows up on their next request.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType string, e mapcache.Entry) {
+	w.Header().Set("ETag", e.ETag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), e.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(e.Data)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == etag || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------- Heatmap ----------
+
+// heatmapCellPx is the width of a heatmap cell in screen pixels, fine
+// enough for a heat layer to blur the cells into a smooth surface.
+const heatmapCellPx = 16
+
+// heatmapWeights maps the weight parameter to the SQL for how much one
+// sighting adds to its cell.
+var heatmapWeights = map[string]string{
+	"count":    "1",
+	"quantity": "COALESCE(a.quantity,1)",
+}
+
+// GET /api/sightings/heatmap?min_lat=&min_lng=&max_lat=&max_lng=&zoom=&weight=
+// Sighting density in the viewport for a heat layer: the sightings are
+// binned into grid cells about 16 screen pixels wide at the map's zoom and
+// each cell is weighted by its number of sightings (weight=count, the
+// default) or the animals counted in them (weight=quantity). It takes the
+// same filters as GET /api/sightings, including observed_from and
+// observed_to. At most MAX_VIEWPORT_RESULTS cells are returned, heaviest
+// first. Responses are cached like vector tiles.
+func handleGetSightingHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	q := r.URL.Query()
+	box, msg := parseViewport(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	zoom, err := strconv.Atoi(q.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxZoom {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("zoom must be an integer between 0 and %d", maxZoom)})
+		return
+	}
+	weight := q.Get("weight")
+	if weight == "" {
+		weight = "count"
+	}
+	weightExpr, ok := heatmapWeights[weight]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "weight must be one of: count, quantity"})
+		return
+	}
+	filter, msg := parseSightingFilter(q)
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	key := fmt.Sprintf("heatmap:%d:%s:%g,%g,%g,%g:%s", zoom, weight,
+		box.minLng, box.minLat, box.maxLng, box.maxLat, filter.cacheKey())
+	if e, ok := mapCache.Get(key); ok {
+		writeCached(w, r, "application/json", e)
+		return
+	}
+	gen := mapCache.Generation()
+	cells, truncated, err := sightingHeatmap(filter, box, zoom, weightExpr, cfg.MaxViewportResults)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to build heatmap"})
+		return
+	}
+	// Cells come heaviest first; max lets clients scale the intensity.
+	maxWeight := 0
+	if len(cells) > 0 {
+		maxWeight = cells[0].Weight
+	}
+	body, err := json.Marshal(map[string]any{
+		"zoom":      zoom,
+		"weight":    weight,
+		"max":       maxWeight,
+		"cells":     cells,
+		"truncated": truncated,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to build heatmap"})
+		return
+	}
+	e := mapcache.NewEntry(body)
+	mapCache.Put(key, gen, e)
+	writeCached(w, r, "application/json", e)
+}
+
+// sightingHeatmap sums weightExpr over the sightings matching filter inside
+// box for each cell of the heatmap grid at zoom. It returns the limit
+// heaviest cells and whether there were more.
+func sightingHeatmap(filter *sightingFilter, box bbox, zoom int, weightExpr string, limit int) ([]models.HeatCell, bool, error) {
+	cond, args := box.cond()
+	filter.add(cond, args...)
+	nIdx, latIdx, limitIdx := len(filter.args)+1, len(filter.args)+2, len(filter.args)+3
+	args = append(filter.args, gridCells(zoom, heatmapCellPx), mercatorMaxLat, limit+1)
+	cellX, cellY := mercatorCell(nIdx, latIdx)
+
+	rows, err := database.DB.Query(`
+		SELECT AVG(latitude), AVG(longitude), SUM(w), COUNT(*)
+		FROM (
+			SELECT a.latitude, a.longitude, `+weightExpr+` AS w,
+			       `+cellX+` AS cx, `+cellY+` AS cy
+			FROM animals a
+			`+filter.where()+`
+		) pts
+		GROUP BY cx, cy
+		ORDER BY SUM(w) DESC, cx, cy
+		LIMIT $`+strconv.Itoa(limitIdx),
+		args...,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	cells := []models.HeatCell{}
+	for rows.Next() {
+		var c models.HeatCell
+		if err := rows.Scan(&c.Latitude, &c.Longitude, &c.Weight, &c.Count); err != nil {
+			return nil, false, err
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(cells) > limit {
+		return cells[:limit], true, nil
+	}
+	return cells, false, nil
+}
+
+// ──────────────────────────────────────────────
+// Friend system handlers
+// ──────────────────────────────────────────────
+
+// GET /api/users/search?username=X  — find a user by username
+func handleUserSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	username := strings.TrimSpace(r.URL.Query().Get("username"))
+	if username == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "username query param required"})
+		return
+	}
+	var id int
+	var uname string
+	err := database.DB.QueryRow("SELECT id, username FROM users WHERE username = $1", username).Scan(&id, &uname)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "username": uname})
+}
+
+// POST /api/friends/request  body: {requester_id, receiver_username}
+func handleFriendRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	var body struct {
+		RequesterID      int    `json:"requester_id"`
+		ReceiverUsername string `json:"receiver_username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ReceiverUsername == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "receiver_username required"})
+		return
+	}
+	requesterID, ok := actingUserID(w, r, body.RequesterID)
+	if !ok {
+		return
+	}
+	body.RequesterID = requesterID
+	var receiverID int
+	err := database.DB.QueryRow("SELECT id FROM users WHERE username = $1", body.ReceiverUsername).Scan(&receiverID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	if receiverID == body.RequesterID {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot add yourself"})
+		return
+	}
+	// Check if friendship already exists in either direction
+	var existing string
+	err = database.DB.QueryRow(
+		`SELECT status FROM friendships WHERE (requester_id=$1 AND receiver_id=$2) OR (requester_id=$2 AND receiver_id=$1)`,
+		body.RequesterID, receiverID,
+	).Scan(&existing)
+	if err == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "friendship already exists", "status": existing})
+		return
+	}
+	_, err = database.DB.Exec(
+		`INSERT INTO friendships (requester_id, receiver_id, status) VALUES ($1, $2, 'pending')`,
+		body.RequesterID, receiverID,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "pending"})
+}
+
+// GET /api/friends?user_id=N  — list accepted friends
+func handleFriendList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	userID, ok := queryActingUserID(w, r)
+	if !ok {
+		return
+	}
+	page, msg := friendKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	query, args := friendKeyset.paginate(
+		`SELECT f.id,
+			CASE WHEN f.requester_id=$1 THEN f.receiver_id ELSE f.requester_id END AS friend_id,
+			CASE WHEN f.requester_id=$1 THEN u2.username ELSE u1.username END AS friend_username
+		FROM friendships f
+		JOIN users u1 ON u1.id = f.requester_id
+		JOIN users u2 ON u2.id = f.receiver_id
+		WHERE (f.requester_id=$1 OR f.receiver_id=$1) AND f.status='accepted'`,
+		[]interface{}{userID}, page,
+	)
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	defer rows.Close()
+	type Friend struct {
+		FriendshipID int    `json:"friendship_id"`
+		FriendID     int    `json:"friend_id"`
+		Username     string `json:"username"`
+	}
+	friends := []Friend{}
+	for rows.Next() {
+		var f Friend
+		if err := rows.Scan(&f.FriendshipID, &f.FriendID, &f.Username); err == nil {
+			friends = append(friends, f)
+		}
+	}
+	writeCursorPage(w, friendKeyset, page, friends, func(f Friend) (any, int) { return nil, f.FriendshipID })
+}
+
+// Friends are listed in the order the friendships were made.
+var friendKeyset = keyset{Name: "friends", ID: "f.id"}
+
+// GET /api/friends/requests?user_id=N  — pending requests received by user
+func handleFriendRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	userID, ok := queryActingUserID(w, r)
+	if !ok {
+		return
+	}
+	page, msg := friendRequestKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+	query, args := friendRequestKeyset.paginate(
+		`SELECT f.id, f.requester_id, u.username, f.created_at
+		FROM friendships f
+		JOIN users u ON u.id = f.requester_id
+		WHERE f.receiver_id=$1 AND f.status='pending'`,
+		[]interface{}{userID}, page,
+	)
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	defer rows.Close()
+	type Request struct {
+		ID          int       `json:"id"`
+		RequesterID int       `json:"requester_id"`
+		Username    string    `json:"username"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	requests := []Request{}
+	for rows.Next() {
+		var req Request
+		if err := rows.Scan(&req.ID, &req.RequesterID, &req.Username, &req.CreatedAt); err == nil {
+			requests = append(requests, req)
+		}
+	}
+	writeCursorPage(w, friendRequestKeyset, page, requests, func(req Request) (any, int) { return req.CreatedAt, req.ID })
+}
+
+var friendRequestKeyset = keyset{Name: "friend_requests", Key: "f.created_at", ID: "f.id", Desc: true}
+
+// POST /api/friends/accept  body: {friendship_id, user_id}
+func handleFriendAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	var body struct {
+		FriendshipID int `json:"friendship_id"`
+		UserID       int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FriendshipID == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id required"})
+		return
+	}
+	userID, ok := actingUserID(w, r, body.UserID)
+	if !ok {
+		return
+	}
+	body.UserID = userID
+	res, err := database.DB.Exec(
+		`UPDATE friendships SET status='accepted' WHERE id=$1 AND receiver_id=$2 AND status='pending'`,
+		body.FriendshipID, body.UserID,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "accepted"})
+}
+
+// POST /api/friends/decline  body: {friendship_id, user_id}
+func handleFriendDecline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	var body struct {
+		FriendshipID int `json:"friendship_id"`
+		UserID       int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FriendshipID == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id required"})
+		return
+	}
+	userID, ok := actingUserID(w, r, body.UserID)
+	if !ok {
+		return
+	}
+	body.UserID = userID
+	res, err := database.DB.Exec(
+		`DELETE FROM friendships WHERE id=$1 AND (receiver_id=$2 OR requester_id=$2)`,
+		body.FriendshipID, body.UserID,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		return
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "declined"})
+}
+
+// POST /api/friends/remove  body: {friendship_id, user_id}
+func handleFriendRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	var body struct {
+		FriendshipID int `json:"friendship_id"`
+		UserID       int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FriendshipID == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id required"})
+		return
+	}
+	userID, ok := actingUserID(w, r, body.UserID)
+	if !ok {
+		return
+	}
+	body.UserID = userID
+	database.DB.Exec(
+		`DELETE FROM friendships WHERE id=$1 AND (requester_id=$2 OR receiver_id=$2)`,
+		body.FriendshipID, body.UserID,
+	)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// GET  /api/dm?user1=N&user2=N  — chat history
+// POST /api/dm  body: {sender_id, receiver_id, content}
+func handleDM(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		u1, err1 := strconv.Atoi(r.URL.Query().Get("user1"))
+		u2, err2 := strconv.Atoi(r.URL.Query().Get("user2"))
+		if err1 != nil || err2 != nil || u1 == 0 || u2 == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user1 and user2 required"})
+			return
+		}
+		// Only the two participants may read a conversation.
+		user, ok := currentUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+			return
+		}
+		if user.ID != u1 && user.ID != u2 {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Not a participant in this conversation"})
+			return
+		}
+		page, msg := dmKeyset.parse(r.URL.Query())
+		if msg != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+			return
+		}
+		query, args := dmKeyset.paginate(
+			`SELECT dm.id, dm.sender_id, u.username, dm.receiver_id, dm.content, dm.created_at
+			FROM direct_messages dm
+			JOIN users u ON u.id = dm.sender_id
+			WHERE ((dm.sender_id=$1 AND dm.receiver_id=$2) OR (dm.sender_id=$2 AND dm.receiver_id=$1))`,
+			[]interface{}{u1, u2}, page,
+		)
+		rows, err := database.DB.Query(query, args...)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		defer rows.Close()
+		type DM struct {
+			ID         int       `json:"id"`
+			SenderID   int       `json:"sender_id"`
+			SenderName string    `json:"sender_name"`
+			ReceiverID int       `json:"receiver_id"`
+			Content    string    `json:"content"`
+			CreatedAt  time.Time `json:"created_at"`
+		}
+		msgs := []DM{}
+		for rows.Next() {
+			var m DM
+			if err := rows.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.ReceiverID, &m.Content, &m.CreatedAt); err == nil {
+				msgs = append(msgs, m)
+			}
+		}
+		writeCursorPage(w, dmKeyset, page, msgs, func(m DM) (any, int) { return m.CreatedAt, m.ID })
+
+	case http.MethodPost:
+		var body struct {
+			SenderID   int    `json:"sender_id"`
+			ReceiverID int    `json:"receiver_id"`
+			Content    string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ReceiverID == 0 || strings.TrimSpace(body.Content) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "receiver_id and content required"})
+			return
+		}
+		senderID, ok := actingUserID(w, r, body.SenderID)
+		if !ok {
+			return
+		}
+		body.SenderID = senderID
+		var id int
+		err := database.DB.QueryRow(
+			`INSERT INTO direct_messages (sender_id, receiver_id, content) VALUES ($1, $2, $3) RETURNING id`,
+			body.SenderID, body.ReceiverID, body.Content,
+		).Scan(&id)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]int{"id": id})
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	}
+}
+
+// Conversations are paged newest first, so the first page holds the
+// latest messages.
+var dmKeyset = keyset{Name: "dm", Key: "dm.created_at", ID: "dm.id", Desc: true}
+
+// ---------- Area Channels (Group Chat) ----------
+
+func handleGetChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.name, COALESCE(c.description,''), c.creator_id, u.username, c.created_at,
+		       (SELECT COUNT(*) FROM area_messages m WHERE m.channel_id = c.id) AS msg_count
+		FROM area_channels c
+		JOIN users u ON u.id = c.creator_id
+		ORDER BY c.created_at DESC`)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query channels"})
+		return
+	}
+	defer rows.Close()
+
+	type Channel struct {
+		ID           int       `json:"id"`
+		Name         string    `json:"name"`
+		Description  string    `json:"description"`
+		CreatorID    int       `json:"creator_id"`
+		CreatorName  string    `json:"creator_name"`
+		CreatedAt    time.Time `json:"created_at"`
+		MessageCount int       `json:"message_count"`
+	}
+	channels := []Channel{}
+	for rows.Next() {
+		var c Channel
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatorID, &c.CreatorName, &c.CreatedAt, &c.MessageCount); err == nil {
+			channels = append(channels, c)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, channels)
+}
+
+func handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		CreatorID   int    `json:"creator_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Channel name is required"})
+		return
+	}
+	if len(req.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Channel name too long (max 100 characters)"})
+		return
+	}
+	creatorID, ok := actingUserID(w, r, req.CreatorID)
+	if !ok {
+		return
+	}
+
+	var id int
+	err := database.DB.QueryRow(
+		"INSERT INTO area_channels (name, description, creator_id) VALUES ($1, $2, $3) RETURNING id",
+		strings.TrimSpace(req.Name), strings.TrimSpace(req.Description), creatorID,
+	).Scan(&id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create channel"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+func handleGetChannelMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/")
+	if len(parts) < 2 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid path"})
+		return
+	}
+	channelID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid channel ID"})
+		return
+	}
+
+	page, msg := channelMessageKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	query, args := channelMessageKeyset.paginate(`
+		SELECT m.id, m.channel_id, m.sender_id, u.username, m.content, m.created_at
+		FROM area_messages m
+		JOIN users u ON u.id = m.sender_id
+		WHERE m.channel_id = $1`, []interface{}{channelID}, page)
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query messages"})
+		return
+	}
+	defer rows.Close()
+
+	type Msg struct {
+		ID        int       `json:"id"`
+		ChannelID int       `json:"channel_id"`
+		SenderID  int       `json:"sender_id"`
+		Sender    string    `json:"sender"`
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	msgs := []Msg{}
+	for rows.Next() {
+		var m Msg
+		if err := rows.Scan(&m.ID, &m.ChannelID, &m.SenderID, &m.Sender, &m.Content, &m.CreatedAt); err == nil {
+			msgs = append(msgs, m)
+		}
+	}
+
+	writeCursorPage(w, channelMessageKeyset, page, msgs, func(m Msg) (any, int) { return m.CreatedAt, m.ID })
+}
+
+// Like conversations, channels are paged newest first.
+var channelMessageKeyset = keyset{Name: "channel_messages", Key: "m.created_at", ID: "m.id", Desc: true}
+
+func handleCreateChannelMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/")
+	if len(parts) < 2 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid path"})
+		return
+	}
+	channelID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid channel ID"})
+		return
+	}
+
+	var req struct {
+		SenderID int    `json:"sender_id"`
+		Content  string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Content is required"})
+		return
+	}
+	if len(req.Content) > 2000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Message too long (max 2000 characters)"})
+		return
+	}
+
+	senderID, ok := actingUserID(w, r, req.SenderID)
+	if !ok {
+		return
+	}
+
+	var id int
+	err = database.DB.QueryRow(
+		"INSERT INTO area_messages (channel_id, sender_id, content) VALUES ($1, $2, $3) RETURNING id",
+		channelID, senderID, strings.TrimSpace(req.Content),
+	).Scan(&id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create message"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+func handleChannelsRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/channels")
+	path = strings.TrimSuffix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetChannels(w, r)
+		case http.MethodPost:
+			handleCreateChannel(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	// /api/channels/{id}/messages
+	if strings.Contains(path, "/messages") {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetChannelMessages(w, r)
+		case http.MethodPost:
+			handleCreateChannelMessage(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+}
+
+// ---------- Subscriptions & Notifications ----------
+
+func handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	var req struct {
+		UserID int    `json:"user_id"`
+		Type   string `json:"type"`
+		Value  string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	validType := map[string]bool{"species": true, "category": true, "area": true}
+	if !validType[req.Type] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be one of: species, category, area"})
+		return
+	}
+
+	if strings.TrimSpace(req.Value) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "value is required"})
+		return
+	}
+
+	userID, ok := actingUserID(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	var existing int
+	err := database.DB.QueryRow(
+		"SELECT 1 FROM subscriptions WHERE user_id = $1 AND type = $2 AND value = $3",
+		req.UserID, req.Type, req.Value,
+	).Scan(&existing)
+	if err == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "Already subscribed"})
+		return
+	}
+
+	// Species subscriptions follow the taxon, so "Grus canadensis" also
+	// matches sightings logged as "Sandhill Crane".
+	var taxonID *int
+	if req.Type == "species" {
+		if id, ok := matchTaxon(req.Value); ok {
+			taxonID = &id
+		}
+	}
+
+	var id int
+	err = database.DB.QueryRow(
+		"INSERT INTO subscriptions (user_id, type, value, taxon_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		req.UserID, req.Type, strings.TrimSpace(req.Value), taxonID,
+	).Scan(&id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create subscription"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+func handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	userID, ok := queryActingUserID(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.Query(
+		"SELECT id, user_id, type, value, created_at FROM subscriptions WHERE user_id = $1 ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query subscriptions"})
+		return
+	}
+	defer rows.Close()
+
+	type Sub struct {
+		ID        int       `json:"id"`
+		UserID    int       `json:"user_id"`
+		Type      string    `json:"type"`
+		Value     string    `json:"value"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	subs := []Sub{}
+	for rows.Next() {
+		var s Sub
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Type, &s.Value, &s.CreatedAt); err == nil {
+			subs = append(subs, s)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid subscription ID"})
+		return
+	}
+
+	userID, ok := actingUserID(w, r, 0)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM subscriptions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete subscription"})
+		return
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Subscription not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	userID, ok := queryActingUserID(w, r)
+	if !ok {
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	var query string
+	var args []interface{}
+	if unreadOnly {
+		query = `
+			SELECT n.id, n.user_id, n.sighting_id, n.subscription_id, n.message, n.is_read, n.created_at
+			FROM notifications n
+			WHERE n.user_id = $1 AND n.is_read = FALSE
+			ORDER BY n.created_at DESC LIMIT 50`
+		args = []interface{}{userID}
+	} else {
+		query = `
+			SELECT n.id, n.user_id, n.sighting_id, n.subscription_id, n.message, n.is_read, n.created_at
+			FROM notifications n
+			WHERE n.user_id = $1
+			ORDER BY n.created_at DESC LIMIT 50`
+		args = []interface{}{userID}
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query notifications"})
+		return
+	}
+	defer rows.Close()
+
+	type Notif struct {
+		ID             int       `json:"id"`
+		UserID         int       `json:"user_id"`
+		SightingID     int       `json:"sighting_id"`
+		SubscriptionID int       `json:"subscription_id"`
+		Message        string    `json:"message"`
+		IsRead         bool      `json:"is_read"`
+		CreatedAt      time.Time `json:"created_at"`
+	}
+	notifs := []Notif{}
+	for rows.Next() {
+		var n Notif
+		if err := rows.Scan(&n.ID, &n.UserID, &n.SightingID, &n.SubscriptionID, &n.Message, &n.IsRead, &n.CreatedAt); err == nil {
+			notifs = append(notifs, n)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, notifs)
+}
+
+func handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/notifications/")
+	idStr = strings.TrimSuffix(idStr, "/read")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid notification ID"})
+		return
+	}
+
+	userID, ok := actingUserID(w, r, 0)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec("UPDATE notifications SET is_read = TRUE WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update notification"})
+		return
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Notification not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "read"})
+}
+
+func triggerNotifications(sightingID int, species string, taxonID *int, category string, lat, lng float64) {
+	rows, err := database.DB.Query(
+		`SELECT id, user_id, type, value FROM subscriptions
+		 WHERE (type = 'species' AND (value = $1 OR taxon_id = $3))
+		    OR (type = 'category' AND value = $2)`,
+		species, category, taxonID,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subID, userID int
+		var subType, subValue string
+		if err := rows.Scan(&subID, &userID, &subType, &subValue); err != nil {
+			continue
+		}
+		msg := fmt.Sprintf("New %s sighting: %s spotted nearby!", category, species)
+		database.DB.Exec(
+			"INSERT INTO notifications (user_id, sighting_id, subscription_id, message) VALUES ($1, $2, $3, $4)",
+			userID, sightingID, subID, msg,
+		)
+	}
+}
+
+func handleSubscriptionsRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/subscriptions")
+	path = strings.TrimSuffix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetSubscriptions(w, r)
+		case http.MethodPost:
+			handleCreateSubscription(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	handleDeleteSubscription(w, r)
+}
+
+func handleNotificationsRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/notifications")
+	path = strings.TrimSuffix(path, "/")
+
+	if path == "" {
+		handleGetNotifications(w, r)
+		return
+	}
+
+	if strings.HasSuffix(path, "/read") {
+		handleMarkNotificationRead(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+}
+
+// ---------- Reports ----------
+
+func handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	var req struct {
+		SightingID int    `json:"sighting_id"`
+		ReporterID int    `json:"reporter_id"`
+		Reason     string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if req.SightingID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sighting_id is required"})
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason is required"})
+		return
+	}
+	if len(req.Reason) > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason too long (max 1000 characters)"})
+		return
+	}
+
+	reporterID, ok := actingUserID(w, r, req.ReporterID)
+	if !ok {
+		return
+	}
+	req.ReporterID = reporterID
+
+	var existing int
+	err := database.DB.QueryRow(
+		"SELECT 1 FROM reports WHERE sighting_id = $1 AND reporter_id = $2 AND status = 'pending'",
+		req.SightingID, req.ReporterID,
+	).Scan(&existing)
+	if err == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "You have already reported this sighting"})
+		return
+	}
+
+	var id int
+	err = database.DB.QueryRow(
+		"INSERT INTO reports (sighting_id, reporter_id, reason) VALUES ($1, $2, $3) RETURNING id",
+		req.SightingID, req.ReporterID, strings.TrimSpace(req.Reason),
+	).Scan(&id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create report"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+}
+
+func handleGetReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	if !requireRole(w, r, models.RoleModerator, models.RoleAdmin) {
+		return
+	}
+
+	page, msg := reportKeyset.parse(r.URL.Query())
+	if msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	query := `
+		SELECT r.id, r.sighting_id, r.reporter_id, u.username, r.reason, r.status,
+		       COALESCE(r.admin_note,''), r.created_at, r.resolved_at
+		FROM reports r
+		JOIN users u ON u.id = r.reporter_id
+		WHERE TRUE`
+	var args []interface{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND r.status = $1"
+		args = append(args, status)
+	}
+	query, args = reportKeyset.paginate(query, args, page)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query reports"})
+		return
+	}
+	defer rows.Close()
+
+	type Report struct {
+		ID         int        `json:"id"`
+		SightingID int        `json:"sighting_id"`
+		ReporterID int        `json:"reporter_id"`
+		Reporter   string     `json:"reporter"`
+		Reason     string     `json:"reason"`
+		Status     string     `json:"status"`
+		AdminNote  string     `json:"admin_note"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ResolvedAt *time.Time `json:"resolved_at"`
+	}
+	reports := []Report{}
+	for rows.Next() {
+		var rpt Report
+		if err := rows.Scan(&rpt.ID, &rpt.SightingID, &rpt.ReporterID, &rpt.Reporter,
+			&rpt.Reason, &rpt.Status, &rpt.AdminNote, &rpt.CreatedAt, &rpt.ResolvedAt); err == nil {
+			reports = append(reports, rpt)
+		}
+	}
+
+	writeCursorPage(w, reportKeyset, page, reports, func(rpt Report) (any, int) { return rpt.CreatedAt, rpt.ID })
+}
+
+var reportKeyset = keyset{Name: "reports", Key: "r.created_at", ID: "r.id", Desc: true}
+
+func handleUpdateReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/reports/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid report ID"})
+		return
+	}
+
+	var req struct {
+		Status    string `json:"status"`
+		AdminNote string `json:"admin_note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	validStatus := map[string]bool{"resolved": true, "dismissed": true, "pending": true}
+	if !validStatus[req.Status] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be one of: pending, resolved, dismissed"})
+		return
+	}
+
+	if !requireRole(w, r, models.RoleModerator, models.RoleAdmin) {
+		return
+	}
+
+	var resolvedAt interface{}
+	if req.Status == "resolved" || req.Status == "dismissed" {
+		resolvedAt = time.Now()
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE reports SET status = $1, admin_note = $2, resolved_at = $3 WHERE id = $4",
+		req.Status, req.AdminNote, resolvedAt, id,
+	)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update report"})
+		return
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Report not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": req.Status})
+}
+
+func handleReportsRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/reports")
+	path = strings.TrimSuffix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetReports(w, r)
+		case http.MethodPost:
+			handleCreateReport(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
+		return
+	}
+
+	handleUpdateReport(w, r)
+}
+
+// ---------- Leaderboard ----------
+
+// speciesKey groups sightings by species: a taxon counts once however it
+// was spelled, and names that matched no taxon count by their lower-cased
+// text.
+const speciesKey = "COALESCE('taxon:' || a.taxon_id, 'name:' || LOWER(TRIM(a.species)))"
+
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "sightings"
+	}
+
+	validSort := map[string]bool{"sightings": true, "species": true, "likes": true}
+	if !validSort[sortBy] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be one of: sightings, species, likes"})
+		return
+	}
+
+	var dateFilter string
+	switch period {
+	case "week":
+		dateFilter = "AND a.created_at >= NOW() - INTERVAL '7 days'"
+	case "month":
+		dateFilter = "AND a.created_at >= NOW() - INTERVAL '30 days'"
+	default:
+		dateFilter = ""
+	}
+
+	var query string
+	switch sortBy {
+	case "sightings":
+		query = fmt.Sprintf(`
+			SELECT u.id, u.username, COUNT(a.id) AS score
+			FROM users u
+			LEFT JOIN animals a ON a.user_id = u.id %s
+			GROUP BY u.id, u.username
+			HAVING COUNT(a.id) > 0
+			ORDER BY score DESC
+			LIMIT 20`, dateFilter)
+	case "species":
+		query = fmt.Sprintf(`
+			SELECT u.id, u.username, COUNT(DISTINCT %s) AS score
+			FROM users u
+			LEFT JOIN animals a ON a.user_id = u.id %s
+			GROUP BY u.id, u.username
+			HAVING COUNT(a.id) > 0
+			ORDER BY score DESC
+			LIMIT 20`, speciesKey, dateFilter)
+	case "likes":
+		query = fmt.Sprintf(`
+			SELECT u.id, u.username, COUNT(sl.sighting_id) AS score
+			FROM users u
+			JOIN animals a ON a.user_id = u.id %s
+			JOIN sighting_likes sl ON sl.sighting_id = a.id
+			GROUP BY u.id, u.username
+			HAVING COUNT(sl.sighting_id) > 0
+			ORDER BY score DESC
+			LIMIT 20`, dateFilter)
+	}
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query leaderboard"})
+		return
+	}
+	defer rows.Close()
+
+	type Entry struct {
+		Rank     int    `json:"rank"`
+		UserID   int    `json:"user_id"`
+		Username string `json:"username"`
+		Score    int    `json:"score"`
+	}
+	entries := []Entry{}
+	rank := 1
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.UserID, &e.Username, &e.Score); err == nil {
+			e.Rank = rank
+			entries = append(entries, e)
+			rank++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"period":  period,
+		"sort_by": sortBy,
+		"entries": entries,
+	})
+}
+
+// ---------- Change Password ----------
+
+func validatePasswordStrength(password string) string {
+	if len(password) < 8 {
+		return "Password must be at least 8 characters"
+	}
+	var hasUpper, hasLower, hasDigit bool
+	for _, ch := range password {
+		if unicode.IsUpper(ch) {
+			hasUpper = true
+		} else if unicode.IsLower(ch) {
+			hasLower = true
+		} else if unicode.IsDigit(ch) {
+			hasDigit = true
+		}
+	}
+	if !hasUpper {
+		return "Password must contain at least one uppercase letter"
+	}
+	if !hasLower {
+		return "Password must contain at least one lowercase letter"
+	}
+	if !hasDigit {
+		return "Password must contain at least one digit"
+	}
+	return ""
+}
+
+func handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+
+	var req struct {
+		UserID      int    `json:"user_id"`
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if req.OldPassword == "" || req.NewPassword == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "old_password and new_password are required"})
+		return
+	}
+
+	if req.OldPassword == req.NewPassword {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "New password must be different from old password"})
+		return
+	}
+
+	if msg := validatePasswordStrength(req.NewPassword); msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
+		return
+	}
+
+	userID, ok := actingUserID(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
 
-func handleSightings(w http.ResponseWriter, r *http.Request) {
-	// Route /api/sightings, /api/sightings/nearby, /api/sightings/{id},
-	// /api/sightings/{id}/messages, /api/sightings/{id}/like(s)
-	path := strings.TrimPrefix(r.URL.Path, "/api/sightings")
-	path = strings.TrimPrefix(path, "/")
+	var storedHash string
+	err := database.DB.QueryRow("SELECT password FROM users WHERE id = $1", req.UserID).Scan(&storedHash)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
 
-	if path == "" {
-		// Collection routes
-		switch r.Method {
-		case http.MethodGet:
-			handleGetSightings(w, r)
-		case http.MethodPost:
-			handleCreateSighting(w, r)
-		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(req.OldPassword)); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Old password is incorrect"})
+		return
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
+		return
+	}
+
+	if _, err := database.DB.Exec("UPDATE users SET password = $1 WHERE id = $2", string(newHash), req.UserID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update password"})
+		return
+	}
+
+	// Sign every device out, then hand this one a fresh session.
+	if err := revokeAllSessions(req.UserID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to revoke sessions"})
+		return
+	}
+	user, _ := currentUser(r)
+	token, refreshToken, err := issueTokens(user.ID, user.Email, user.Role)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":        "password changed",
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+// ---------- Roles ----------
+
+// isBootstrapAdmin reports whether the email is listed in BOOTSTRAP_ADMIN_EMAILS.
+func isBootstrapAdmin(email string) bool {
+	for _, e := range cfg.BootstrapAdminEmails {
+		if strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// bootstrapAdmins promotes existing accounts listed in BOOTSTRAP_ADMIN_EMAILS
+// so the first admin can be created without touching the database by hand.
+func bootstrapAdmins() {
+	for _, e := range cfg.BootstrapAdminEmails {
+		if _, err := database.DB.Exec("UPDATE users SET role = $1 WHERE LOWER(email) = LOWER($2)", models.RoleAdmin, e); err != nil {
+			log.Printf("Warning: failed to bootstrap admin %s — %v", e, err)
 		}
+	}
+}
+
+// PUT /api/users/{id}/role  body: {role}  — admin only
+func handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	// /api/sightings/nearby
-	if path == "nearby" {
-		handleGetNearbySightings(w, r)
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/role")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 		return
 	}
 
-	// Check sub-paths: {id}/messages, {id}/like, {id}/likes
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) == 2 {
-		switch parts[1] {
-		case "messages":
-			switch r.Method {
-			case http.MethodGet:
-				handleGetComments(w, r)
-			case http.MethodPost:
-				handleCreateComment(w, r)
-			default:
-				writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if !validRole(req.Role) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role must be one of: user, moderator, admin"})
+		return
+	}
+
+	if !requireRole(w, r, models.RoleAdmin) {
+		return
+	}
+	if user, _ := currentUser(r); user.ID == id {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Admins cannot change their own role"})
+		return
+	}
+
+	result, err := database.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", req.Role, id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update role"})
+		return
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "role": req.Role})
+}
+
+// Router for /api/users/{id}/...
+func handleUsersRouter(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/role") {
+		handleSetUserRole(w, r)
+		return
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+}
+
+// ---------- Institutions & Invites ----------
+
+// emailDomain returns the lower-cased part after "@", or "" if email is malformed.
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// institutionDomains mirrors institutions.domain so signup and login can
+// check a domain without a query. main loads it at startup and
+// handleInstitutions adds each new institution.
+var institutionDomains = struct {
+	sync.RWMutex
+	list []string
+}{}
+
+// loadInstitutionDomains reads every institution's domain.
+func loadInstitutionDomains() {
+	rows, err := database.DB.Query("SELECT domain FROM institutions ORDER BY domain")
+	if err != nil {
+		log.Fatal("Error loading institution domains:", err)
+	}
+	defer rows.Close()
+	var domains []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			log.Fatal("Error loading institution domains:", err)
+		}
+		domains = append(domains, d)
+	}
+	institutionDomains.Lock()
+	institutionDomains.list = domains
+	institutionDomains.Unlock()
+}
+
+// allowedDomains is the allow-list: the configured domains followed by any
+// other institution's domain.
+func allowedDomains() []string {
+	domains := slices.Clone(cfg.EmailDomains)
+	institutionDomains.RLock()
+	defer institutionDomains.RUnlock()
+	for _, d := range institutionDomains.list {
+		if !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// emailDomainAllowed reports whether email is on an allowed domain or one of
+// its subdomains, so "ufl.edu" also admits "cise.ufl.edu".
+func emailDomainAllowed(email string) bool {
+	domain := emailDomain(email)
+	if domain == "" {
+		return false
+	}
+	for _, d := range allowedDomains() {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedDomainsText renders the allow-list for error messages.
+func allowedDomainsText() string {
+	domains := allowedDomains()
+	parts := make([]string, len(domains))
+	for i, d := range domains {
+		parts[i] = "@" + d
+	}
+	return strings.Join(parts, ", ")
+}
+
+// generateInviteCode returns a code short enough to type, e.g. "K3QF-7ZPA-M2XD-H9TB".
+func generateInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(b)
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeInviteCode makes redemption ignore case, spaces and dashes.
+func normalizeInviteCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// GET  /api/institutions
+// POST /api/institutions  body: {name, domain}  — admin only
+func handleInstitutions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := database.DB.Query("SELECT id, name, domain FROM institutions ORDER BY name")
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch institutions"})
+			return
+		}
+		defer rows.Close()
+
+		institutions := []models.Institution{}
+		for rows.Next() {
+			var inst models.Institution
+			if err := rows.Scan(&inst.ID, &inst.Name, &inst.Domain); err != nil {
+				continue
 			}
+			institutions = append(institutions, inst)
+		}
+		writeJSON(w, http.StatusOK, institutions)
+
+	case http.MethodPost:
+		var req models.Institution
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 			return
-		case "like":
-			handleToggleLike(w, r)
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		req.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(req.Domain)), "@")
+		if req.Name == "" || req.Domain == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name and domain are required"})
 			return
-		case "likes":
-			handleGetLikes(w, r)
+		}
+		if !strings.Contains(req.Domain, ".") || strings.ContainsAny(req.Domain, "@ ") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "domain must look like ufl.edu"})
 			return
 		}
-	}
 
-	// Individual resource routes: /api/sightings/{id}
-	switch r.Method {
-	case http.MethodPut:
-		handleUpdateSighting(w, r)
-	case http.MethodDelete:
-		handleDeleteSighting(w, r)
+		if !requireRole(w, r, models.RoleAdmin) {
+			return
+		}
+
+		err := database.DB.QueryRow(
+			"INSERT INTO institutions (name, domain) VALUES ($1, $2) RETURNING id",
+			req.Name, req.Domain,
+		).Scan(&req.ID)
+		if err != nil {
+			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "An institution with that domain already exists"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create institution"})
+			return
+		}
+		institutionDomains.Lock()
+		institutionDomains.list = append(institutionDomains.list, req.Domain)
+		institutionDomains.Unlock()
+		writeJSON(w, http.StatusCreated, req)
+
 	default:
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 	}
 }
 
-// ──────────────────────────────────────────────
-// Friend system handlers
-// ──────────────────────────────────────────────
+type createInviteRequest struct {
+	Email          string `json:"email"`
+	InstitutionID  *int   `json:"institution_id"`
+	MaxUses        int    `json:"max_uses"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+}
 
-// GET /api/users/search?username=X  — find a user by username
-func handleUserSearch(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// POST /api/invites  body: {email?, institution_id?, max_uses?, expires_in_hours?}  — admin only
+// The plain code is only returned here; the database keeps its hash.
+func handleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	var req createInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-	username := strings.TrimSpace(r.URL.Query().Get("username"))
-	if username == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "username query param required"})
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && emailDomain(req.Email) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
 		return
 	}
-	var id int
-	var uname string
-	err := database.DB.QueryRow("SELECT id, username FROM users WHERE username = $1", username).Scan(&id, &uname)
-	if err == sql.ErrNoRows {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 1 || req.MaxUses > 1000 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_uses must be between 1 and 1000"})
+		return
+	}
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = 7 * 24
+	}
+	if req.ExpiresInHours < 1 || req.ExpiresInHours > 365*24 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_in_hours must be between 1 and 8760"})
 		return
 	}
+
+	if !requireRole(w, r, models.RoleAdmin) {
+		return
+	}
+	admin, _ := currentUser(r)
+
+	code, err := generateInviteCode()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create invite"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "username": uname})
+
+	var email *string
+	if req.Email != "" {
+		email = &req.Email
+	}
+
+	var id int
+	var expiresAt time.Time
+	err = database.DB.QueryRow(`
+		INSERT INTO invite_codes (code_hash, email, institution_id, max_uses, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6 * INTERVAL '1 hour')
+		RETURNING id, expires_at`,
+		hashToken(normalizeInviteCode(code)), email, req.InstitutionID, req.MaxUses, admin.ID, req.ExpiresInHours,
+	).Scan(&id, &expiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Institution not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create invite"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id":             id,
+		"code":           code,
+		"email":          email,
+		"institution_id": req.InstitutionID,
+		"max_uses":       req.MaxUses,
+		"expires_at":     expiresAt,
+	})
 }
 
-// POST /api/friends/request  body: {requester_id, receiver_username}
-func handleFriendRequest(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// GET /api/invites  — admin only
+func handleGetInvites(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, models.RoleAdmin) {
 		return
 	}
-	var body struct {
-		RequesterID      int    `json:"requester_id"`
-		ReceiverUsername string `json:"receiver_username"`
+
+	rows, err := database.DB.Query(`
+		SELECT id, email, institution_id, max_uses, uses, created_by, expires_at, revoked_at IS NOT NULL, created_at
+		FROM invite_codes ORDER BY created_at DESC`)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch invites"})
+		return
+	}
+	defer rows.Close()
+
+	invites := []map[string]any{}
+	for rows.Next() {
+		var id, maxUses, uses, createdBy int
+		var email *string
+		var institutionID *int
+		var expiresAt, createdAt time.Time
+		var revoked bool
+		if err := rows.Scan(&id, &email, &institutionID, &maxUses, &uses, &createdBy, &expiresAt, &revoked, &createdAt); err != nil {
+			continue
+		}
+		invites = append(invites, map[string]any{
+			"id":             id,
+			"email":          email,
+			"institution_id": institutionID,
+			"max_uses":       maxUses,
+			"uses":           uses,
+			"created_by":     createdBy,
+			"expires_at":     expiresAt,
+			"revoked":        revoked,
+			"created_at":     createdAt,
+		})
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RequesterID == 0 || body.ReceiverUsername == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "requester_id and receiver_username required"})
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// DELETE /api/invites/{id}  — admin only; revokes the code
+func handleRevokeInvite(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/invites/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid invite ID"})
 		return
 	}
-	var receiverID int
-	err := database.DB.QueryRow("SELECT id FROM users WHERE username = $1", body.ReceiverUsername).Scan(&receiverID)
-	if err == sql.ErrNoRows {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+
+	if !requireRole(w, r, models.RoleAdmin) {
 		return
 	}
+
+	result, err := database.DB.Exec("UPDATE invite_codes SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to revoke invite"})
 		return
 	}
-	if receiverID == body.RequesterID {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot add yourself"})
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Invite not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// Router for /api/invites and /api/invites/{id}
+func handleInvitesRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/invites")
+	path = strings.TrimSuffix(path, "/")
+
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetInvites(w, r)
+		case http.MethodPost:
+			handleCreateInvite(w, r)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		}
 		return
 	}
-	// Check if friendship already exists in either direction
-	var existing string
-	err = database.DB.QueryRow(
-		`SELECT status FROM friendships WHERE (requester_id=$1 AND receiver_id=$2) OR (requester_id=$2 AND receiver_id=$1)`,
-		body.RequesterID, receiverID,
-	).Scan(&existing)
-	if err == nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "friendship already exists", "status": existing})
+
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
+	handleRevokeInvite(w, r)
+}
+
+// ---------- Password Reset ----------
+
+// appMailer delivers reset and verification links; set in main from MAIL_DRIVER.
+var appMailer mailer.Mailer = &mailer.OutboxMailer{Dir: "outbox"}
+
+const passwordResetTTL = time.Hour
+
+// generateToken returns a random URL-safe token to hand to the user.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken is what gets stored, so a database leak does not expose live tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createUserToken stores the hash of a fresh token in table (password_resets,
+// email_verifications or refresh_tokens) and returns the plain token.
+func createUserToken(table string, userID int, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
 	_, err = database.DB.Exec(
-		`INSERT INTO friendships (requester_id, receiver_id, status) VALUES ($1, $2, 'pending')`,
-		body.RequesterID, receiverID,
+		"INSERT INTO "+table+" (user_id, token_hash, expires_at) VALUES ($1, $2, NOW() + $3 * INTERVAL '1 second')",
+		userID, hashToken(token), int(ttl.Seconds()),
 	)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
-		return
+		return "", err
 	}
-	writeJSON(w, http.StatusCreated, map[string]string{"status": "pending"})
+	return token, nil
 }
 
-// GET /api/friends?user_id=N  — list accepted friends
-func handleFriendList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// POST /api/password/forgot  body: {email}
+func handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "valid user_id required"})
+
+	var req models.ForgetPassword
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-	rows, err := database.DB.Query(
-		`SELECT f.id,
-			CASE WHEN f.requester_id=$1 THEN f.receiver_id ELSE f.requester_id END AS friend_id,
-			CASE WHEN f.requester_id=$1 THEN u2.username ELSE u1.username END AS friend_username
-		FROM friendships f
-		JOIN users u1 ON u1.id = f.requester_id
-		JOIN users u2 ON u2.id = f.receiver_id
-		WHERE (f.requester_id=$1 OR f.receiver_id=$1) AND f.status='accepted'`,
-		userID,
-	)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Email is required"})
 		return
 	}
-	defer rows.Close()
-	type Friend struct {
-		FriendshipID int    `json:"friendship_id"`
-		FriendID     int    `json:"friend_id"`
-		Username     string `json:"username"`
-	}
-	friends := []Friend{}
-	for rows.Next() {
-		var f Friend
-		if err := rows.Scan(&f.FriendshipID, &f.FriendID, &f.Username); err == nil {
-			friends = append(friends, f)
-		}
+	if emailDomain(req.Email) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid email address"})
+		return
 	}
-	writeJSON(w, http.StatusOK, friends)
+
+	// Respond the same way, and equally fast, whether or not the account
+	// exists: the lookup and the email happen after the response.
+	go sendPasswordReset(req.Email)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "If that account exists, a password reset link has been sent"})
 }
 
-// GET /api/friends/requests?user_id=N  — pending requests received by user
-func handleFriendRequests(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// sendPasswordReset emails a reset link to the account with this email, if
+// there is one. Failures are only logged so callers cannot tell accounts
+// apart.
+func sendPasswordReset(email string) {
+	var userID int
+	err := database.DB.QueryRow("SELECT id FROM users WHERE email = $1", email).Scan(&userID)
+	if err == sql.ErrNoRows {
 		return
 	}
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "valid user_id required"})
+	if err != nil {
+		log.Printf("Failed to look up %s for password reset: %v", email, err)
 		return
 	}
-	rows, err := database.DB.Query(
-		`SELECT f.id, f.requester_id, u.username, f.created_at
-		FROM friendships f
-		JOIN users u ON u.id = f.requester_id
-		WHERE f.receiver_id=$1 AND f.status='pending'
-		ORDER BY f.created_at DESC`,
-		userID,
-	)
+
+	token, err := createUserToken("password_resets", userID, passwordResetTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		log.Printf("Failed to create password reset token for user %d: %v", userID, err)
 		return
 	}
-	defer rows.Close()
-	type Request struct {
-		ID          int       `json:"id"`
-		RequesterID int       `json:"requester_id"`
-		Username    string    `json:"username"`
-		CreatedAt   time.Time `json:"created_at"`
+
+	resetURL := cfg.PasswordResetURL
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Reset your UF Wildlife password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your UF Wildlife account.\n\n"+
+			"Use this link within %d minutes to choose a new password:\n%s?token=%s\n\n"+
+			"If you did not ask for this, you can ignore this email.",
+			int(passwordResetTTL.Minutes()), resetURL, token),
 	}
-	requests := []Request{}
-	for rows.Next() {
-		var req Request
-		if err := rows.Scan(&req.ID, &req.RequesterID, &req.Username, &req.CreatedAt); err == nil {
-			requests = append(requests, req)
-		}
+	if err := appMailer.Send(msg); err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", email, err)
 	}
-	writeJSON(w, http.StatusOK, requests)
 }
 
-// POST /api/friends/accept  body: {friendship_id, user_id}
-func handleFriendAccept(w http.ResponseWriter, r *http.Request) {
+// POST /api/password/reset  body: {token, new_password}
+func handleResetPassword(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
-	var body struct {
-		FriendshipID int `json:"friendship_id"`
-		UserID       int `json:"user_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FriendshipID == 0 || body.UserID == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id and user_id required"})
+
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-	res, err := database.DB.Exec(
-		`UPDATE friendships SET status='accepted' WHERE id=$1 AND receiver_id=$2 AND status='pending'`,
-		body.FriendshipID, body.UserID,
-	)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+
+	if req.Token == "" || req.NewPassword == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token and new_password are required"})
 		return
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+	if msg := validatePasswordStrength(req.NewPassword); msg != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "accepted"})
-}
 
-// POST /api/friends/decline  body: {friendship_id, user_id}
-func handleFriendDecline(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
 		return
 	}
-	var body struct {
-		FriendshipID int `json:"friendship_id"`
-		UserID       int `json:"user_id"`
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FriendshipID == 0 || body.UserID == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id and user_id required"})
+	defer tx.Rollback()
+
+	// Consuming the token and checking it in one statement keeps it single-use
+	// even when two resets race.
+	var userID int
+	err = tx.QueryRow(
+		`UPDATE password_resets SET used_at = NOW()
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING user_id`,
+		hashToken(req.Token),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired reset token"})
 		return
 	}
-	res, err := database.DB.Exec(
-		`DELETE FROM friendships WHERE id=$1 AND (receiver_id=$2 OR requester_id=$2)`,
-		body.FriendshipID, body.UserID,
-	)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "request not found"})
+
+	if _, err := tx.Exec("UPDATE users SET password = $1 WHERE id = $2", string(newHash), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update password"})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "declined"})
-}
-
-// POST /api/friends/remove  body: {friendship_id, user_id}
-func handleFriendRemove(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	// Any other outstanding links for this account stop working too.
+	if _, err := tx.Exec("UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
-	var body struct {
-		FriendshipID int `json:"friendship_id"`
-		UserID       int `json:"user_id"`
+	if _, err := tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to revoke sessions"})
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.UserID == 0 || body.FriendshipID == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "friendship_id and user_id required"})
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update password"})
 		return
 	}
-	database.DB.Exec(
-		`DELETE FROM friendships WHERE id=$1 AND (requester_id=$2 OR receiver_id=$2)`,
-		body.FriendshipID, body.UserID,
-	)
-	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "password reset"})
 }
 
-// GET  /api/dm?user1=N&user2=N  — chat history
-// POST /api/dm  body: {sender_id, receiver_id, content}
-func handleDM(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		u1, err1 := strconv.Atoi(r.URL.Query().Get("user1"))
-		u2, err2 := strconv.Atoi(r.URL.Query().Get("user2"))
-		if err1 != nil || err2 != nil || u1 == 0 || u2 == 0 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user1 and user2 required"})
-			return
-		}
-		rows, err := database.DB.Query(
-			`SELECT dm.id, dm.sender_id, u.username, dm.receiver_id, dm.content, dm.created_at
-			FROM direct_messages dm
-			JOIN users u ON u.id = dm.sender_id
-			WHERE (dm.sender_id=$1 AND dm.receiver_id=$2) OR (dm.sender_id=$2 AND dm.receiver_id=$1)
-			ORDER BY dm.created_at ASC`,
-			u1, u2,
-		)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
-			return
-		}
-		defer rows.Close()
-		type DM struct {
-			ID         int       `json:"id"`
-			SenderID   int       `json:"sender_id"`
-			SenderName string    `json:"sender_name"`
-			ReceiverID int       `json:"receiver_id"`
-			Content    string    `json:"content"`
-			CreatedAt  time.Time `json:"created_at"`
-		}
-		msgs := []DM{}
-		for rows.Next() {
-			var m DM
-			if err := rows.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.ReceiverID, &m.Content, &m.CreatedAt); err == nil {
-				msgs = append(msgs, m)
-			}
-		}
-		writeJSON(w, http.StatusOK, msgs)
+// ---------- Email Verification ----------
 
-	case http.MethodPost:
-		var body struct {
-			SenderID   int    `json:"sender_id"`
-			ReceiverID int    `json:"receiver_id"`
-			Content    string `json:"content"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.SenderID == 0 || body.ReceiverID == 0 || strings.TrimSpace(body.Content) == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sender_id, receiver_id, content required"})
-			return
-		}
-		var id int
-		err := database.DB.QueryRow(
-			`INSERT INTO direct_messages (sender_id, receiver_id, content) VALUES ($1, $2, $3) RETURNING id`,
-			body.SenderID, body.ReceiverID, body.Content,
-		).Scan(&id)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
-			return
-		}
-		writeJSON(w, http.StatusCreated, map[string]int{"id": id})
+const emailVerificationTTL = 48 * time.Hour
 
-	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-	}
+// sendVerificationEmail mails the confirmation link for token to email.
+func sendVerificationEmail(email, token string) error {
+	verifyURL := cfg.EmailVerifyURL
+	return appMailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your UF Wildlife email address",
+		Body: fmt.Sprintf("Welcome to UF Wildlife!\n\n"+
+			"Confirm that you own this address by opening this link within %d hours:\n%s?token=%s\n\n"+
+			"If you did not sign up, you can ignore this email.",
+			int(emailVerificationTTL.Hours()), verifyURL, token),
+	})
 }
 
-// ---------- Area Channels (Group Chat) ----------
-
-func handleGetChannels(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
-	}
-
-	rows, err := database.DB.Query(`
-		SELECT c.id, c.name, COALESCE(c.description,''), c.creator_id, u.username, c.created_at,
-		       (SELECT COUNT(*) FROM area_messages m WHERE m.channel_id = c.id) AS msg_count
-		FROM area_channels c
-		JOIN users u ON u.id = c.creator_id
-		ORDER BY c.created_at DESC`)
+// issueVerification creates a verification token for the user and emails it.
+func issueVerification(userID int, email string) error {
+	token, err := createUserToken("email_verifications", userID, emailVerificationTTL)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query channels"})
-		return
-	}
-	defer rows.Close()
-
-	type Channel struct {
-		ID           int       `json:"id"`
-		Name         string    `json:"name"`
-		Description  string    `json:"description"`
-		CreatorID    int       `json:"creator_id"`
-		CreatorName  string    `json:"creator_name"`
-		CreatedAt    time.Time `json:"created_at"`
-		MessageCount int       `json:"message_count"`
-	}
-	channels := []Channel{}
-	for rows.Next() {
-		var c Channel
-		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatorID, &c.CreatorName, &c.CreatedAt, &c.MessageCount); err == nil {
-			channels = append(channels, c)
-		}
+		return err
 	}
-
-	writeJSON(w, http.StatusOK, channels)
+	return sendVerificationEmail(email, token)
 }
 
-func handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+// POST /api/email/verify  body: {token}
+func handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
 	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		CreatorID   int    `json:"creator_id"`
+		Token string `json:"token"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	if strings.TrimSpace(req.Name) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Channel name is required"})
-		return
-	}
-	if len(req.Name) > 100 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Channel name too long (max 100 characters)"})
-		return
-	}
-	if req.CreatorID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "creator_id is required"})
+	if req.Token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
 		return
 	}
 
-	var id int
-	err := database.DB.QueryRow(
-		"INSERT INTO area_channels (name, description, creator_id) VALUES ($1, $2, $3) RETURNING id",
-		strings.TrimSpace(req.Name), strings.TrimSpace(req.Description), req.CreatorID,
-	).Scan(&id)
+	// Consuming the token and verifying the account succeed or fail together.
+	tx, err := database.DB.Begin()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create channel"})
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
-}
-
-func handleGetChannelMessages(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
+	defer tx.Rollback()
 
-	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/")
-	if len(parts) < 2 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid path"})
+	var userID int
+	err = tx.QueryRow(
+		`UPDATE email_verifications SET used_at = NOW()
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING user_id`,
+		hashToken(req.Token),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired verification token"})
 		return
 	}
-	channelID, err := strconv.Atoi(parts[0])
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid channel ID"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
 
-	rows, err := database.DB.Query(`
-		SELECT m.id, m.channel_id, m.sender_id, u.username, m.content, m.created_at
-		FROM area_messages m
-		JOIN users u ON u.id = m.sender_id
-		WHERE m.channel_id = $1
-		ORDER BY m.created_at ASC`, channelID)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query messages"})
+	if _, err := tx.Exec("UPDATE users SET email_verified = TRUE WHERE id = $1", userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to verify email"})
 		return
 	}
-	defer rows.Close()
-
-	type Msg struct {
-		ID        int       `json:"id"`
-		ChannelID int       `json:"channel_id"`
-		SenderID  int       `json:"sender_id"`
-		Sender    string    `json:"sender"`
-		Content   string    `json:"content"`
-		CreatedAt time.Time `json:"created_at"`
-	}
-	msgs := []Msg{}
-	for rows.Next() {
-		var m Msg
-		if err := rows.Scan(&m.ID, &m.ChannelID, &m.SenderID, &m.Sender, &m.Content, &m.CreatedAt); err == nil {
-			msgs = append(msgs, m)
-		}
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to verify email"})
+		return
 	}
 
-	writeJSON(w, http.StatusOK, msgs)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "email verified"})
 }
 
-func handleCreateChannelMessage(w http.ResponseWriter, r *http.Request) {
+// POST /api/email/resend  body: {email}
+func handleResendVerification(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/")
-	if len(parts) < 2 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid path"})
-		return
-	}
-	channelID, err := strconv.Atoi(parts[0])
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid channel ID"})
-		return
-	}
-
 	var req struct {
-		SenderID int    `json:"sender_id"`
-		Content  string `json:"content"`
+		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Email is required"})
+		return
+	}
 
-	if req.SenderID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sender_id is required"})
+	ip, email := clientIP(r), strings.ToLower(req.Email)
+	if retryAfter := max(lockedFor(resendLimiter, "resend_ip", ip), lockedFor(resendLimiter, "resend_email", email)); retryAfter > 0 {
+		writeTooManyAttempts(w, retryAfter)
 		return
 	}
-	if strings.TrimSpace(req.Content) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Content is required"})
+	recordFailure(resendLimiter, "resend_ip", ip)
+	recordFailure(resendLimiter, "resend_email", email)
+
+	// Respond the same way whether or not the account exists or is verified.
+	sent := map[string]string{"status": "If that account needs verification, a new link has been sent"}
+
+	var userID int
+	err := database.DB.QueryRow(
+		"SELECT id FROM users WHERE email = $1 AND email_verified = FALSE", req.Email,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusOK, sent)
 		return
 	}
-	if len(req.Content) > 2000 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Message too long (max 2000 characters)"})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
 
-	var id int
-	err = database.DB.QueryRow(
-		"INSERT INTO area_messages (channel_id, sender_id, content) VALUES ($1, $2, $3) RETURNING id",
-		channelID, req.SenderID, strings.TrimSpace(req.Content),
-	).Scan(&id)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create message"})
+	// Only the newest link stays valid.
+	if _, err := database.DB.Exec("UPDATE email_verifications SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if err := issueVerification(userID, req.Email); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", req.Email, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to send verification email"})
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+	writeJSON(w, http.StatusOK, sent)
 }
 
-func handleChannelsRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/channels")
-	path = strings.TrimSuffix(path, "/")
+// ---------- Sessions ----------
 
-	if path == "" {
-		switch r.Method {
-		case http.MethodGet:
-			handleGetChannels(w, r)
-		case http.MethodPost:
-			handleCreateChannel(w, r)
-		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		}
-		return
+// issueTokens starts a session: a short-lived access JWT plus a refresh token
+// whose hash is stored in refresh_tokens.
+func issueTokens(userID int, email, role string) (string, string, error) {
+	token, err := generateJWT(userID, email, role)
+	if err != nil {
+		return "", "", err
 	}
-
-	// /api/channels/{id}/messages
-	if strings.Contains(path, "/messages") {
-		switch r.Method {
-		case http.MethodGet:
-			handleGetChannelMessages(w, r)
-		case http.MethodPost:
-			handleCreateChannelMessage(w, r)
-		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		}
-		return
+	refreshToken, err := createUserToken("refresh_tokens", userID, time.Duration(cfg.RefreshTokenTTL))
+	if err != nil {
+		return "", "", err
 	}
+	return token, refreshToken, nil
+}
 
-	writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+// revokeAllSessions invalidates every refresh token the user holds.
+func revokeAllSessions(userID int) error {
+	_, err := database.DB.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
 }
 
-// ---------- Subscriptions & Notifications ----------
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
 
-func handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+// POST /api/token/refresh  body: {refresh_token}
+func handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	var req struct {
-		UserID int    `json:"user_id"`
-		Type   string `json:"type"`
-		Value  string `json:"value"`
-	}
+	var req refreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	if req.UserID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id is required"})
-		return
-	}
-
-	validType := map[string]bool{"species": true, "category": true, "area": true}
-	if !validType[req.Type] {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "type must be one of: species, category, area"})
+	if req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "refresh_token is required"})
 		return
 	}
 
-	if strings.TrimSpace(req.Value) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "value is required"})
+	// Rotate: the presented token is revoked in the same statement that checks it.
+	var user models.User
+	err := database.DB.QueryRow(
+		`UPDATE refresh_tokens rt SET revoked_at = NOW()
+		 FROM users u
+		 WHERE rt.token_hash = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW() AND u.id = rt.user_id
+		 RETURNING u.id, u.email, u.role`,
+		hashToken(req.RefreshToken),
+	).Scan(&user.ID, &user.Email, &user.Role)
+	if err == sql.ErrNoRows {
+		// A revoked token being replayed means it may have been stolen, so
+		// end every session for that user.
+		var userID int
+		if database.DB.QueryRow(
+			"SELECT user_id FROM refresh_tokens WHERE token_hash = $1 AND revoked_at IS NOT NULL",
+			hashToken(req.RefreshToken),
+		).Scan(&userID) == nil {
+			revokeAllSessions(userID)
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid or expired refresh token"})
 		return
 	}
-
-	var existing int
-	err := database.DB.QueryRow(
-		"SELECT 1 FROM subscriptions WHERE user_id = $1 AND type = $2 AND value = $3",
-		req.UserID, req.Type, req.Value,
-	).Scan(&existing)
-	if err == nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "Already subscribed"})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
 
-	var id int
-	err = database.DB.QueryRow(
-		"INSERT INTO subscriptions (user_id, type, value) VALUES ($1, $2, $3) RETURNING id",
-		req.UserID, req.Type, strings.TrimSpace(req.Value),
-	).Scan(&id)
+	token, refreshToken, err := issueTokens(user.ID, user.Email, user.Role)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create subscription"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+	writeJSON(w, http.StatusOK, map[string]string{
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
 }
 
-func handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// POST /api/logout  body: {refresh_token}  — ends this device's session
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "valid user_id is required"})
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	rows, err := database.DB.Query(
-		"SELECT id, user_id, type, value, created_at FROM subscriptions WHERE user_id = $1 ORDER BY created_at DESC",
-		userID,
-	)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query subscriptions"})
+	if req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "refresh_token is required"})
 		return
 	}
-	defer rows.Close()
 
-	type Sub struct {
-		ID        int       `json:"id"`
-		UserID    int       `json:"user_id"`
-		Type      string    `json:"type"`
-		Value     string    `json:"value"`
-		CreatedAt time.Time `json:"created_at"`
-	}
-	subs := []Sub{}
-	for rows.Next() {
-		var s Sub
-		if err := rows.Scan(&s.ID, &s.UserID, &s.Type, &s.Value, &s.CreatedAt); err == nil {
-			subs = append(subs, s)
-		}
+	if _, err := database.DB.Exec(
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL",
+		hashToken(req.RefreshToken),
+	); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to log out"})
+		return
 	}
 
-	writeJSON(w, http.StatusOK, subs)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
 }
 
-func handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+// POST /api/logout/all  — ends every session of the authenticated user
+func handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid subscription ID"})
+	userID, ok := actingUserID(w, r, 0)
+	if !ok {
 		return
 	}
-
-	result, err := database.DB.Exec("DELETE FROM subscriptions WHERE id = $1", id)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete subscription"})
+	if err := revokeAllSessions(userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to log out"})
 		return
 	}
-	n, _ := result.RowsAffected()
-	if n == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Subscription not found"})
-		return
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "logged out of all devices"})
+}
+
+// Router for /api/friends and /api/friends/
+func handleFriendsRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/friends")
+	path = strings.TrimSuffix(path, "/")
+	switch path {
+	case "", "/list":
+		handleFriendList(w, r)
+	case "/request":
+		handleFriendRequest(w, r)
+	case "/requests":
+		handleFriendRequests(w, r)
+	case "/accept":
+		handleFriendAccept(w, r)
+	case "/decline":
+		handleFriendDecline(w, r)
+	case "/remove":
+		handleFriendRemove(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 	}
+}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+// ---------- Uploads ----------
+
+// appStorage holds uploaded files; set in main from STORAGE_DRIVER.
+var appStorage storage.Storage = &storage.Local{Dir: "uploads", BaseURL: "http://localhost:8080/uploads"}
+
+func newStorage(c config.Config) storage.Storage {
+	if c.StorageDriver == "s3" {
+		return &storage.S3{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			Bucket:    c.S3Bucket,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			PublicURL: c.S3PublicURL,
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	return &storage.Local{Dir: c.UploadDir, BaseURL: c.UploadBaseURL}
 }
 
-func handleGetNotifications(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// maxUploadPixels guards against decompression bombs: small files that
+// declare enormous dimensions.
+const maxUploadPixels = 50_000_000
+
+// maxMediaDuration caps client-reported audio and video lengths.
+const maxMediaDuration = time.Hour
+
+// POST /api/uploads  multipart/form-data with a "file" field and, for audio
+// or video the server cannot measure, an optional "duration_ms" field.
+// Returns the stored file's URL for use as a sighting's image_url or media.
+func handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		return
 	}
-
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "valid user_id is required"})
+	// Check who is uploading before reading, sniffing or decoding anything.
+	user, ok := currentUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
 		return
 	}
 
-	unreadOnly := r.URL.Query().Get("unread") == "true"
-
-	var query string
-	var args []interface{}
-	if unreadOnly {
-		query = `
-			SELECT n.id, n.user_id, n.sighting_id, n.subscription_id, n.message, n.is_read, n.created_at
-			FROM notifications n
-			WHERE n.user_id = $1 AND n.is_read = FALSE
-			ORDER BY n.created_at DESC LIMIT 50`
-		args = []interface{}{userID}
-	} else {
-		query = `
-			SELECT n.id, n.user_id, n.sighting_id, n.subscription_id, n.message, n.is_read, n.created_at
-			FROM notifications n
-			WHERE n.user_id = $1
-			ORDER BY n.created_at DESC LIMIT 50`
-		args = []interface{}{userID}
+	tooLarge := func(limit int) string {
+		return fmt.Sprintf("File too large (max %.0f MB)", float64(limit)/(1<<20))
 	}
+	maxBytes := max(cfg.MaxUploadBytes, cfg.MaxMediaUploadBytes)
 
-	rows, err := database.DB.Query(query, args...)
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)+64<<10)
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query notifications"})
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": tooLarge(maxBytes)})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": `Expected a multipart form with a "file" field`})
 		return
 	}
-	defer rows.Close()
+	defer file.Close()
 
-	type Notif struct {
-		ID             int       `json:"id"`
-		UserID         int       `json:"user_id"`
-		SightingID     int       `json:"sighting_id"`
-		SubscriptionID int       `json:"subscription_id"`
-		Message        string    `json:"message"`
-		IsRead         bool      `json:"is_read"`
-		CreatedAt      time.Time `json:"created_at"`
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to read file"})
+		return
 	}
-	notifs := []Notif{}
-	for rows.Next() {
-		var n Notif
-		if err := rows.Scan(&n.ID, &n.UserID, &n.SightingID, &n.SubscriptionID, &n.Message, &n.IsRead, &n.CreatedAt); err == nil {
-			notifs = append(notifs, n)
+	if len(data) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "File is empty"})
+		return
+	}
+
+	// Trust the bytes, not the client's declared Content-Type.
+	contentType, kind, ext, ok := probe.Sniff(data, header.Header.Get("Content-Type"))
+	if !ok {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Unsupported file type; upload a JPEG, PNG or WebP image, MP3, WAV, Ogg or M4A audio, or MP4 or WebM video"})
+		return
+	}
+	limit := cfg.MaxUploadBytes
+	if kind != probe.KindImage {
+		limit = cfg.MaxMediaUploadBytes
+	}
+	if len(data) > limit {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": tooLarge(limit)})
+		return
+	}
+
+	var width, height int
+	var durationMs *int
+	var info exif.Info
+	if kind == probe.KindImage {
+		imgCfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "File is not a valid image"})
+			return
+		}
+		if imgCfg.Width <= 0 || imgCfg.Height <= 0 || imgCfg.Width*imgCfg.Height > maxUploadPixels {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Image dimensions are too large"})
+			return
+		}
+		width, height = imgCfg.Width, imgCfg.Height
+	} else {
+		d, ok := probe.Duration(data, contentType)
+		if !ok {
+			if v := r.FormValue("duration_ms"); v != "" {
+				ms, err := strconv.Atoi(v)
+				if err != nil || ms <= 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "duration_ms must be a positive integer"})
+					return
+				}
+				d, ok = time.Duration(ms)*time.Millisecond, true
+			}
+		}
+		if d > maxMediaDuration {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Recordings may be at most 60 minutes long"})
+			return
+		}
+		if ok {
+			ms := int(d.Milliseconds())
+			durationMs = &ms
 		}
 	}
 
-	writeJSON(w, http.StatusOK, notifs)
-}
+	// Read EXIF before it is stripped; GPS and capture time are kept only in
+	// the database, never in the public file.
+	clean := data
+	if kind == probe.KindImage {
+		info, _ = exif.Read(data)
+		clean, err = exif.Strip(data)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "File is not a valid image"})
+			return
+		}
+	}
 
-func handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	name, err := generateToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to store file"})
 		return
 	}
+	key := "sightings/" + time.Now().UTC().Format("2006/01") + "/" + name[:32] + ext
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/notifications/")
-	idStr = strings.TrimSuffix(idStr, "/read")
-	id, err := strconv.Atoi(idStr)
+	url, err := appStorage.Put(r.Context(), key, clean, contentType)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid notification ID"})
+		log.Printf("Failed to store upload %s: %v", key, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to store file"})
 		return
 	}
 
-	result, err := database.DB.Exec("UPDATE notifications SET is_read = TRUE WHERE id = $1", id)
+	var takenAt, offset *string
+	var lat, lng *float64
+	if info.DateTimeOriginal != "" {
+		takenAt = &info.DateTimeOriginal
+	}
+	if info.OffsetTimeOriginal != "" {
+		offset = &info.OffsetTimeOriginal
+	}
+	if info.HasGPS {
+		lat, lng = &info.Latitude, &info.Longitude
+	}
+	// Only images get resized variants.
+	variantsStatus := "none"
+	if kind == probe.KindImage {
+		variantsStatus = "pending"
+	}
+
+	var id int
+	err = database.DB.QueryRow(`
+		INSERT INTO uploads (user_id, storage_key, url, content_type, kind, size_bytes, width, height, duration_ms,
+		                     exif_taken_at, exif_offset, exif_latitude, exif_longitude, variants_status)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		RETURNING id`,
+		user.ID, key, url, contentType, kind, len(clean), width, height, durationMs,
+		takenAt, offset, lat, lng, variantsStatus,
+	).Scan(&id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update notification"})
+		appStorage.Delete(context.Background(), key)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save upload"})
 		return
 	}
-	n, _ := result.RowsAffected()
-	if n == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Notification not found"})
-		return
+	if kind == probe.KindImage {
+		enqueueVariants(variantJob{uploadID: id, key: key, data: clean})
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "read"})
+	resp := map[string]any{
+		"id":           id,
+		"url":          url,
+		"kind":         kind,
+		"content_type": contentType,
+		"size":         len(clean),
+	}
+	if kind == probe.KindImage {
+		resp["width"] = width
+		resp["height"] = height
+		resp["suggestions"] = suggestFromExif(info)
+	} else {
+		resp["duration_ms"] = durationMs
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
 
-func triggerNotifications(sightingID int, species, category string, lat, lng float64) {
-	rows, err := database.DB.Query(
-		`SELECT id, user_id, type, value FROM subscriptions
-		 WHERE (type = 'species' AND value = $1)
-		    OR (type = 'category' AND value = $2)`,
-		species, category,
-	)
-	if err != nil {
-		return
+// attachUpload links the user's upload at url to a sighting so stored files
+// can be traced back to the records that use them.
+func attachUpload(sightingID, userID int, url string) {
+	if url == "" {
+		return
+	}
+	// An upload attached to another sighting stays there.
+	if _, err := database.DB.Exec(
+		"UPDATE uploads SET sighting_id = $1 WHERE url = $2 AND user_id = $3 AND (sighting_id IS NULL OR sighting_id = $1)",
+		sightingID, url, userID,
+	); err != nil {
+		log.Printf("Failed to attach upload to sighting %d: %v", sightingID, err)
+	}
+	if _, err := database.DB.Exec(`
+		INSERT INTO sighting_media (sighting_id, upload_id, kind, url, mime_type, duration_ms, position)
+		SELECT $1, u.id, u.kind, u.url, u.content_type, u.duration_ms,
+			COALESCE((SELECT MAX(position) + 1 FROM sighting_media WHERE sighting_id = $1), 0)
+		FROM uploads u WHERE u.url = $2 AND u.user_id = $3 AND u.sighting_id = $1
+		ON CONFLICT (sighting_id, upload_id) DO NOTHING`,
+		sightingID, url, userID,
+	); err != nil {
+		log.Printf("Failed to add media to sighting %d: %v", sightingID, err)
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		var subID, userID int
-		var subType, subValue string
-		if err := rows.Scan(&subID, &userID, &subType, &subValue); err != nil {
-			continue
-		}
-		msg := fmt.Sprintf("New %s sighting: %s spotted nearby!", category, species)
-		database.DB.Exec(
-			"INSERT INTO notifications (user_id, sighting_id, subscription_id, message) VALUES ($1, $2, $3, $4)",
-			userID, sightingID, subID, msg,
-		)
+// uploadAvailable responds 409 if url is one of userID's uploads already
+// attached to a sighting other than sightingID (0 for a new sighting).
+// URLs that are not uploads are left alone.
+func uploadAvailable(w http.ResponseWriter, url string, userID, sightingID int) bool {
+	if url == "" {
+		return true
+	}
+	var inUse bool
+	err := database.DB.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM uploads WHERE url = $1 AND user_id = $2 AND sighting_id <> $3)",
+		url, userID, sightingID,
+	).Scan(&inUse)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return false
 	}
+	if inUse {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "Upload is already attached to another sighting"})
+		return false
+	}
+	return true
 }
 
-func handleSubscriptionsRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/subscriptions")
-	path = strings.TrimSuffix(path, "/")
+// ---------- Sighting Media ----------
 
-	if path == "" {
+// handleSightingMedia routes /api/sightings/{id}/media, /media/order and
+// /media/{mediaId}.
+func handleSightingMedia(w http.ResponseWriter, r *http.Request, idStr, rest string) {
+	sightingID, err := strconv.Atoi(idStr)
+	if err != nil || sightingID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid sighting ID"})
+		return
+	}
+
+	switch {
+	case rest == "":
 		switch r.Method {
 		case http.MethodGet:
-			handleGetSubscriptions(w, r)
+			handleGetSightingMedia(w, r, sightingID)
 		case http.MethodPost:
-			handleCreateSubscription(w, r)
+			handleAddSightingMedia(w, r, sightingID)
 		default:
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
 		}
-		return
+	case rest == "order":
+		if r.Method != http.MethodPut {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+			return
+		}
+		handleReorderSightingMedia(w, r, sightingID)
+	default:
+		if r.Method != http.MethodDelete {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+			return
+		}
+		mediaID, err := strconv.Atoi(rest)
+		if err != nil || mediaID <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid media ID"})
+			return
+		}
+		handleRemoveSightingMedia(w, r, sightingID, mediaID)
 	}
-
-	handleDeleteSubscription(w, r)
 }
 
-func handleNotificationsRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/notifications")
-	path = strings.TrimSuffix(path, "/")
-
-	if path == "" {
-		handleGetNotifications(w, r)
+// GET /api/sightings/{id}/media
+func handleGetSightingMedia(w http.ResponseWriter, r *http.Request, sightingID int) {
+	if _, err := sightingOwner(sightingID); err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
 		return
-	}
-
-	if strings.HasSuffix(path, "/read") {
-		handleMarkNotificationRead(w, r)
+	} else if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
 
-	writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	sightings := []models.Animals{{ID: sightingID}}
+	loadSightingMedia(sightings)
+	writeJSON(w, http.StatusOK, sightings[0].Media)
 }
 
-// ---------- Reports ----------
-
-func handleCreateReport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
+// authorizeSightingEdit loads the sighting's owner and checks that the
+// caller may change it, writing the error response when not.
+func authorizeSightingEdit(w http.ResponseWriter, r *http.Request, sightingID int) bool {
+	if _, ok := currentUser(r); !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return false
 	}
+	ownerID, err := sightingOwner(sightingID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Sighting not found"})
+		return false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return false
+	}
+	return authorizeOwner(w, r, ownerID)
+}
 
+// POST /api/sightings/{id}/media body: {"upload_id":12}
+func handleAddSightingMedia(w http.ResponseWriter, r *http.Request, sightingID int) {
 	var req struct {
-		SightingID int    `json:"sighting_id"`
-		ReporterID int    `json:"reporter_id"`
-		Reason     string `json:"reason"`
+		UploadID int `json:"upload_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	if req.SightingID <= 0 || req.ReporterID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sighting_id and reporter_id are required"})
-		return
-	}
-	if strings.TrimSpace(req.Reason) == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason is required"})
+	if req.UploadID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "upload_id is required"})
 		return
 	}
-	if len(req.Reason) > 1000 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason too long (max 1000 characters)"})
+	if !authorizeSightingEdit(w, r, sightingID) {
 		return
 	}
+	user, _ := currentUser(r)
 
-	var existing int
+	// Only the uploader can attach a file, and only to one sighting.
+	var url string
+	var attachedTo sql.NullInt64
 	err := database.DB.QueryRow(
-		"SELECT 1 FROM reports WHERE sighting_id = $1 AND reporter_id = $2 AND status = 'pending'",
-		req.SightingID, req.ReporterID,
-	).Scan(&existing)
-	if err == nil {
-		writeJSON(w, http.StatusConflict, map[string]string{"error": "You have already reported this sighting"})
+		"SELECT url, sighting_id FROM uploads WHERE id = $1 AND user_id = $2",
+		req.UploadID, user.ID,
+	).Scan(&url, &attachedTo)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Upload not found"})
 		return
 	}
-
-	var id int
-	err = database.DB.QueryRow(
-		"INSERT INTO reports (sighting_id, reporter_id, reason) VALUES ($1, $2, $3) RETURNING id",
-		req.SightingID, req.ReporterID, strings.TrimSpace(req.Reason),
-	).Scan(&id)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create report"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if attachedTo.Valid && int(attachedTo.Int64) != sightingID {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "Upload is already attached to another sighting"})
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+	attachUpload(sightingID, user.ID, url)
+	syncCoverImage(sightingID)
+
+	sightings := []models.Animals{{ID: sightingID}}
+	loadSightingMedia(sightings)
+	writeJSON(w, http.StatusCreated, sightings[0].Media)
 }
 
-func handleGetReports(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// DELETE /api/sightings/{id}/media/{mediaId}
+func handleRemoveSightingMedia(w http.ResponseWriter, r *http.Request, sightingID, mediaID int) {
+	if !authorizeSightingEdit(w, r, sightingID) {
 		return
 	}
 
-	status := r.URL.Query().Get("status")
-	var query string
-	var args []interface{}
-
-	if status != "" {
-		query = `
-			SELECT r.id, r.sighting_id, r.reporter_id, u.username, r.reason, r.status,
-			       COALESCE(r.admin_note,''), r.created_at, r.resolved_at
-			FROM reports r
-			JOIN users u ON u.id = r.reporter_id
-			WHERE r.status = $1
-			ORDER BY r.created_at DESC`
-		args = append(args, status)
-	} else {
-		query = `
-			SELECT r.id, r.sighting_id, r.reporter_id, u.username, r.reason, r.status,
-			       COALESCE(r.admin_note,''), r.created_at, r.resolved_at
-			FROM reports r
-			JOIN users u ON u.id = r.reporter_id
-			ORDER BY r.created_at DESC`
+	var uploadID sql.NullInt64
+	err := database.DB.QueryRow(
+		"DELETE FROM sighting_media WHERE id = $1 AND sighting_id = $2 RETURNING upload_id",
+		mediaID, sightingID,
+	).Scan(&uploadID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Media not found"})
+		return
 	}
-
-	rows, err := database.DB.Query(query, args...)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query reports"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to remove media"})
 		return
 	}
-	defer rows.Close()
-
-	type Report struct {
-		ID         int        `json:"id"`
-		SightingID int        `json:"sighting_id"`
-		ReporterID int        `json:"reporter_id"`
-		Reporter   string     `json:"reporter"`
-		Reason     string     `json:"reason"`
-		Status     string     `json:"status"`
-		AdminNote  string     `json:"admin_note"`
-		CreatedAt  time.Time  `json:"created_at"`
-		ResolvedAt *time.Time `json:"resolved_at"`
-	}
-	reports := []Report{}
-	for rows.Next() {
-		var rpt Report
-		if err := rows.Scan(&rpt.ID, &rpt.SightingID, &rpt.ReporterID, &rpt.Reporter,
-			&rpt.Reason, &rpt.Status, &rpt.AdminNote, &rpt.CreatedAt, &rpt.ResolvedAt); err == nil {
-			reports = append(reports, rpt)
+	if uploadID.Valid {
+		// Free the upload so it can be attached elsewhere.
+		if _, err := database.DB.Exec(
+			"UPDATE uploads SET sighting_id = NULL WHERE id = $1 AND sighting_id = $2",
+			uploadID.Int64, sightingID,
+		); err != nil {
+			log.Printf("Failed to detach upload %d: %v", uploadID.Int64, err)
 		}
 	}
+	syncCoverImage(sightingID)
 
-	writeJSON(w, http.StatusOK, reports)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
-func handleUpdateReport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
-	}
-
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/reports/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid report ID"})
-		return
-	}
-
+// PUT /api/sightings/{id}/media/order body: {"media_ids":[3,1,2]}
+func handleReorderSightingMedia(w http.ResponseWriter, r *http.Request, sightingID int) {
 	var req struct {
-		Status    string `json:"status"`
-		AdminNote string `json:"admin_note"`
+		MediaIDs []int `json:"media_ids"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 		return
 	}
-
-	validStatus := map[string]bool{"resolved": true, "dismissed": true, "pending": true}
-	if !validStatus[req.Status] {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be one of: pending, resolved, dismissed"})
+	if len(req.MediaIDs) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "media_ids is required"})
+		return
+	}
+	seen := make(map[int]bool, len(req.MediaIDs))
+	for _, id := range req.MediaIDs {
+		if seen[id] {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "media_ids must not repeat"})
+			return
+		}
+		seen[id] = true
+	}
+	if !authorizeSightingEdit(w, r, sightingID) {
 		return
 	}
 
-	var resolvedAt interface{}
-	if req.Status == "resolved" || req.Status == "dismissed" {
-		resolvedAt = time.Now()
+	tx, err := database.DB.Begin()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
 	}
+	defer tx.Rollback()
 
-	result, err := database.DB.Exec(
-		"UPDATE reports SET status = $1, admin_note = $2, resolved_at = $3 WHERE id = $4",
-		req.Status, req.AdminNote, resolvedAt, id,
-	)
+	// The new order must name every media item on the sighting exactly once.
+	var count, matched int
+	err = tx.QueryRow(
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE id = ANY($2)) FROM sighting_media WHERE sighting_id = $1",
+		sightingID, toInt64s(req.MediaIDs),
+	).Scan(&count, &matched)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update report"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
 	}
-	n, _ := result.RowsAffected()
-	if n == 0 {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Report not found"})
+	if count != len(req.MediaIDs) || matched != count {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "media_ids must list every media item on the sighting"})
+		return
+	}
+
+	for pos, id := range req.MediaIDs {
+		if _, err := tx.Exec(
+			"UPDATE sighting_media SET position = $1 WHERE id = $2 AND sighting_id = $3",
+			pos, id, sightingID,
+		); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to reorder media"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to reorder media"})
 		return
 	}
+	syncCoverImage(sightingID)
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": req.Status})
+	sightings := []models.Animals{{ID: sightingID}}
+	loadSightingMedia(sightings)
+	writeJSON(w, http.StatusOK, sightings[0].Media)
 }
 
-func handleReportsRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/reports")
-	path = strings.TrimSuffix(path, "/")
+// syncCoverImage keeps animals.image_url pointing at the first photo so
+// clients that only read image_url keep working.
+func syncCoverImage(sightingID int) {
+	if _, err := database.DB.Exec(`
+		UPDATE animals SET image_url = COALESCE((
+			SELECT url FROM sighting_media
+			WHERE sighting_id = $1 AND kind = 'image'
+			ORDER BY position, id LIMIT 1), '')
+		WHERE id = $1`, sightingID,
+	); err != nil {
+		log.Printf("Failed to update cover image for sighting %d: %v", sightingID, err)
+		return
+	}
+	sightingsChanged()
+}
 
-	if path == "" {
-		switch r.Method {
-		case http.MethodGet:
-			handleGetReports(w, r)
-		case http.MethodPost:
-			handleCreateReport(w, r)
-		default:
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		}
+// setCoverImage makes url the sighting's first photo, if it is one of them,
+// and points image_url at the first photo so the two agree after an edit.
+// Sightings without photos keep the image_url they were given.
+func setCoverImage(sightingID int, url string) {
+	var photos int
+	if err := database.DB.QueryRow(
+		"SELECT COUNT(*) FROM sighting_media WHERE sighting_id = $1 AND kind = 'image'", sightingID,
+	).Scan(&photos); err != nil {
+		log.Printf("Failed to update cover image for sighting %d: %v", sightingID, err)
+		return
+	}
+	if photos == 0 {
+		return
+	}
+	if _, err := database.DB.Exec(`
+		UPDATE sighting_media SET position = (SELECT MIN(position) - 1 FROM sighting_media WHERE sighting_id = $1)
+		WHERE sighting_id = $1 AND url = $2 AND kind = 'image'`, sightingID, url,
+	); err != nil {
+		log.Printf("Failed to update cover image for sighting %d: %v", sightingID, err)
 		return
 	}
+	syncCoverImage(sightingID)
+}
 
-	handleUpdateReport(w, r)
+func toInt64s(ids []int) []int64 {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		out[i] = int64(id)
+	}
+	return out
 }
 
-// ---------- Leaderboard ----------
+// loadSightingMedia fills in Media for each sighting, in display order.
+// Sightings without media get an empty slice so the JSON is always an array.
+func loadSightingMedia(sightings []models.Animals) {
+	if len(sightings) == 0 {
+		return
+	}
+	ids := make([]int, len(sightings))
+	for i, a := range sightings {
+		ids[i] = a.ID
+		sightings[i].Media = []models.Media{}
+	}
 
-func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	rows, err := database.DB.Query(`
+		SELECT sighting_id, id, kind, url, mime_type, duration_ms, position
+		FROM sighting_media
+		WHERE sighting_id = ANY($1)
+		ORDER BY sighting_id, position, id`, toInt64s(ids))
+	if err != nil {
+		log.Printf("Failed to load sighting media: %v", err)
 		return
 	}
+	defer rows.Close()
 
-	period := r.URL.Query().Get("period")
-	sortBy := r.URL.Query().Get("sort")
-	if sortBy == "" {
-		sortBy = "sightings"
+	byID := map[int][]models.Media{}
+	for rows.Next() {
+		var sightingID int
+		var m models.Media
+		var duration sql.NullInt64
+		if err := rows.Scan(&sightingID, &m.ID, &m.Kind, &m.URL, &m.MimeType, &duration, &m.Position); err != nil {
+			continue
+		}
+		if duration.Valid {
+			d := int(duration.Int64)
+			m.DurationMs = &d
+		}
+		byID[sightingID] = append(byID[sightingID], m)
 	}
 
-	validSort := map[string]bool{"sightings": true, "species": true, "likes": true}
-	if !validSort[sortBy] {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be one of: sightings, species, likes"})
-		return
+	for i := range sightings {
+		if media, ok := byID[sightings[i].ID]; ok {
+			sightings[i].Media = media
+		}
 	}
+}
 
-	var dateFilter string
-	switch period {
-	case "week":
-		dateFilter = "AND a.created_at >= NOW() - INTERVAL '7 days'"
-	case "month":
-		dateFilter = "AND a.created_at >= NOW() - INTERVAL '30 days'"
+// ---------- Observation Time ----------
+
+// observedAtSkew allows for clocks on phones running slightly ahead.
+const observedAtSkew = 5 * time.Minute
+
+// earliestObservation rejects dates that can only be typos.
+var earliestObservation = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// parseObservation reads a sighting's date ("2006-01-02") and optional time
+// ("15:04" or "15:04:05") in loc. A missing time means midnight.
+func parseObservation(date, clock string, loc *time.Location) (time.Time, string) {
+	day, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return time.Time{}, "date must be YYYY-MM-DD"
+	}
+	if clock == "" {
+		return day, ""
+	}
+	var tod time.Time
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if tod, err = time.Parse(layout, clock); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, "time must be HH:MM"
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), tod.Hour(), tod.Minute(), tod.Second(), 0, loc), ""
+}
+
+// observationTime works out when a sighting was observed, from observed_at
+// (RFC 3339) when given and otherwise from date and time in the configured
+// zone. observed_at also rewrites date and time so the text columns agree.
+// given is false, with the current time, when the client sent neither; msg
+// describes an invalid or future value.
+func observationTime(req *models.CreateSightingRequest, now time.Time) (t time.Time, given bool, msg string) {
+	loc := cfg.Location()
+	switch {
+	case req.ObservedAt != "":
+		parsed, err := time.Parse(time.RFC3339, req.ObservedAt)
+		if err != nil {
+			return time.Time{}, false, "observed_at must be an RFC 3339 timestamp like 2026-02-18T14:30:00-05:00"
+		}
+		t = parsed
+		local := t.In(loc)
+		req.Date, req.Time = local.Format("2006-01-02"), local.Format("15:04")
+	case req.Date != "":
+		if t, msg = parseObservation(req.Date, req.Time, loc); msg != "" {
+			return time.Time{}, false, msg
+		}
+	case req.Time != "":
+		return time.Time{}, false, "date is required when time is given"
 	default:
-		dateFilter = ""
+		return now, false, ""
 	}
 
-	var query string
-	switch sortBy {
-	case "sightings":
-		query = fmt.Sprintf(`
-			SELECT u.id, u.username, COUNT(a.id) AS score
-			FROM users u
-			LEFT JOIN animals a ON a.user_id = u.id %s
-			GROUP BY u.id, u.username
-			HAVING COUNT(a.id) > 0
-			ORDER BY score DESC
-			LIMIT 20`, dateFilter)
-	case "species":
-		query = fmt.Sprintf(`
-			SELECT u.id, u.username, COUNT(DISTINCT a.species) AS score
-			FROM users u
-			LEFT JOIN animals a ON a.user_id = u.id %s
-			GROUP BY u.id, u.username
-			HAVING COUNT(DISTINCT a.species) > 0
-			ORDER BY score DESC
-			LIMIT 20`, dateFilter)
-	case "likes":
-		query = fmt.Sprintf(`
-			SELECT u.id, u.username, COUNT(sl.sighting_id) AS score
-			FROM users u
-			JOIN animals a ON a.user_id = u.id %s
-			JOIN sighting_likes sl ON sl.sighting_id = a.id
-			GROUP BY u.id, u.username
-			HAVING COUNT(sl.sighting_id) > 0
-			ORDER BY score DESC
-			LIMIT 20`, dateFilter)
+	if t.After(now.Add(observedAtSkew)) {
+		return time.Time{}, false, "Observation time cannot be in the future"
 	}
+	if t.Before(earliestObservation) {
+		return time.Time{}, false, "Observation time is too far in the past"
+	}
+	return t, true, ""
+}
 
-	rows, err := database.DB.Query(query)
+// backfillObservedAt sets observed_at on sightings saved before the column
+// existed, from their date and time text, or their creation time when that
+// text is missing or unreadable.
+func backfillObservedAt() {
+	rows, err := database.DB.Query(
+		"SELECT id, COALESCE(date,''), COALESCE(time,''), created_at FROM animals WHERE observed_at IS NULL",
+	)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to query leaderboard"})
+		log.Printf("Failed to load sightings without observed_at: %v", err)
 		return
 	}
-	defer rows.Close()
-
-	type Entry struct {
-		Rank     int    `json:"rank"`
-		UserID   int    `json:"user_id"`
-		Username string `json:"username"`
-		Score    int    `json:"score"`
+	type pending struct {
+		id         int
+		observedAt time.Time
 	}
-	entries := []Entry{}
-	rank := 1
+	var updates []pending
 	for rows.Next() {
-		var e Entry
-		if err := rows.Scan(&e.UserID, &e.Username, &e.Score); err == nil {
-			e.Rank = rank
-			entries = append(entries, e)
-			rank++
+		var id int
+		var date, clock string
+		var created time.Time
+		if err := rows.Scan(&id, &date, &clock, &created); err != nil {
+			continue
 		}
+		t, msg := parseObservation(date, clock, cfg.Location())
+		if msg != "" || t.After(created.Add(observedAtSkew)) || t.Before(earliestObservation) {
+			t = created
+		}
+		updates = append(updates, pending{id, t})
 	}
+	rows.Close()
 
-	writeJSON(w, http.StatusOK, map[string]any{
-		"period":  period,
-		"sort_by": sortBy,
-		"entries": entries,
-	})
+	for _, u := range updates {
+		if _, err := database.DB.Exec(
+			"UPDATE animals SET observed_at = $1 WHERE id = $2 AND observed_at IS NULL", u.observedAt, u.id,
+		); err != nil {
+			log.Printf("Failed to backfill observed_at for sighting %d: %v", u.id, err)
+		}
+	}
+	if len(updates) > 0 {
+		log.Printf("Backfilled observed_at for %d sightings", len(updates))
+	}
+	// Every sighting now has one, so sorting and filtering can use the
+	// column and its index directly.
+	if _, err := database.DB.Exec("ALTER TABLE animals ALTER COLUMN observed_at SET NOT NULL"); err != nil {
+		log.Printf("Warning: could not make observed_at required — %v", err)
+	}
 }
 
-// ---------- Change Password ----------
+// ---------- Photo Suggestions ----------
 
-func validatePasswordStrength(password string) string {
-	if len(password) < 8 {
-		return "Password must be at least 8 characters"
+// photoSuggestion holds sighting form values read from a photo's EXIF data.
+type photoSuggestion struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Date      string   `json:"date,omitempty"`
+	Time      string   `json:"time,omitempty"`
+}
+
+// suggestFromExif converts the GPS position and capture time, giving the
+// date and time in the configured zone in the same format as the sighting form.
+func suggestFromExif(info exif.Info) photoSuggestion {
+	var s photoSuggestion
+	if info.HasGPS {
+		lat, lng := info.Latitude, info.Longitude
+		s.Latitude, s.Longitude = &lat, &lng
+	}
+	loc := cfg.Location()
+	if t, ok := info.TakenAt(loc); ok {
+		t = t.In(loc)
+		s.Date = t.Format("2006-01-02")
+		s.Time = t.Format("15:04")
+	}
+	return s
+}
+
+// uploadSuggestion looks up the EXIF data saved for the user's upload at url.
+func uploadSuggestion(url string, userID int) (photoSuggestion, bool) {
+	var info exif.Info
+	var takenAt, offset sql.NullString
+	var lat, lng sql.NullFloat64
+	err := database.DB.QueryRow(
+		"SELECT exif_taken_at, exif_offset, exif_latitude, exif_longitude FROM uploads WHERE url = $1 AND user_id = $2",
+		url, userID,
+	).Scan(&takenAt, &offset, &lat, &lng)
+	if err != nil {
+		return photoSuggestion{}, false
 	}
-	var hasUpper, hasLower, hasDigit bool
-	for _, ch := range password {
-		if unicode.IsUpper(ch) {
-			hasUpper = true
-		} else if unicode.IsLower(ch) {
-			hasLower = true
-		} else if unicode.IsDigit(ch) {
-			hasDigit = true
-		}
+	info.DateTimeOriginal = takenAt.String
+	info.OffsetTimeOriginal = offset.String
+	if lat.Valid && lng.Valid {
+		info.HasGPS, info.Latitude, info.Longitude = true, lat.Float64, lng.Float64
 	}
-	if !hasUpper {
-		return "Password must contain at least one uppercase letter"
+	return suggestFromExif(info), true
+}
+
+// applyPhotoSuggestion fills the location, date and time the client left
+// empty (a 0,0 location counts as empty). It returns the fields it filled
+// and, when the client did send a location, its distance from the photo's.
+func applyPhotoSuggestion(req *models.CreateSightingRequest, s photoSuggestion) ([]string, float64) {
+	var defaulted []string
+	var distance float64
+	if s.Latitude != nil && s.Longitude != nil {
+		if req.Latitude == 0 && req.Longitude == 0 {
+			req.Latitude, req.Longitude = *s.Latitude, *s.Longitude
+			defaulted = append(defaulted, "latitude", "longitude")
+		} else {
+			distance = haversineMeters(req.Latitude, req.Longitude, *s.Latitude, *s.Longitude)
+		}
 	}
-	if !hasLower {
-		return "Password must contain at least one lowercase letter"
+	if req.Date == "" && s.Date != "" {
+		req.Date = s.Date
+		defaulted = append(defaulted, "date")
 	}
-	if !hasDigit {
-		return "Password must contain at least one digit"
+	if req.Time == "" && s.Time != "" {
+		req.Time = s.Time
+		defaulted = append(defaulted, "time")
 	}
-	return ""
+	return defaulted, distance
 }
 
-func handleChangePassword(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
-	}
+// haversineMeters is the great-circle distance between two points.
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
 
-	var req struct {
-		UserID      int    `json:"user_id"`
-		OldPassword string `json:"old_password"`
-		NewPassword string `json:"new_password"`
+// ---------- Image Variants ----------
+
+// variantJob asks a worker to build the resized variants of an upload. data
+// may be nil, in which case the original is read back from storage.
+type variantJob struct {
+	uploadID int
+	key      string
+	data     []byte
+}
+
+var variantQueue = make(chan variantJob, 100)
+
+// startVariantWorkers runs n goroutines that process variantQueue.
+func startVariantWorkers(n int) {
+	for i := 0; i < n; i++ {
+		go func() {
+			for job := range variantQueue {
+				if err := processVariants(job); err != nil {
+					log.Printf("Failed to build variants for upload %d: %v", job.uploadID, err)
+					database.DB.Exec("UPDATE uploads SET variants_status = 'failed' WHERE id = $1", job.uploadID)
+				}
+			}
+		}()
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
-		return
+}
+
+// enqueueVariants never blocks the upload request; a job dropped because the
+// queue is full stays pending and is picked up by resumePendingVariants.
+func enqueueVariants(job variantJob) {
+	select {
+	case variantQueue <- job:
+	default:
+		log.Printf("Variant queue full; upload %d will be processed on next start", job.uploadID)
 	}
+}
 
-	if req.UserID <= 0 || req.OldPassword == "" || req.NewPassword == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "user_id, old_password, and new_password are required"})
+// resumePendingVariants re-queues uploads whose variants were never built,
+// e.g. because the server stopped first.
+func resumePendingVariants() {
+	rows, err := database.DB.Query("SELECT id, storage_key FROM uploads WHERE variants_status = 'pending' ORDER BY id")
+	if err != nil {
+		log.Printf("Failed to load pending image variants: %v", err)
 		return
 	}
+	defer rows.Close()
 
-	if req.OldPassword == req.NewPassword {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "New password must be different from old password"})
-		return
+	var jobs []variantJob
+	for rows.Next() {
+		var job variantJob
+		if err := rows.Scan(&job.uploadID, &job.key); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
 	}
+	go func() {
+		for _, job := range jobs {
+			variantQueue <- job
+		}
+	}()
+}
 
-	if msg := validatePasswordStrength(req.NewPassword); msg != "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": msg})
-		return
+// variantKey derives a variant's storage key from the original's, e.g.
+// "sightings/2026/01/ab12.jpg" -> "sightings/2026/01/ab12_thumb.webp".
+func variantKey(key, variant, format string) string {
+	ext := ".jpg"
+	if format == imaging.FormatWebP {
+		ext = ".webp"
 	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + variant + ext
+}
 
-	var storedHash string
-	err := database.DB.QueryRow("SELECT password FROM users WHERE id = $1", req.UserID).Scan(&storedHash)
-	if err == sql.ErrNoRows {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
-		return
+func processVariants(job variantJob) error {
+	ctx := context.Background()
+	data := job.data
+	if data == nil {
+		var err error
+		if data, err = appStorage.Get(ctx, job.key); err != nil {
+			return err
+		}
 	}
+
+	// Stored originals keep only their orientation tag; variants are rotated
+	// upright so they need no metadata at all.
+	info, _ := exif.Read(data)
+	outs, err := imaging.Generate(data, info.Orientation, imaging.DefaultVariants)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
-		return
+		return err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(req.OldPassword)); err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Old password is incorrect"})
+	for _, out := range outs {
+		key := variantKey(job.key, out.Variant, out.Format)
+		url, err := appStorage.Put(ctx, key, out.Data, out.ContentType)
+		if err != nil {
+			return err
+		}
+		_, err = database.DB.Exec(`
+			INSERT INTO image_variants (upload_id, variant, format, storage_key, url, width, height, size_bytes)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+			ON CONFLICT (upload_id, variant, format)
+			DO UPDATE SET storage_key = EXCLUDED.storage_key, url = EXCLUDED.url, width = EXCLUDED.width,
+			              height = EXCLUDED.height, size_bytes = EXCLUDED.size_bytes`,
+			job.uploadID, out.Variant, out.Format, key, url, out.Width, out.Height, len(out.Data),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = database.DB.Exec("UPDATE uploads SET variants_status = 'done' WHERE id = $1", job.uploadID)
+	return err
+}
+
+// loadImageVariants fills in ImageVariants for sightings whose image_url is
+// an upload with generated variants.
+func loadImageVariants(sightings []models.Animals) {
+	if len(sightings) == 0 {
 		return
 	}
+	ids := make([]int64, len(sightings))
+	for i, a := range sightings {
+		ids[i] = int64(a.ID)
+	}
 
-	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	rows, err := database.DB.Query(`
+		SELECT a.id, v.variant, v.format, v.url, v.width, v.height
+		FROM animals a
+		JOIN uploads u ON u.sighting_id = a.id AND u.url = a.image_url
+		JOIN image_variants v ON v.upload_id = u.id
+		WHERE a.id = ANY($1)`, ids)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to hash password"})
+		log.Printf("Failed to load image variants: %v", err)
 		return
 	}
+	defer rows.Close()
 
-	if _, err := database.DB.Exec("UPDATE users SET password = $1 WHERE id = $2", string(newHash), req.UserID); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update password"})
-		return
+	byID := map[int]map[string]models.ImageVariant{}
+	for rows.Next() {
+		var id, width, height int
+		var variant, format, url string
+		if err := rows.Scan(&id, &variant, &format, &url, &width, &height); err != nil {
+			continue
+		}
+		if byID[id] == nil {
+			byID[id] = map[string]models.ImageVariant{}
+		}
+		v := byID[id][variant]
+		v.Width, v.Height = width, height
+		switch format {
+		case imaging.FormatJPEG:
+			v.JPEG = url
+		case imaging.FormatWebP:
+			v.WebP = url
+		}
+		byID[id][variant] = v
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "password changed"})
+	for i := range sightings {
+		sightings[i].ImageVariants = byID[sightings[i].ID]
+	}
 }
 
-// Router for /api/friends and /api/friends/
-func handleFriendsRouter(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/friends")
-	path = strings.TrimSuffix(path, "/")
-	switch path {
-	case "", "/list":
-		handleFriendList(w, r)
-	case "/request":
-		handleFriendRequest(w, r)
-	case "/requests":
-		handleFriendRequests(w, r)
-	case "/accept":
-		handleFriendAccept(w, r)
-	case "/decline":
-		handleFriendDecline(w, r)
-	case "/remove":
-		handleFriendRemove(w, r)
-	default:
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
-	}
+// uploadsHandler serves files written by the local storage driver without
+// directory listings.
+func uploadsHandler(dir string) http.Handler {
+	files := http.StripPrefix("/uploads/", http.FileServer(http.Dir(dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		files.ServeHTTP(w, r)
+	})
 }
 
 func main() {
 	loadEnv(".env")
+	loaded, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal("Invalid configuration:\n", err)
+	}
+	cfg = loaded
+	appStorage = newStorage(cfg)
+	mapCache = mapcache.New(cfg.MapCacheBytes, mapCacheTTL)
 	database.InitDB()
+	bootstrapAdmins()
+	loadInstitutionDomains()
+	backfillObservedAt()
+	startVariantWorkers(cfg.ImageWorkers)
+	resumePendingVariants()
+	appMailer = mailer.FromEnv()
 
 	http.HandleFunc("/api/signup", corsMiddleware(handleSignup))
 	http.HandleFunc("/api/login", corsMiddleware(handleLogin))
-	http.HandleFunc("/api/sightings", corsMiddleware(handleSightings))
-	http.HandleFunc("/api/sightings/", corsMiddleware(handleSightings))
-	http.HandleFunc("/api/stats", corsMiddleware(handleStats))
-	http.HandleFunc("/api/messages/", corsMiddleware(handleDeleteComment))
-	http.HandleFunc("/api/friends", corsMiddleware(handleFriendsRouter))
-	http.HandleFunc("/api/friends/", corsMiddleware(handleFriendsRouter))
-	http.HandleFunc("/api/dm", corsMiddleware(handleDM))
-	http.HandleFunc("/api/users/search", corsMiddleware(handleUserSearch))
-	http.HandleFunc("/api/users/password", corsMiddleware(handleChangePassword))
-	http.HandleFunc("/api/leaderboard", corsMiddleware(handleLeaderboard))
-	http.HandleFunc("/api/reports", corsMiddleware(handleReportsRouter))
-	http.HandleFunc("/api/reports/", corsMiddleware(handleReportsRouter))
-	http.HandleFunc("/api/subscriptions", corsMiddleware(handleSubscriptionsRouter))
-	http.HandleFunc("/api/subscriptions/", corsMiddleware(handleSubscriptionsRouter))
-	http.HandleFunc("/api/notifications", corsMiddleware(handleNotificationsRouter))
-	http.HandleFunc("/api/notifications/", corsMiddleware(handleNotificationsRouter))
-	http.HandleFunc("/api/channels", corsMiddleware(handleChannelsRouter))
-	http.HandleFunc("/api/channels/", corsMiddleware(handleChannelsRouter))
+	http.HandleFunc("/api/password/forgot", corsMiddleware(handleForgotPassword))
+	http.HandleFunc("/api/password/reset", corsMiddleware(handleResetPassword))
+	http.HandleFunc("/api/email/verify", corsMiddleware(handleVerifyEmail))
+	http.HandleFunc("/api/email/resend", corsMiddleware(handleResendVerification))
+	http.HandleFunc("/api/token/refresh", corsMiddleware(handleRefreshToken))
+	http.HandleFunc("/api/logout", corsMiddleware(handleLogout))
+	http.HandleFunc("/api/logout/all", corsMiddleware(authMiddleware(handleLogoutAll)))
+	http.HandleFunc("/api/sightings", corsMiddleware(authMiddleware(handleSightings)))
+	http.HandleFunc("/api/sightings/", corsMiddleware(authMiddleware(handleSightings)))
+	http.HandleFunc("/api/stats", corsMiddleware(authMiddleware(handleStats)))
+	http.HandleFunc("/api/search", corsMiddleware(authMiddleware(handleSearch)))
+	http.HandleFunc("/api/tiles/", corsMiddleware(authMiddleware(handleTile)))
+	http.HandleFunc("/api/species/suggest", corsMiddleware(authMiddleware(handleSpeciesSuggest)))
+	http.HandleFunc("/api/messages/", corsMiddleware(authMiddleware(handleDeleteComment)))
+	http.HandleFunc("/api/friends", corsMiddleware(authMiddleware(handleFriendsRouter)))
+	http.HandleFunc("/api/friends/", corsMiddleware(authMiddleware(handleFriendsRouter)))
+	http.HandleFunc("/api/dm", corsMiddleware(authMiddleware(handleDM)))
+	http.HandleFunc("/api/users/search", corsMiddleware(authMiddleware(handleUserSearch)))
+	http.HandleFunc("/api/users/password", corsMiddleware(authMiddleware(handleChangePassword)))
+	http.HandleFunc("/api/users/", corsMiddleware(authMiddleware(handleUsersRouter)))
+	http.HandleFunc("/api/institutions", corsMiddleware(authMiddleware(handleInstitutions)))
+	http.HandleFunc("/api/categories", corsMiddleware(authMiddleware(handleCategoriesRouter)))
+	http.HandleFunc("/api/categories/", corsMiddleware(authMiddleware(handleCategoriesRouter)))
+	http.HandleFunc("/api/invites", corsMiddleware(authMiddleware(handleInvitesRouter)))
+	http.HandleFunc("/api/invites/", corsMiddleware(authMiddleware(handleInvitesRouter)))
+	http.HandleFunc("/api/uploads", corsMiddleware(authMiddleware(handleUpload)))
+	if cfg.StorageDriver == "local" {
+		http.Handle("/uploads/", uploadsHandler(cfg.UploadDir))
+	}
+	http.HandleFunc("/api/leaderboard", corsMiddleware(authMiddleware(handleLeaderboard)))
+	http.HandleFunc("/api/reports", corsMiddleware(authMiddleware(handleReportsRouter)))
+	http.HandleFunc("/api/reports/", corsMiddleware(authMiddleware(handleReportsRouter)))
+	http.HandleFunc("/api/subscriptions", corsMiddleware(authMiddleware(handleSubscriptionsRouter)))
+	http.HandleFunc("/api/subscriptions/", corsMiddleware(authMiddleware(handleSubscriptionsRouter)))
+	http.HandleFunc("/api/notifications", corsMiddleware(authMiddleware(handleNotificationsRouter)))
+	http.HandleFunc("/api/notifications/", corsMiddleware(authMiddleware(handleNotificationsRouter)))
+	http.HandleFunc("/api/channels", corsMiddleware(authMiddleware(handleChannelsRouter)))
+	http.HandleFunc("/api/channels/", corsMiddleware(authMiddleware(handleChannelsRouter)))
 
 	http.HandleFunc("/api/parking", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -2052,6 +6316,6 @@ func main() {
 		fmt.Fprintln(w, `{"status":"success","data":"Hello UF Wildlife!"}`)
 	})
 
-	fmt.Println("Go backend running on http://localhost:8080")
-	http.ListenAndServe(":8080", nil)
+	fmt.Printf("Go backend running on %s (%s)\n", cfg.ListenAddr, cfg.Env)
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, nil))
 }
@@ -2,13 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"image"
+	"image/png"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"net/url"
+	"parkinGator-backend/config"
+	"parkinGator-backend/exif"
+	"parkinGator-backend/mailer"
+	"parkinGator-backend/mapcache"
+	"parkinGator-backend/models"
+	"parkinGator-backend/mvt"
+	"parkinGator-backend/taxonomy"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -16,8 +32,8 @@ import (
 // ---------- generateJWT ----------
 
 func TestGenerateJWT_Success(t *testing.T) {
-	os.Setenv("JWT_SECRET", "test-secret")
-	tokenStr, err := generateJWT(1, "test@ufl.edu")
+	cfg.JWTSecret = "test-secret"
+	tokenStr, err := generateJWT(1, "test@ufl.edu", "user")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -39,11 +55,30 @@ func TestGenerateJWT_Success(t *testing.T) {
 	if int(claims["user_id"].(float64)) != 1 {
 		t.Errorf("expected user_id claim 1, got %v", claims["user_id"])
 	}
+	if claims["role"] != "user" {
+		t.Errorf("expected role claim 'user', got %v", claims["role"])
+	}
+}
+
+func TestGenerateJWT_ShortLived(t *testing.T) {
+	cfg.JWTSecret = "test-secret"
+	tokenStr, _ := generateJWT(1, "test@ufl.edu", "user")
+	token, _ := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
+		return []byte("test-secret"), nil
+	})
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil {
+		t.Fatalf("expected exp claim, got %v", err)
+	}
+	if time.Until(exp.Time) > accessTokenTTL() {
+		t.Errorf("expected access token to expire within %v, got %v", accessTokenTTL(), time.Until(exp.Time))
+	}
 }
 
 func TestGenerateJWT_DefaultSecret(t *testing.T) {
-	os.Unsetenv("JWT_SECRET")
-	tokenStr, err := generateJWT(99, "admin@ufl.edu")
+	cfg.JWTSecret = config.DefaultJWTSecret
+	defer func() { cfg.JWTSecret = "test-secret" }()
+	tokenStr, err := generateJWT(99, "admin@ufl.edu", "admin")
 	if err != nil {
 		t.Fatalf("expected no error with default secret, got %v", err)
 	}
@@ -52,6 +87,120 @@ func TestGenerateJWT_DefaultSecret(t *testing.T) {
 	}
 }
 
+// ---------- parseJWT ----------
+
+func TestParseJWT_RoundTrip(t *testing.T) {
+	cfg.JWTSecret = "test-secret"
+	tokenStr, _ := generateJWT(5, "gator@ufl.edu", "moderator")
+	user, err := parseJWT(tokenStr)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.ID != 5 || user.Email != "gator@ufl.edu" || user.Role != "moderator" {
+		t.Errorf("unexpected user: %+v", user)
+	}
+}
+
+func TestParseJWT_WrongSecret(t *testing.T) {
+	cfg.JWTSecret = "test-secret"
+	tokenStr, _ := generateJWT(5, "gator@ufl.edu", "moderator")
+	cfg.JWTSecret = "other-secret"
+	defer func() { cfg.JWTSecret = "test-secret" }()
+	if _, err := parseJWT(tokenStr); err == nil {
+		t.Error("expected error for token signed with a different secret")
+	}
+}
+
+func TestParseJWT_Expired(t *testing.T) {
+	cfg.JWTSecret = "test-secret"
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 5,
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	})
+	tokenStr, _ := token.SignedString([]byte("test-secret"))
+	if _, err := parseJWT(tokenStr); err == nil {
+		t.Error("expected error for expired token")
+	}
+}
+
+// ---------- authMiddleware ----------
+
+// withAuthUser attaches an authenticated user the way authMiddleware does.
+func withAuthUser(req *http.Request, id int) *http.Request {
+	return withAuthRole(req, id, "user")
+}
+
+func withAuthRole(req *http.Request, id int, role string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), authUserKey, authUser{ID: id, Role: role}))
+}
+
+// stubStoredRole makes users.role read as role for the rest of the test.
+func stubStoredRole(t *testing.T, role string) {
+	t.Helper()
+	orig := storedRole
+	storedRole = func(int) (string, error) { return role, nil }
+	t.Cleanup(func() { storedRole = orig })
+}
+
+func TestAuthMiddleware_RejectsMutationWithoutToken(t *testing.T) {
+	called := false
+	h := authMiddleware(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings", nil)
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusUnauthorized || called {
+		t.Errorf("expected 401 and handler not called, got %d (called=%v)", w.Code, called)
+	}
+}
+
+func TestAuthMiddleware_AllowsAnonymousRead(t *testing.T) {
+	called := false
+	h := authMiddleware(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings", nil)
+	h(httptest.NewRecorder(), req)
+	if !called {
+		t.Error("expected anonymous GET to reach the handler")
+	}
+}
+
+func TestAuthMiddleware_AnonymousReadsDisabled(t *testing.T) {
+	cfg.AllowAnonymousReads = false
+	defer func() { cfg.AllowAnonymousReads = true }()
+	h := authMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings", nil)
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 when anonymous reads are disabled, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_InvalidToken(t *testing.T) {
+	h := authMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings", nil)
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for invalid token, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_SetsUserInContext(t *testing.T) {
+	cfg.JWTSecret = "test-secret"
+	tokenStr, _ := generateJWT(42, "gator@ufl.edu", "user")
+	var got authUser
+	h := authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = currentUser(r)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenStr)
+	h(httptest.NewRecorder(), req)
+	if got.ID != 42 {
+		t.Errorf("expected user 42 in context, got %+v", got)
+	}
+}
+
 // ---------- handleSignup ----------
 
 func TestHandleSignup_MethodNotAllowed(t *testing.T) {
@@ -90,6 +239,31 @@ func TestHandleSignup_InvalidEmailDomain(t *testing.T) {
 	}
 }
 
+func TestHandleSignup_SubdomainAllowed(t *testing.T) {
+	// Gets past the domain check and stops at the password check.
+	body := `{"username":"testuser","email":"test@cise.ufl.edu","password":"Pass123!","confirmPassword":"Different!"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/signup", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handleSignup(w, req)
+	var resp map[string]string
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !strings.Contains(resp["error"], "Passwords do not match") {
+		t.Errorf("expected subdomain to pass the domain check, got: %s", resp["error"])
+	}
+}
+
+func TestHandleSignup_InviteCodeBypassesDomain(t *testing.T) {
+	body := `{"username":"visitor","email":"visitor@gmail.com","password":"Pass123!","confirmPassword":"Different!","invite_code":"ABCD-EFGH"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/signup", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handleSignup(w, req)
+	var resp map[string]string
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !strings.Contains(resp["error"], "Passwords do not match") {
+		t.Errorf("expected invite code to skip the domain check, got: %s", resp["error"])
+	}
+}
+
 func TestHandleSignup_PasswordMismatch(t *testing.T) {
 	body := `{"username":"testuser","email":"test@ufl.edu","password":"Pass123!","confirmPassword":"Different!"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/signup", bytes.NewBufferString(body))
@@ -138,19 +312,68 @@ func TestHandleLogin_MissingFields(t *testing.T) {
 	}
 }
 
-func TestHandleLogin_InvalidEmailDomain(t *testing.T) {
-	body := `{"email":"user@yahoo.com","password":"somepassword"}`
+func TestHandleLogin_InvalidEmail(t *testing.T) {
+	body := `{"email":"user-at-yahoo.com","password":"somepassword"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	handleLogin(w, req)
 	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for non-ufl.edu email, got %d", w.Code)
+		t.Errorf("expected 400 for malformed email, got %d", w.Code)
 	}
-	var resp map[string]string
-	json.NewDecoder(w.Body).Decode(&resp)
-	if !strings.Contains(resp["error"], "@ufl.edu") {
-		t.Errorf("expected @ufl.edu error message, got: %s", resp["error"])
+}
+
+func TestHandleLogin_LockedOut(t *testing.T) {
+	for i := 0; i < accountLimiter.Threshold; i++ {
+		accountLimiter.Fail("account:locked@ufl.edu")
+	}
+	defer accountLimiter.Reset("account:locked@ufl.edu")
+
+	body := `{"email":"Locked@ufl.edu","password":"whatever"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleLogin(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for locked account, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+}
+
+func TestHandleLogin_IPLockedOut(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"email":"a@ufl.edu","password":"x"}`))
+	ip := clientIP(req)
+	for i := 0; i < ipLimiter.Threshold; i++ {
+		ipLimiter.Fail("ip:" + ip)
+	}
+	defer ipLimiter.Reset("ip:" + ip)
+
+	w := httptest.NewRecorder()
+	handleLogin(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for locked IP, got %d", w.Code)
+	}
+}
+
+// ---------- clientIP ----------
+
+func TestClientIP_IgnoresForwardedByDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if ip := clientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected remote address, got %s", ip)
+	}
+}
+
+func TestClientIP_TrustedProxy(t *testing.T) {
+	cfg.TrustProxyHeaders = true
+	defer func() { cfg.TrustProxyHeaders = false }()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	if ip := clientIP(req); ip != "1.2.3.4" {
+		t.Errorf("expected first forwarded address, got %s", ip)
 	}
 }
 
@@ -196,6 +419,15 @@ func TestHandleDeleteComment_MethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHandleDeleteComment_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/messages/1", nil)
+	w := httptest.NewRecorder()
+	handleDeleteComment(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
+
 // ---------- handleToggleLike ----------
 
 func TestHandleToggleLike_MethodNotAllowed(t *testing.T) {
@@ -228,19 +460,25 @@ func TestHandleToggleLike_InvalidJSON(t *testing.T) {
 	}
 }
 
-func TestHandleToggleLike_MissingUserID(t *testing.T) {
-	body := `{"user_id":0}`
+func TestHandleToggleLike_Unauthenticated(t *testing.T) {
+	body := `{"user_id":1}`
 	req := httptest.NewRequest(http.MethodPost, "/api/sightings/1/like", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	handleToggleLike(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 when user_id missing, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
 	}
-	var resp map[string]string
-	json.NewDecoder(w.Body).Decode(&resp)
-	if !strings.Contains(resp["error"], "user_id") {
-		t.Errorf("expected user_id error message, got: %s", resp["error"])
+}
+
+func TestHandleToggleLike_MismatchedUserID(t *testing.T) {
+	body := `{"user_id":2}`
+	req := withAuthUser(httptest.NewRequest(http.MethodPost, "/api/sightings/1/like", strings.NewReader(body)), 1)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleToggleLike(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when user_id differs from token, got %d", w.Code)
 	}
 }
 
@@ -330,6 +568,289 @@ func TestHandleGetNearby_InvalidLng(t *testing.T) {
 	}
 }
 
+func TestHandleGetNearby_OutOfRange(t *testing.T) {
+	for _, query := range []string{"lat=91&lng=0", "lat=0&lng=-181", "lat=29.6&lng=-82.3&cursor=bogus"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sightings/nearby?"+query, nil)
+		w := httptest.NewRecorder()
+		handleGetNearbySightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestNearbyBox(t *testing.T) {
+	// Every point on the circle must fall inside the box.
+	check := func(lat, lng, radius float64) {
+		b := nearbyBox(lat, lng, radius)
+		for deg := 0.0; deg < 360; deg += 5 {
+			// Walk radius meters along bearing deg.
+			d := radius / earthRadiusMeters
+			brg := deg * math.Pi / 180
+			lat1, lng1 := lat*math.Pi/180, lng*math.Pi/180
+			lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brg))
+			lng2 := lng1 + math.Atan2(math.Sin(brg)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+			pLat, pLng := lat2*180/math.Pi, math.Remainder(lng2*180/math.Pi, 360)
+			inLng := pLng >= b.minLng-1e-9 && pLng <= b.maxLng+1e-9
+			if b.minLng > b.maxLng {
+				inLng = pLng >= b.minLng-1e-9 || pLng <= b.maxLng+1e-9
+			}
+			if pLat < b.minLat-1e-9 || pLat > b.maxLat+1e-9 || !inLng {
+				t.Errorf("point %.5f,%.5f at bearing %v from %v,%v is outside %+v", pLat, pLng, deg, lat, lng, b)
+				return
+			}
+		}
+	}
+	check(29.6436, -82.3549, 10000)
+	check(64.8, -147.7, 50000)
+	check(-16.5, 179.95, 20000)
+
+	if b := nearbyBox(0, 179.99, 5000); b.minLng <= b.maxLng {
+		t.Errorf("expected the box to cross the antimeridian, got %+v", b)
+	}
+	if b := nearbyBox(89.99, 0, 5000); b.minLng != -180 || b.maxLng != 180 || b.maxLat != 90 {
+		t.Errorf("expected a polar circle to span every longitude, got %+v", b)
+	}
+	if b := nearbyBox(29.6436, -82.3549, 1000); b.maxLat-b.minLat > 0.02 || b.maxLng-b.minLng > 0.03 {
+		t.Errorf("expected a tight box for a 1 km radius, got %+v", b)
+	}
+}
+
+// ---------- handleGetViewportSightings ----------
+
+func TestParseViewport(t *testing.T) {
+	b, msg := parseViewport(url.Values{"min_lat": {"29.6"}, "min_lng": {"-82.4"}, "max_lat": {"29.7"}, "max_lng": {"-82.3"}})
+	if msg != "" || b != (bbox{minLng: -82.4, minLat: 29.6, maxLng: -82.3, maxLat: 29.7}) {
+		t.Errorf("unexpected viewport %+v %q", b, msg)
+	}
+	// Crossing the antimeridian is allowed.
+	if _, msg := parseViewport(url.Values{"min_lat": {"-10"}, "min_lng": {"170"}, "max_lat": {"10"}, "max_lng": {"-170"}}); msg != "" {
+		t.Errorf("unexpected error for an antimeridian viewport: %s", msg)
+	}
+}
+
+func TestHandleGetViewportSightings_Validation(t *testing.T) {
+	const box = "min_lat=29.6&min_lng=-82.4&max_lat=29.7&max_lng=-82.3"
+	for _, query := range []string{
+		"", "min_lat=29.6&min_lng=-82.4&max_lat=29.7",
+		"min_lat=x&min_lng=-82.4&max_lat=29.7&max_lng=-82.3",
+		"min_lat=29.8&min_lng=-82.4&max_lat=29.7&max_lng=-82.3",
+		"min_lat=-91&min_lng=-82.4&max_lat=29.7&max_lng=-82.3",
+		"min_lat=29.6&min_lng=-182&max_lat=29.7&max_lng=-82.3",
+		box + "&limit=0", box + "&sort=species", box + "&min_quantity=0",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sightings/bbox?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings/bbox?"+box, nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+// ---------- handleGetSightingClusters ----------
+
+func TestHandleGetSightingClusters_Validation(t *testing.T) {
+	const box = "min_lat=29.6&min_lng=-82.4&max_lat=29.7&max_lng=-82.3"
+	for _, query := range []string{
+		"zoom=10", box, box + "&zoom=-1", box + "&zoom=23", box + "&zoom=x",
+		box + "&zoom=10&bbox=1,2,3", box + "&zoom=10&has_photo=maybe",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sightings/clusters?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestCellBounds(t *testing.T) {
+	// Zoom 0 has four cells across; the top-left one starts at the
+	// antimeridian and the web map's north edge.
+	n := gridCells(0, clusterCellPx)
+	if n != 4 {
+		t.Fatalf("expected 4 cells at zoom 0, got %d", n)
+	}
+	b := cellBounds(0, 0, n)
+	if b[0] != -180 || b[2] != -90 || math.Abs(b[3]-mercatorMaxLat) > 1e-6 {
+		t.Errorf("unexpected bounds for cell 0,0: %v", b)
+	}
+	if b := cellBounds(1, 1, n); math.Abs(b[1]) > 1e-9 || b[2] != 0 {
+		t.Errorf("expected cell 1,1 to end at the equator and prime meridian, got %v", b)
+	}
+	if gridCells(clusterMaxZoom, clusterCellPx) != 1<<(clusterMaxZoom+2) {
+		t.Errorf("unexpected cell count at zoom %d: %d", clusterMaxZoom, gridCells(clusterMaxZoom, clusterCellPx))
+	}
+}
+
+// ---------- handleTile ----------
+
+func TestParseTilePath(t *testing.T) {
+	if z, x, y, msg := parseTilePath("/api/tiles/12/1110/1694.mvt"); msg != "" || z != 12 || x != 1110 || y != 1694 {
+		t.Errorf("unexpected tile %d/%d/%d %q", z, x, y, msg)
+	}
+	for _, p := range []string{
+		"/api/tiles/12/1110/1694", "/api/tiles/12/1110.mvt", "/api/tiles/a/1/1.mvt",
+		"/api/tiles/-1/0/0.mvt", "/api/tiles/23/0/0.mvt", "/api/tiles/1/2/0.mvt", "/api/tiles/1/0/-1.mvt",
+	} {
+		if _, _, _, msg := parseTilePath(p); msg == "" {
+			t.Errorf("%s: expected an error", p)
+		}
+	}
+}
+
+func TestHandleTile_Validation(t *testing.T) {
+	for _, target := range []string{"/api/tiles/0/1/0.mvt", "/api/tiles/0/0/0.png", "/api/tiles/0/0/0.mvt?has_photo=maybe"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handleTile(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tiles/0/0/0.mvt", nil)
+	w := httptest.NewRecorder()
+	handleTile(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleTile_Cached(t *testing.T) {
+	defer func(c *mapcache.Cache) { mapCache = c }(mapCache)
+	mapCache = mapcache.New(1<<20, time.Minute)
+	e := mapcache.NewEntry([]byte("tile"))
+	filter, _ := parseSightingFilter(url.Values{"category": {"Bird"}})
+	mapCache.Put("tile:3/2/1:"+filter.cacheKey(), mapCache.Generation(), e)
+
+	// Parameters that aren't filters don't change which tile is served.
+	req := httptest.NewRequest(http.MethodGet, "/api/tiles/3/2/1.mvt?v=2&category=Bird", nil)
+	w := httptest.NewRecorder()
+	handleTile(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "tile" {
+		t.Fatalf("expected the cached tile, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != e.ETag || w.Header().Get("Content-Type") != mvt.ContentType {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tiles/3/2/1.mvt?category=Bird", nil)
+	req.Header.Set("If-None-Match", `"other", `+e.ETag)
+	w = httptest.NewRecorder()
+	handleTile(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304 with no body, got %d %q", w.Code, w.Body.String())
+	}
+
+	sightingsChanged()
+	if mapCache.Len() != 0 {
+		t.Error("expected a sighting change to purge cached tiles")
+	}
+}
+
+func TestLikesChanged_PurgesTilesHoldingTheSighting(t *testing.T) {
+	defer func(c *mapcache.Cache) { mapCache = c }(mapCache)
+	mapCache = mapcache.New(1<<20, time.Minute)
+	orig := sightingLocation
+	defer func() { sightingLocation = orig }()
+	// Gainesville is in tile 4/4/6 but not 4/5/6.
+	sightingLocation = func(int) (float64, float64, error) { return 29.65, -82.34, nil }
+
+	gen := mapCache.Generation()
+	for _, key := range []string{"tile:4/4/6:a", "tile:4/4/6:b", "tile:4/5/6:a", "heatmap:4:count:a"} {
+		mapCache.Put(key, gen, mapcache.NewEntry([]byte(key)))
+	}
+	likesChanged(1)
+	for key, kept := range map[string]bool{"tile:4/4/6:a": false, "tile:4/4/6:b": false, "tile:4/5/6:a": true, "heatmap:4:count:a": true} {
+		if _, ok := mapCache.Get(key); ok != kept {
+			t.Errorf("%s: expected cached=%v after a like", key, kept)
+		}
+	}
+
+	// Without the sighting's location every tile is dropped.
+	mapCache.Put("tile:4/5/6:a", mapCache.Generation(), mapcache.NewEntry([]byte("tile")))
+	sightingLocation = func(int) (float64, float64, error) { return 0, 0, sql.ErrNoRows }
+	likesChanged(1)
+	if _, ok := mapCache.Get("tile:4/5/6:a"); ok {
+		t.Error("expected every tile to be dropped when the location is unknown")
+	}
+	if _, ok := mapCache.Get("heatmap:4:count:a"); !ok {
+		t.Error("expected heatmaps to stay cached")
+	}
+}
+
+func TestBBoxContains(t *testing.T) {
+	b := bbox{minLng: -83, minLat: 29, maxLng: -82, maxLat: 30}
+	if !b.contains(29.65, -82.34) || b.contains(29.65, -81) || b.contains(31, -82.34) {
+		t.Error("unexpected result for a plain box")
+	}
+	// A box crossing the antimeridian.
+	b = bbox{minLng: 170, minLat: -10, maxLng: -170, maxLat: 10}
+	if !b.contains(0, 175) || !b.contains(0, -175) || b.contains(0, 0) {
+		t.Error("unexpected result for a box crossing the antimeridian")
+	}
+}
+
+// ---------- handleGetSightingHeatmap ----------
+
+func TestHandleGetSightingHeatmap_Validation(t *testing.T) {
+	const box = "min_lat=29.6&min_lng=-82.4&max_lat=29.7&max_lng=-82.3"
+	for _, query := range []string{
+		"zoom=10", box, box + "&zoom=23", box + "&zoom=10&weight=likes",
+		box + "&zoom=10&observed_from=yesterday", box + "&zoom=10&category=Bird&bbox=1,2",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sightings/heatmap?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings/heatmap?"+box+"&zoom=10", nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleGetSightingHeatmap_Cached(t *testing.T) {
+	defer func(c *mapcache.Cache) { mapCache = c }(mapCache)
+	mapCache = mapcache.New(1<<20, time.Minute)
+	q := url.Values{
+		"min_lat": {"29.6"}, "min_lng": {"-82.4"}, "max_lat": {"29.7"}, "max_lng": {"-82.3"},
+		"zoom": {"15"}, "weight": {"quantity"}, "species": {"Sandhill Crane"},
+	}
+	e := mapcache.NewEntry([]byte(`{"cells":[]}`))
+	filter, _ := parseSightingFilter(q)
+	mapCache.Put("heatmap:15:quantity:-82.4,29.6,-82.3,29.7:"+filter.cacheKey(), mapCache.Generation(), e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings/heatmap?"+q.Encode(), nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != `{"cells":[]}` || w.Header().Get("ETag") != e.ETag {
+		t.Fatalf("expected the cached heatmap, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sightings/heatmap?"+q.Encode(), nil)
+	req.Header.Set("If-None-Match", e.ETag)
+	w = httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", w.Code)
+	}
+}
+
 // ---------- handleGetSightings (category filter) ----------
 
 func TestHandleGetSightings_MethodNotAllowed(t *testing.T) {
@@ -375,14 +896,25 @@ func TestHandleCreateChannel_NameTooLong(t *testing.T) {
 	}
 }
 
-func TestHandleCreateChannel_MissingCreatorID(t *testing.T) {
+func TestHandleCreateChannel_Unauthenticated(t *testing.T) {
 	body := `{"name":"Lake Alice","creator_id":0}`
 	req := httptest.NewRequest(http.MethodPost, "/api/channels", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	handleCreateChannel(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateChannel_MismatchedCreatorID(t *testing.T) {
+	body := `{"name":"Lake Alice","creator_id":7}`
+	req := withAuthUser(httptest.NewRequest(http.MethodPost, "/api/channels", strings.NewReader(body)), 3)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleCreateChannel(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
 	}
 }
 
@@ -449,14 +981,25 @@ func TestHandleCreateChannelMessage_InvalidChannelID(t *testing.T) {
 	}
 }
 
-func TestHandleCreateChannelMessage_MissingSenderID(t *testing.T) {
+func TestHandleCreateChannelMessage_Unauthenticated(t *testing.T) {
 	body := `{"sender_id":0,"content":"hello"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/channels/1/messages", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	handleCreateChannelMessage(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateChannelMessage_MismatchedSenderID(t *testing.T) {
+	body := `{"sender_id":9,"content":"hello"}`
+	req := withAuthUser(httptest.NewRequest(http.MethodPost, "/api/channels/1/messages", strings.NewReader(body)), 1)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleCreateChannelMessage(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
 	}
 }
 
@@ -504,14 +1047,14 @@ func TestHandleCreateSubscription_MethodNotAllowed(t *testing.T) {
 	}
 }
 
-func TestHandleCreateSubscription_MissingUserID(t *testing.T) {
+func TestHandleCreateSubscription_Unauthenticated(t *testing.T) {
 	body := `{"user_id":0,"type":"species","value":"Crane"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	handleCreateSubscription(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
 	}
 }
 
@@ -563,12 +1106,21 @@ func TestHandleGetSubscriptions_MethodNotAllowed(t *testing.T) {
 	}
 }
 
-func TestHandleGetSubscriptions_MissingUserID(t *testing.T) {
+func TestHandleGetSubscriptions_Unauthenticated(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
 	w := httptest.NewRecorder()
 	handleGetSubscriptions(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for missing user_id, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
+
+func TestHandleGetSubscriptions_OtherUser(t *testing.T) {
+	req := withAuthUser(httptest.NewRequest(http.MethodGet, "/api/subscriptions?user_id=2", nil), 1)
+	w := httptest.NewRecorder()
+	handleGetSubscriptions(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another user's subscriptions, got %d", w.Code)
 	}
 }
 
@@ -612,12 +1164,12 @@ func TestHandleGetNotifications_MethodNotAllowed(t *testing.T) {
 	}
 }
 
-func TestHandleGetNotifications_MissingUserID(t *testing.T) {
+func TestHandleGetNotifications_Unauthenticated(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/notifications", nil)
 	w := httptest.NewRecorder()
 	handleGetNotifications(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for missing user_id, got %d", w.Code)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
 	}
 }
 
@@ -707,6 +1259,24 @@ func TestHandleGetReports_MethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestHandleGetReports_RequiresModerator(t *testing.T) {
+	req := withAuthUser(httptest.NewRequest(http.MethodGet, "/api/reports", nil), 1)
+	w := httptest.NewRecorder()
+	handleGetReports(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a regular user, got %d", w.Code)
+	}
+}
+
+func TestHandleGetReports_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/reports", nil)
+	w := httptest.NewRecorder()
+	handleGetReports(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
 // ---------- handleUpdateReport ----------
 
 func TestHandleUpdateReport_MethodNotAllowed(t *testing.T) {
@@ -755,26 +1325,229 @@ func TestHandleUpdateReport_InvalidJSON(t *testing.T) {
 	}
 }
 
-// ---------- handleLeaderboard ----------
-
-func TestHandleLeaderboard_MethodNotAllowed(t *testing.T) {
-	req := httptest.NewRequest(http.MethodPost, "/api/leaderboard", nil)
+func TestHandleUpdateReport_RequiresModerator(t *testing.T) {
+	body := `{"status":"resolved"}`
+	req := withAuthUser(httptest.NewRequest(http.MethodPut, "/api/reports/1", strings.NewReader(body)), 1)
+	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
-	handleLeaderboard(w, req)
-	if w.Code != http.StatusMethodNotAllowed {
-		t.Errorf("expected 405, got %d", w.Code)
+	handleUpdateReport(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a regular user, got %d", w.Code)
 	}
 }
 
-func TestHandleLeaderboard_InvalidSort(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/leaderboard?sort=invalid", nil)
+// ---------- handleSetUserRole ----------
+
+func TestHandleSetUserRole_InvalidRole(t *testing.T) {
+	body := `{"role":"superuser"}`
+	req := withAuthRole(httptest.NewRequest(http.MethodPut, "/api/users/2/role", strings.NewReader(body)), 1, "admin")
 	w := httptest.NewRecorder()
-	handleLeaderboard(w, req)
+	handleSetUserRole(w, req)
 	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for invalid sort, got %d", w.Code)
+		t.Errorf("expected 400 for invalid role, got %d", w.Code)
 	}
-	var resp map[string]string
-	json.NewDecoder(w.Body).Decode(&resp)
+}
+
+func TestHandleSetUserRole_RequiresAdmin(t *testing.T) {
+	body := `{"role":"moderator"}`
+	req := withAuthRole(httptest.NewRequest(http.MethodPut, "/api/users/2/role", strings.NewReader(body)), 1, "moderator")
+	w := httptest.NewRecorder()
+	handleSetUserRole(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a moderator, got %d", w.Code)
+	}
+}
+
+func TestHandleSetUserRole_Self(t *testing.T) {
+	stubStoredRole(t, "admin")
+	body := `{"role":"user"}`
+	req := withAuthRole(httptest.NewRequest(http.MethodPut, "/api/users/1/role", strings.NewReader(body)), 1, "admin")
+	w := httptest.NewRecorder()
+	handleSetUserRole(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when changing own role, got %d", w.Code)
+	}
+}
+
+func TestRequireRole_ReadsStoredRole(t *testing.T) {
+	// A token minted before a demotion still claims the old role.
+	stubStoredRole(t, models.RoleUser)
+	req := withAuthRole(httptest.NewRequest(http.MethodGet, "/api/reports", nil), 1, models.RoleAdmin)
+	w := httptest.NewRecorder()
+	if requireRole(w, req, models.RoleAdmin) || w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a demoted admin, got %d", w.Code)
+	}
+
+	storedRole = func(int) (string, error) { return "", sql.ErrNoRows }
+	w = httptest.NewRecorder()
+	if requireRole(w, req, models.RoleAdmin) || w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a deleted user, got %d", w.Code)
+	}
+
+	stubStoredRole(t, models.RoleAdmin)
+	if !requireRole(httptest.NewRecorder(), req, models.RoleModerator, models.RoleAdmin) {
+		t.Error("expected a current admin to be allowed")
+	}
+}
+
+func TestRequireRole_TokenWithoutRoleSkipsLookup(t *testing.T) {
+	stubStoredRole(t, models.RoleAdmin)
+	called := false
+	storedRole = func(int) (string, error) { called = true; return models.RoleAdmin, nil }
+	req := withAuthUser(httptest.NewRequest(http.MethodGet, "/api/reports", nil), 1)
+	w := httptest.NewRecorder()
+	if requireRole(w, req, models.RoleModerator) || w.Code != http.StatusForbidden || called {
+		t.Errorf("expected 403 without a lookup, got %d (looked up=%v)", w.Code, called)
+	}
+}
+
+func TestIsBootstrapAdmin(t *testing.T) {
+	cfg.BootstrapAdminEmails = []string{"boss@ufl.edu", "Chief@ufl.edu"}
+	defer func() { cfg.BootstrapAdminEmails = nil }()
+	if !isBootstrapAdmin("chief@ufl.edu") {
+		t.Error("expected case-insensitive match")
+	}
+	if isBootstrapAdmin("someone@ufl.edu") {
+		t.Error("expected no match for unlisted email")
+	}
+}
+
+// ---------- Institutions & Invites ----------
+
+func TestEmailDomainAllowed(t *testing.T) {
+	cfg.EmailDomains = []string{"ufl.edu", "fsu.edu"}
+	defer func() { cfg.EmailDomains = []string{"ufl.edu"} }()
+	cases := map[string]bool{
+		"gator@ufl.edu":      true,
+		"gator@UFL.EDU":      true,
+		"gator@cise.ufl.edu": true,
+		"nole@fsu.edu":       true,
+		"gator@notufl.edu":   false,
+		"gator@ufl.edu.evil": false,
+		"gator@gmail.com":    false,
+		"ufl.edu":            false,
+	}
+	for email, want := range cases {
+		if got := emailDomainAllowed(email); got != want {
+			t.Errorf("emailDomainAllowed(%q) = %v, want %v", email, got, want)
+		}
+	}
+	if got := allowedDomainsText(); got != "@ufl.edu, @fsu.edu" {
+		t.Errorf("unexpected allowed domains text: %s", got)
+	}
+}
+
+func TestEmailDomainAllowed_Institutions(t *testing.T) {
+	institutionDomains.list = []string{"fsu.edu", "ufl.edu"}
+	defer func() { institutionDomains.list = nil }()
+	if !emailDomainAllowed("nole@fsu.edu") || !emailDomainAllowed("nole@cs.fsu.edu") {
+		t.Error("expected an institution's domain to be allowed")
+	}
+	if emailDomainAllowed("gator@gmail.com") {
+		t.Error("expected other domains to stay refused")
+	}
+	if got := allowedDomainsText(); got != "@ufl.edu, @fsu.edu" {
+		t.Errorf("unexpected allowed domains text: %s", got)
+	}
+}
+
+func TestGenerateInviteCode(t *testing.T) {
+	a, err := generateInviteCode()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	b, _ := generateInviteCode()
+	if len(a) != 19 || a == b {
+		t.Errorf("expected distinct XXXX-XXXX-XXXX-XXXX codes, got %s and %s", a, b)
+	}
+	if normalizeInviteCode(strings.ToLower(a)) != strings.ReplaceAll(a, "-", "") {
+		t.Error("expected normalization to ignore case and dashes")
+	}
+}
+
+func TestHandleInstitutions_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/institutions", nil)
+	w := httptest.NewRecorder()
+	handleInstitutions(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleInstitutions_InvalidDomain(t *testing.T) {
+	req := withAuthRole(httptest.NewRequest(http.MethodPost, "/api/institutions", strings.NewReader(`{"name":"FSU","domain":"fsu"}`)), 1, "admin")
+	w := httptest.NewRecorder()
+	handleInstitutions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid domain, got %d", w.Code)
+	}
+}
+
+func TestHandleInstitutions_NonAdmin(t *testing.T) {
+	req := withAuthUser(httptest.NewRequest(http.MethodPost, "/api/institutions", strings.NewReader(`{"name":"FSU","domain":"fsu.edu"}`)), 1)
+	w := httptest.NewRecorder()
+	handleInstitutions(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for non-admin, got %d", w.Code)
+	}
+}
+
+func TestHandleInvitesRouter_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/invites", nil)
+	w := httptest.NewRecorder()
+	handleInvitesRouter(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateInvite_InvalidMaxUses(t *testing.T) {
+	req := withAuthRole(httptest.NewRequest(http.MethodPost, "/api/invites", strings.NewReader(`{"max_uses":5000}`)), 1, "admin")
+	w := httptest.NewRecorder()
+	handleInvitesRouter(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for max_uses out of range, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateInvite_NonAdmin(t *testing.T) {
+	req := withAuthRole(httptest.NewRequest(http.MethodPost, "/api/invites", strings.NewReader(`{}`)), 1, "moderator")
+	w := httptest.NewRecorder()
+	handleInvitesRouter(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for non-admin, got %d", w.Code)
+	}
+}
+
+func TestHandleRevokeInvite_InvalidID(t *testing.T) {
+	req := withAuthRole(httptest.NewRequest(http.MethodDelete, "/api/invites/abc", nil), 1, "admin")
+	w := httptest.NewRecorder()
+	handleInvitesRouter(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid invite ID, got %d", w.Code)
+	}
+}
+
+// ---------- handleLeaderboard ----------
+
+func TestHandleLeaderboard_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/leaderboard", nil)
+	w := httptest.NewRecorder()
+	handleLeaderboard(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleLeaderboard_InvalidSort(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/leaderboard?sort=invalid", nil)
+	w := httptest.NewRecorder()
+	handleLeaderboard(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid sort, got %d", w.Code)
+	}
+	var resp map[string]string
+	json.NewDecoder(w.Body).Decode(&resp)
 	if !strings.Contains(resp["error"], "sightings, species, likes") {
 		t.Errorf("expected sort options in error, got: %s", resp["error"])
 	}
@@ -826,6 +1599,241 @@ func TestHandleCreateSighting_MissingSpecies(t *testing.T) {
 	}
 }
 
+func TestHandleCreateSighting_InvalidTaxonID(t *testing.T) {
+	body := `{"taxon_id":0,"latitude":29.6,"longitude":-82.3}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleCreateSighting(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for taxon_id 0, got %d", w.Code)
+	}
+}
+
+func TestMatchTaxon_EmptyName(t *testing.T) {
+	// Blank or punctuation-only names must not reach the database.
+	if _, ok := matchTaxon(" -- "); ok {
+		t.Error("expected no match for an empty name")
+	}
+}
+
+func TestHandleCreateSighting_CategoryTooLong(t *testing.T) {
+	body := `{"species":"Crane","category":"` + strings.Repeat("x", 41) + `","latitude":29.6,"longitude":-82.3}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleCreateSighting(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a long category, got %d", w.Code)
+	}
+}
+
+// ---------- Categories ----------
+
+func TestValidateCategory(t *testing.T) {
+	c := models.Category{Name: "  Mollusk "}
+	if msg := validateCategory(&c); msg != "" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+	if c.Name != "Mollusk" || c.DisplayName != "Mollusk" || c.Color != "#757575" {
+		t.Errorf("expected trimmed name and defaults, got %+v", c)
+	}
+
+	bad := []models.Category{
+		{},
+		{Name: strings.Repeat("x", 41)},
+		{Name: "Mollusk", DisplayName: strings.Repeat("x", 61)},
+		{Name: "Mollusk", Icon: strings.Repeat("x", 33)},
+		{Name: "Mollusk", Color: "red"},
+		{Name: "Mollusk", Color: "#12345G"},
+	}
+	for _, c := range bad {
+		if msg := validateCategory(&c); msg == "" {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
+
+func TestHandleCategoriesRouter_MethodNotAllowed(t *testing.T) {
+	cases := []struct{ method, path string }{
+		{http.MethodDelete, "/api/categories"},
+		{http.MethodGet, "/api/categories/1"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		handleCategoriesRouter(w, req)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected 405, got %d", c.method, c.path, w.Code)
+		}
+	}
+}
+
+func TestHandleCategoriesRouter_InvalidID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/categories/abc", strings.NewReader(`{"name":"Bird"}`))
+	w := httptest.NewRecorder()
+	handleCategoriesRouter(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid id, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateCategory_Validation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Mollusk","color":"blue"}`))
+	w := httptest.NewRecorder()
+	handleCategoriesRouter(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad color, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateCategory_RequiresAdmin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name":"Mollusk"}`))
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleCategoriesRouter(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", w.Code)
+	}
+}
+
+func TestHandleDeleteCategory_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/categories/3", nil)
+	w := httptest.NewRecorder()
+	handleCategoriesRouter(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a user, got %d", w.Code)
+	}
+}
+
+// ---------- Species suggestions ----------
+
+func TestHandleSpeciesSuggest_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/species/suggest?q=crane", nil)
+	w := httptest.NewRecorder()
+	handleSpeciesSuggest(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleSpeciesSuggest_Validation(t *testing.T) {
+	for _, query := range []string{"", "q=", "q=--", "q=" + strings.Repeat("a", 101), "q=crane&limit=0", "q=crane&limit=26", "q=crane&limit=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/species/suggest?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSpeciesSuggest(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandleSpeciesSuggest_CachedCandidates(t *testing.T) {
+	defer func() { speciesCandidates = speciesIndex{} }()
+	speciesCandidates = speciesIndex{
+		built: true,
+		candidates: []taxonomy.Candidate{
+			{Key: "taxon:1", Name: "Sandhill Crane", Canonical: "Sandhill Crane", Sightings: 4},
+			{Key: "name:heron", Name: "heron", Canonical: "heron", Sightings: 2},
+		},
+		taxa: map[string]speciesTaxon{"taxon:1": {id: 1, scientificName: "Antigone canadensis", category: "Bird"}},
+	}
+
+	// Served from the cache, so no database is needed.
+	req := httptest.NewRequest(http.MethodGet, "/api/species/suggest?q=sandhil", nil)
+	w := httptest.NewRecorder()
+	handleSpeciesSuggest(w, req)
+	var got []map[string]any
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || len(got) != 1 || got[0]["name"] != "Sandhill Crane" || got[0]["category"] != "Bird" {
+		t.Fatalf("unexpected suggestions %d %v", w.Code, got)
+	}
+
+	sightingsChanged()
+	if speciesCandidates.builtGen == speciesCandidates.gen {
+		t.Error("expected a sighting change to mark the cached names stale")
+	}
+}
+
+// ---------- Search ----------
+
+func TestHandleSearch_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/search?q=heron", nil)
+	w := httptest.NewRecorder()
+	handleSearch(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleSearch_Validation(t *testing.T) {
+	for _, query := range []string{
+		"", "q=", "q=%20%20", "q=" + strings.Repeat("a", 201),
+		"q=heron&type=user", "q=heron&type=sighting,dm",
+		"q=heron&page=0", "q=heron&page=x", "q=heron&limit=0", "q=heron&limit=-5",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+query, nil)
+		w := httptest.NewRecorder()
+		handleSearch(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestParseSearchTypes(t *testing.T) {
+	cases := map[string][]string{
+		"":                          {"sighting", "comment", "channel_message"},
+		"comment":                   {"comment"},
+		"channel_message, sighting": {"sighting", "channel_message"},
+		"comment,comment":           {"comment"},
+	}
+	for in, want := range cases {
+		got, ok := parseSearchTypes(in)
+		if !ok || strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("parseSearchTypes(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+}
+
+func TestSearchQueries_EveryTypeSubset(t *testing.T) {
+	// The outer query reads the union through the first branch's column
+	// names, so whichever type comes first must alias all of them.
+	columns := []string{"type", "id", "sighting_id", "channel_id", "title", "author", "body", "rank", "created_at"}
+	all := []string{"sighting", "comment", "channel_message"}
+	for mask := 1; mask < 1<<len(all); mask++ {
+		var types []string
+		for i, t := range all {
+			if mask&(1<<i) != 0 {
+				types = append(types, t)
+			}
+		}
+		count, page := searchQueries(types)
+		for _, typ := range types {
+			if !strings.Contains(count, searchTypes[typ]) || !strings.Contains(page, searchTypes[typ]) {
+				t.Errorf("%v: query is missing the %s branch", types, typ)
+			}
+		}
+		if n := strings.Count(page, "UNION ALL"); n != len(types)-1 {
+			t.Errorf("%v: expected %d UNION ALL, got %d", types, len(types)-1, n)
+		}
+		first := page[strings.Index(page, "FROM (")+len("FROM ("):]
+		selectList := first[:strings.Index(first, "FROM")]
+		for _, col := range columns {
+			if !strings.Contains(selectList, " AS "+col+",") && !strings.HasSuffix(strings.TrimSpace(selectList), " AS "+col) {
+				t.Errorf("%v: first branch does not alias %s", types, col)
+			}
+		}
+	}
+}
+
+func TestSearchHighlight(t *testing.T) {
+	in := "a " + headlineStart + "heron" + headlineStop + " near <script>alert(1)</script>"
+	want := "a <mark>heron</mark> near &lt;script&gt;alert(1)&lt;/script&gt;"
+	if got := searchHighlight(in); got != want {
+		t.Errorf("searchHighlight() = %q, want %q", got, want)
+	}
+}
+
 func TestHandleCreateSighting_SpeciesTooLong(t *testing.T) {
 	longName := strings.Repeat("x", 201)
 	body := `{"species":"` + longName + `","latitude":29.6,"longitude":-82.3}`
@@ -918,6 +1926,17 @@ func TestHandleUpdateSighting_InvalidID(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateSighting_Unauthenticated(t *testing.T) {
+	body := `{"species":"Crane"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/sightings/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handleUpdateSighting(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
+
 // ---------- handleDeleteSighting validation ----------
 
 func TestHandleDeleteSighting_MethodNotAllowed(t *testing.T) {
@@ -938,73 +1957,473 @@ func TestHandleDeleteSighting_InvalidID(t *testing.T) {
 	}
 }
 
-// ---------- handleGetSightings pagination ----------
+func TestHandleDeleteSighting_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/sightings/1", nil)
+	w := httptest.NewRecorder()
+	handleDeleteSighting(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
 
-func TestParsePaginationParams_Defaults(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=2&limit=15", nil)
-	pageStr := req.URL.Query().Get("page")
-	limitStr := req.URL.Query().Get("limit")
+// ---------- Observation time ----------
 
-	page := 1
-	limit := 20
-	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-		page = p
+func TestObservationTime_DateAndTime(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := models.CreateSightingRequest{Date: "2026-02-18", Time: "14:30"}
+	got, given, msg := observationTime(&req, now)
+	if msg != "" || !given {
+		t.Fatalf("unexpected result: given=%v msg=%q", given, msg)
 	}
-	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-		limit = l
-		if limit > 100 {
-			limit = 100
-		}
+	// America/New_York is UTC-5 in February.
+	if want := time.Date(2026, 2, 18, 19, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
 	}
+}
 
-	if page != 2 {
-		t.Errorf("expected page 2, got %d", page)
-	}
-	if limit != 15 {
-		t.Errorf("expected limit 15, got %d", limit)
+func TestObservationTime_ObservedAtRewritesText(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := models.CreateSightingRequest{ObservedAt: "2026-02-18T19:30:00Z", Date: "2020-01-01"}
+	if _, given, msg := observationTime(&req, now); msg != "" || !given {
+		t.Fatalf("unexpected result: given=%v msg=%q", given, msg)
 	}
-	offset := (page - 1) * limit
-	if offset != 15 {
-		t.Errorf("expected offset 15, got %d", offset)
+	if req.Date != "2026-02-18" || req.Time != "14:30" {
+		t.Errorf("expected local date and time, got %s %s", req.Date, req.Time)
 	}
 }
 
-func TestParsePaginationParams_LimitCap(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=1&limit=999", nil)
-	limitStr := req.URL.Query().Get("limit")
-
-	limit := 20
-	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-		limit = l
-		if limit > 100 {
-			limit = 100
-		}
+func TestObservationTime_Invalid(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []models.CreateSightingRequest{
+		{Date: "02/18/2026"},
+		{Date: "2026-02-30"},
+		{Date: "2026-02-18", Time: "2:30pm"},
+		{Time: "14:30"},
+		{ObservedAt: "2026-02-18 14:30"},
+		{Date: "2026-03-02"},
+		{ObservedAt: "2026-03-01T12:30:00Z"},
+		{Date: "1850-06-01"},
 	}
-	if limit != 100 {
-		t.Errorf("expected limit capped at 100, got %d", limit)
+	for _, req := range cases {
+		if _, _, msg := observationTime(&req, now); msg == "" {
+			t.Errorf("expected an error for %+v", req)
+		}
 	}
 }
 
-func TestParsePaginationParams_NegativePageDefaults(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=-5&limit=10", nil)
-	pageStr := req.URL.Query().Get("page")
-
-	page := 1
-	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-		page = p
-	}
-	if page != 1 {
-		t.Errorf("expected negative page to default to 1, got %d", page)
+func TestObservationTime_NoneGiven(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	got, given, msg := observationTime(&models.CreateSightingRequest{}, now)
+	if given || msg != "" || !got.Equal(now) {
+		t.Errorf("expected now and given=false, got %v %v %q", got, given, msg)
 	}
 }
 
-func TestParsePaginationParams_NoPagination(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/sightings", nil)
+func TestHandleCreateSighting_FutureDate(t *testing.T) {
+	future := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	body := `{"species":"Crane","date":"` + future + `","latitude":29.6,"longitude":-82.3}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleCreateSighting(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a future date, got %d", w.Code)
+	}
+}
+
+func TestHandleUpdateSighting_InvalidTime(t *testing.T) {
+	body := `{"species":"Crane","date":"2026-01-05","time":"25:00"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/sightings/1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleUpdateSighting(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid time, got %d", w.Code)
+	}
+}
+
+func TestHandleGetSightings_InvalidSortAndRange(t *testing.T) {
+	for _, query := range []string{"sort=species", "observed_from=yesterday", "observed_to=2026-13-01"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sightings?"+query, nil)
+		w := httptest.NewRecorder()
+		handleGetSightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// ---------- Sighting filters ----------
+
+func TestParseSightingFilter_Combined(t *testing.T) {
+	q, _ := url.ParseQuery("species=Sandhill+Crane&user_id=4&bbox=-82.4,29.6,-82.3,29.7&min_quantity=2&has_photo=true&q=50%25_lake")
+	f, msg := parseSightingFilter(q)
+	if msg != "" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+	where := f.where()
+	for _, want := range []string{"LOWER(a.species) = LOWER($1)", "a.user_id = $2", "point(a.longitude, a.latitude) <@ box(point($3, $4), point($5, $6))", ">= $7", "COALESCE(a.image_url,'') <> ''", "a.description ILIKE $8 OR a.address ILIKE $8"} {
+		if !strings.Contains(where, want) {
+			t.Errorf("expected %q in %s", want, where)
+		}
+	}
+	if len(f.args) != 8 {
+		t.Fatalf("expected 8 args, got %d", len(f.args))
+	}
+	if got := f.args[7]; got != `%50\%\_lake%` {
+		t.Errorf("expected escaped wildcards, got %v", got)
+	}
+}
+
+func TestParseSightingFilter_Empty(t *testing.T) {
+	f, msg := parseSightingFilter(url.Values{})
+	if msg != "" || f.where() != "" || len(f.args) != 0 {
+		t.Errorf("expected no conditions, got %q %v %q", f.where(), f.args, msg)
+	}
+}
+
+func TestSightingFilter_CacheKey(t *testing.T) {
+	key := func(query string) string {
+		q, _ := url.ParseQuery(query)
+		f, msg := parseSightingFilter(q)
+		if msg != "" {
+			t.Fatalf("%q: unexpected error: %s", query, msg)
+		}
+		return f.cacheKey()
+	}
+	base := key("category=Bird&min_quantity=2")
+	if got := key("min_quantity=2&utm_source=x&category=Bird&_=1712345"); got != base {
+		t.Error("expected parameter order and unknown parameters not to change the key")
+	}
+	if key("category=Bird&min_quantity=3") == base || key("category=Reptile&min_quantity=2") == base {
+		t.Error("expected different filter values to change the key")
+	}
+	if key("min_quantity=2") == base || key("") == key("has_photo=false") {
+		t.Error("expected different filters to change the key")
+	}
+	if got := key("behavior=" + strings.Repeat("x", 100000)); len(got) != len(base) {
+		t.Errorf("expected a fixed-length key for a long value, got %d bytes", len(got))
+	}
+}
+
+func TestParseSightingFilter_AntimeridianBBox(t *testing.T) {
+	f, msg := parseSightingFilter(url.Values{"bbox": {"170,-10,-170,10"}})
+	if msg != "" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+	if !strings.Contains(f.where(), " OR ") || len(f.args) != 8 {
+		t.Fatalf("expected a box on each side of the antimeridian, got %s %v", f.where(), f.args)
+	}
+	want := []interface{}{170.0, -10.0, 180.0, 10.0, -180.0, -10.0, -170.0, 10.0}
+	for i, v := range want {
+		if f.args[i] != v {
+			t.Errorf("arg %d = %v, want %v", i, f.args[i], v)
+		}
+	}
+}
+
+func TestParseSightingFilter_Invalid(t *testing.T) {
+	cases := []string{
+		"taxon_id=0",
+		"taxon_id=crane",
+		"user_id=-1",
+		"bbox=1,2,3",
+		"bbox=a,b,c,d",
+		"bbox=-82,30,-81,29",
+		"bbox=-200,29,-81,30",
+		"bbox=-82,29,-81,95",
+		"min_quantity=0",
+		"min_quantity=many",
+		"has_photo=maybe",
+		"observed_from=2026-02-10&observed_to=2026-02-01",
+		"q=" + strings.Repeat("a", 101),
+		"species=" + strings.Repeat("a", 201),
+	}
+	for _, c := range cases {
+		q, _ := url.ParseQuery(c)
+		if _, msg := parseSightingFilter(q); msg == "" {
+			t.Errorf("%s: expected an error", c)
+		}
+	}
+}
+
+func TestHandleGetSightings_InvalidFilter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?bbox=1,2&page=1", nil)
+	w := httptest.NewRecorder()
+	handleGetSightings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "bbox") {
+		t.Errorf("expected the error to name bbox, got %s", w.Body.String())
+	}
+}
+
+// ---------- handleGetSightings pagination ----------
+
+func TestParsePaginationParams_Defaults(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=2&limit=15", nil)
 	pageStr := req.URL.Query().Get("page")
 	limitStr := req.URL.Query().Get("limit")
-	usePagination := pageStr != "" || limitStr != ""
-	if usePagination {
-		t.Error("expected no pagination when no params provided")
+
+	page := 1
+	limit := 20
+	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+		limit = l
+		if limit > 100 {
+			limit = 100
+		}
+	}
+
+	if page != 2 {
+		t.Errorf("expected page 2, got %d", page)
+	}
+	if limit != 15 {
+		t.Errorf("expected limit 15, got %d", limit)
+	}
+	offset := (page - 1) * limit
+	if offset != 15 {
+		t.Errorf("expected offset 15, got %d", offset)
+	}
+}
+
+func TestParsePaginationParams_LimitCap(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=1&limit=999", nil)
+	limitStr := req.URL.Query().Get("limit")
+
+	limit := 20
+	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+		limit = l
+		if limit > 100 {
+			limit = 100
+		}
+	}
+	if limit != 100 {
+		t.Errorf("expected limit capped at 100, got %d", limit)
+	}
+}
+
+func TestParsePaginationParams_NegativePageDefaults(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?page=-5&limit=10", nil)
+	pageStr := req.URL.Query().Get("page")
+
+	page := 1
+	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+		page = p
+	}
+	if page != 1 {
+		t.Errorf("expected negative page to default to 1, got %d", page)
+	}
+}
+
+func TestParsePaginationParams_NoPagination(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?limit=10", nil)
+	if req.URL.Query().Has("page") {
+		t.Error("expected cursor paging unless page is provided")
+	}
+}
+
+// ---------- Cursor pagination ----------
+
+func TestKeysetParse(t *testing.T) {
+	k := keyset{Name: "comments", Key: "created_at", ID: "id"}
+	if req, msg := k.parse(url.Values{"limit": {"5"}}); msg != "" || req.After != nil || req.Limit != 5 {
+		t.Errorf("expected a first page of 5 without a cursor parameter, got %+v %q", req, msg)
+	}
+
+	req, msg := k.parse(url.Values{"cursor": {""}})
+	if msg != "" || req == nil || req.After != nil || req.Limit != cfg.DefaultPageSize {
+		t.Errorf("expected a first page of the default size, got %+v %q", req, msg)
+	}
+	if req, _ := k.parse(url.Values{"cursor": {""}, "limit": {"100000"}}); req.Limit != cfg.MaxPageSize {
+		t.Errorf("expected limit capped at %d, got %d", cfg.MaxPageSize, req.Limit)
+	}
+
+	at := time.Date(2026, 3, 14, 9, 26, 53, 589793000, time.UTC)
+	c := pageCursor{Name: "comments", Key: at, ID: 42}.encode()
+	req, msg = k.parse(url.Values{"cursor": {c}})
+	if msg != "" || req.After == nil || req.After.ID != 42 || req.After.Key != at.Format(time.RFC3339Nano) {
+		t.Errorf("expected the cursor to round-trip, got %+v %q", req, msg)
+	}
+
+	other := pageCursor{Name: "reports", Key: at, ID: 42}.encode()
+	for _, q := range []url.Values{
+		{"cursor": {"not a cursor"}},
+		{"cursor": {other}},
+		{"cursor": {pageCursor{Name: "comments"}.encode()}},
+		{"cursor": {""}, "limit": {"0"}},
+		{"cursor": {""}, "limit": {"x"}},
+	} {
+		if _, msg := k.parse(q); msg == "" {
+			t.Errorf("%v: expected an error", q)
+		}
+	}
+}
+
+func TestKeysetParse_KeyTypes(t *testing.T) {
+	rawCursor := func(name, key string) url.Values {
+		js := `{"n":"` + name + `","i":7`
+		if key != "" {
+			js += `,"k":` + key
+		}
+		return url.Values{"cursor": {base64.RawURLEncoding.EncodeToString([]byte(js + "}"))}}
+	}
+	comments := keyset{Name: "comments", Key: "created_at", ID: "id"}
+	nearby := keyset{Name: "nearby", Key: "a.distance_meters", Numeric: true, ID: "a.id"}
+	friends := keyset{Name: "friends", ID: "f.id"}
+
+	valid := []struct {
+		k   keyset
+		key string
+	}{
+		{comments, `"2026-03-14T09:26:53.589793Z"`},
+		{comments, `"2026-03-14T09:26:53-05:00"`},
+		{nearby, `123.5`},
+		{nearby, `0`},
+		{friends, ``},
+	}
+	for _, c := range valid {
+		if _, msg := c.k.parse(rawCursor(c.k.Name, c.key)); msg != "" {
+			t.Errorf("%s key %s: unexpected error %q", c.k.Name, c.key, msg)
+		}
+	}
+
+	invalid := []struct {
+		k   keyset
+		key string
+	}{
+		{comments, ``},
+		{comments, `"yesterday"`},
+		{comments, `"2026-03-14"`},
+		{comments, `1710408413`},
+		{comments, `{"t":"2026-03-14T09:26:53Z"}`},
+		{comments, `["2026-03-14T09:26:53Z"]`},
+		{nearby, ``},
+		{nearby, `"123.5"`},
+		{nearby, `[1]`},
+		{nearby, `{}`},
+		{friends, `"2026-03-14T09:26:53Z"`},
+		{friends, `3`},
+	}
+	for _, c := range invalid {
+		if _, msg := c.k.parse(rawCursor(c.k.Name, c.key)); msg != "Invalid cursor" {
+			t.Errorf("%s key %s: expected Invalid cursor, got %q", c.k.Name, c.key, msg)
+		}
+	}
+}
+
+func TestKeysetPaginate(t *testing.T) {
+	k := keyset{Name: "reports", Key: "r.created_at", ID: "r.id", Desc: true}
+	at := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	query, args := k.paginate("SELECT * FROM reports r WHERE r.status = $1", []interface{}{"pending"}, &cursorRequest{Limit: 10})
+	if query != "SELECT * FROM reports r WHERE r.status = $1 ORDER BY r.created_at DESC, r.id DESC LIMIT $2" || len(args) != 2 || args[1] != 11 {
+		t.Errorf("unexpected first page query %q %v", query, args)
+	}
+
+	query, args = k.paginate("SELECT * FROM reports r WHERE r.status = $1", []interface{}{"pending"},
+		&cursorRequest{Limit: 10, After: &pageCursor{Name: "reports", Key: at, ID: 7}})
+	want := "SELECT * FROM reports r WHERE r.status = $1 AND (r.created_at, r.id) < ($2, $3) ORDER BY r.created_at DESC, r.id DESC LIMIT $4"
+	if query != want {
+		t.Errorf("got query %q, want %q", query, want)
+	}
+	if len(args) != 4 || args[2] != 7 || args[3] != 11 {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	ids := keyset{Name: "friends", ID: "f.id"}
+	if cond, args := ids.after(pageCursor{ID: 3}); cond != "f.id > $%d" || len(args) != 1 {
+		t.Errorf("unexpected id-only condition %q %v", cond, args)
+	}
+}
+
+func TestWriteCursorPage(t *testing.T) {
+	k := keyset{Name: "comments", Key: "created_at", ID: "id"}
+	at := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	rows := []models.Message{{ID: 1, CreateTime: at}, {ID: 2, CreateTime: at}, {ID: 3, CreateTime: at}}
+	pos := func(m models.Message) (any, int) { return m.CreateTime, m.ID }
+
+	w := httptest.NewRecorder()
+	writeCursorPage(w, k, &cursorRequest{Limit: 2}, rows, pos)
+	var resp struct {
+		Data       []models.Message `json:"data"`
+		NextCursor *string          `json:"next_cursor"`
+		Limit      int              `json:"limit"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Data) != 2 || resp.Limit != 2 || resp.NextCursor == nil {
+		t.Fatalf("expected two rows and a next cursor, got %+v", resp)
+	}
+	req, msg := k.parse(url.Values{"cursor": {*resp.NextCursor}})
+	if msg != "" || req.After.ID != 2 || req.After.Key != at.Format(time.RFC3339Nano) {
+		t.Errorf("expected the next cursor to point at row 2, got %+v %q", req.After, msg)
+	}
+
+	w = httptest.NewRecorder()
+	writeCursorPage(w, k, &cursorRequest{Limit: 3}, rows, pos)
+	resp.NextCursor = nil
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Data) != 3 || resp.NextCursor != nil {
+		t.Errorf("expected the last page to have no next cursor, got %+v", resp)
+	}
+}
+
+func TestCursorPagination_InvalidCursor(t *testing.T) {
+	stubStoredRole(t, models.RoleModerator)
+	handlers := map[string]struct {
+		h   http.HandlerFunc
+		req *http.Request
+	}{
+		"sightings": {handleGetSightings, httptest.NewRequest(http.MethodGet, "/api/sightings?cursor=bogus", nil)},
+		"comments":  {handleGetComments, httptest.NewRequest(http.MethodGet, "/api/sightings/1/messages?cursor=bogus", nil)},
+		"channel":   {handleGetChannelMessages, httptest.NewRequest(http.MethodGet, "/api/channels/1/messages?cursor=bogus", nil)},
+		"dm":        {handleDM, withAuthUser(httptest.NewRequest(http.MethodGet, "/api/dm?user1=1&user2=2&cursor=bogus", nil), 1)},
+		"friends":   {handleFriendList, withAuthUser(httptest.NewRequest(http.MethodGet, "/api/friends?cursor=bogus", nil), 1)},
+		"requests":  {handleFriendRequests, withAuthUser(httptest.NewRequest(http.MethodGet, "/api/friends/requests?cursor=bogus", nil), 1)},
+		"reports":   {handleGetReports, withAuthRole(httptest.NewRequest(http.MethodGet, "/api/reports?cursor=bogus", nil), 1, models.RoleModerator)},
+		// A well-formed cursor whose key is a timestamp rather than a distance.
+		"nearby": {handleGetNearbySightings, httptest.NewRequest(http.MethodGet, "/api/sightings/nearby?lat=29.6&lng=-82.3&cursor="+
+			pageCursor{Name: "nearby", Key: time.Now().UTC(), ID: 5}.encode(), nil)},
+	}
+	for name, c := range handlers {
+		w := httptest.NewRecorder()
+		c.h(w, c.req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestCursorPagination_InvalidLimitWithoutCursor(t *testing.T) {
+	stubStoredRole(t, models.RoleModerator)
+	// Lists page by default, so limit is checked even when no cursor is sent.
+	for _, c := range []struct {
+		h   http.HandlerFunc
+		req *http.Request
+	}{
+		{handleGetSightings, httptest.NewRequest(http.MethodGet, "/api/sightings?limit=0", nil)},
+		{handleGetComments, httptest.NewRequest(http.MethodGet, "/api/sightings/1/messages?limit=0", nil)},
+		{handleGetChannelMessages, httptest.NewRequest(http.MethodGet, "/api/channels/1/messages?limit=x", nil)},
+		{handleDM, withAuthUser(httptest.NewRequest(http.MethodGet, "/api/dm?user1=1&user2=2&limit=-1", nil), 1)},
+		{handleFriendList, withAuthUser(httptest.NewRequest(http.MethodGet, "/api/friends?limit=0", nil), 1)},
+		{handleGetReports, withAuthRole(httptest.NewRequest(http.MethodGet, "/api/reports?limit=0", nil), 1, models.RoleModerator)},
+	} {
+		w := httptest.NewRecorder()
+		c.h(w, c.req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", c.req.URL, w.Code)
+		}
+	}
+}
+
+func TestCursorPagination_SortMismatch(t *testing.T) {
+	c := pageCursor{Name: sightingKeysets["-created_at"].Name, Key: time.Now().UTC(), ID: 5}.encode()
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings?sort=observed_at&cursor="+c, nil)
+	w := httptest.NewRecorder()
+	handleGetSightings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a cursor from another sort order, got %d", w.Code)
 	}
 }
 
@@ -1116,6 +2535,196 @@ func TestHandleChangePassword_InvalidJSON(t *testing.T) {
 	}
 }
 
+// ---------- Password reset ----------
+
+func TestGenerateToken_Unique(t *testing.T) {
+	a, err := generateToken()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	b, _ := generateToken()
+	if a == b || len(a) != 64 {
+		t.Errorf("expected distinct 64-char tokens, got %q and %q", a, b)
+	}
+}
+
+func TestHashToken_Deterministic(t *testing.T) {
+	if hashToken("abc") != hashToken("abc") {
+		t.Error("expected same hash for same token")
+	}
+	if hashToken("abc") == "abc" {
+		t.Error("expected hash to differ from token")
+	}
+}
+
+func TestHandleForgotPassword_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/password/forgot", nil)
+	w := httptest.NewRecorder()
+	handleForgotPassword(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleForgotPassword_MissingEmail(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/password/forgot", strings.NewReader(`{"email":""}`))
+	w := httptest.NewRecorder()
+	handleForgotPassword(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleForgotPassword_InvalidEmail(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/password/forgot", strings.NewReader(`{"email":"gmail.com"}`))
+	w := httptest.NewRecorder()
+	handleForgotPassword(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed email, got %d", w.Code)
+	}
+}
+
+func TestHandleResetPassword_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/password/reset", nil)
+	w := httptest.NewRecorder()
+	handleResetPassword(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleResetPassword_MissingToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/password/reset", strings.NewReader(`{"new_password":"SecurePass1"}`))
+	w := httptest.NewRecorder()
+	handleResetPassword(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleResetPassword_WeakPassword(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/password/reset", strings.NewReader(`{"token":"abc","new_password":"weak"}`))
+	w := httptest.NewRecorder()
+	handleResetPassword(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for weak password, got %d", w.Code)
+	}
+}
+
+func TestHandleResetPassword_InvalidJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/password/reset", strings.NewReader("bad"))
+	w := httptest.NewRecorder()
+	handleResetPassword(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid JSON, got %d", w.Code)
+	}
+}
+
+// ---------- Email verification ----------
+
+func TestSendVerificationEmail_CapturesLink(t *testing.T) {
+	rec := &mailer.Recorder{}
+	orig := appMailer
+	appMailer = rec
+	defer func() { appMailer = orig }()
+
+	if err := sendVerificationEmail("gator@ufl.edu", "tok123"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	msg, ok := rec.Last()
+	if !ok {
+		t.Fatal("expected a message to be sent")
+	}
+	if msg.To != "gator@ufl.edu" {
+		t.Errorf("expected recipient gator@ufl.edu, got %s", msg.To)
+	}
+	if !strings.Contains(msg.Body, "token=tok123") {
+		t.Errorf("expected token link in body, got: %s", msg.Body)
+	}
+}
+
+func TestHandleVerifyEmail_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/email/verify", nil)
+	w := httptest.NewRecorder()
+	handleVerifyEmail(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleVerifyEmail_MissingToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/email/verify", strings.NewReader(`{"token":""}`))
+	w := httptest.NewRecorder()
+	handleVerifyEmail(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleResendVerification_MissingEmail(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/email/resend", strings.NewReader(`{"email":"  "}`))
+	w := httptest.NewRecorder()
+	handleResendVerification(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleResendVerification_RateLimited(t *testing.T) {
+	for i := 0; i < resendLimiter.Threshold; i++ {
+		resendLimiter.Fail("resend_email:often@ufl.edu")
+	}
+	defer resendLimiter.Reset("resend_email:often@ufl.edu")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/email/resend", strings.NewReader(`{"email":"Often@ufl.edu"}`))
+	w := httptest.NewRecorder()
+	handleResendVerification(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+}
+
+// ---------- Sessions ----------
+
+func TestHandleRefreshToken_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/token/refresh", nil)
+	w := httptest.NewRecorder()
+	handleRefreshToken(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleRefreshToken_MissingToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	handleRefreshToken(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleLogout_MissingToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", strings.NewReader(`{"refresh_token":""}`))
+	w := httptest.NewRecorder()
+	handleLogout(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleLogoutAll_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/logout/all", nil)
+	w := httptest.NewRecorder()
+	handleLogoutAll(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
 // ---------- writeJSON ----------
 
 func TestWriteJSON_SetsContentType(t *testing.T) {
@@ -1139,3 +2748,316 @@ func TestWriteJSON_EncodesBody(t *testing.T) {
 		t.Errorf("expected status 'ok', got %s", result["status"])
 	}
 }
+
+// ---------- Uploads ----------
+
+func multipartUpload(t *testing.T, field string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, "photo.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHandleUpload_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads", nil)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleUpload_MissingFile(t *testing.T) {
+	req := multipartUpload(t, "image", testPNG(t))
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a file field, got %d", w.Code)
+	}
+}
+
+func TestHandleUpload_UnsupportedType(t *testing.T) {
+	req := multipartUpload(t, "file", []byte("#!/bin/sh\necho not an image\n"))
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for a non-image, got %d", w.Code)
+	}
+}
+
+func TestHandleUpload_CorruptImage(t *testing.T) {
+	req := multipartUpload(t, "file", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...))
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a corrupt image, got %d", w.Code)
+	}
+}
+
+func TestHandleUpload_TooLarge(t *testing.T) {
+	cfg.MaxUploadBytes = 64
+	defer func() { cfg.MaxUploadBytes = config.Default().MaxUploadBytes }()
+	req := multipartUpload(t, "file", testPNG(t))
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized file, got %d", w.Code)
+	}
+}
+
+func TestHandleUpload_Unauthenticated(t *testing.T) {
+	req := multipartUpload(t, "file", testPNG(t))
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a user, got %d", w.Code)
+	}
+}
+
+func TestUploadsHandler_NoDirectoryListing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/uploads/sightings/", nil)
+	w := httptest.NewRecorder()
+	uploadsHandler(t.TempDir()).ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a directory, got %d", w.Code)
+	}
+}
+
+// testWAV returns a one-second 8 kHz mono PCM recording of silence.
+func testWAV() []byte {
+	const rate, n = 8000, 16000
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+n))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, struct {
+		Size                      uint32
+		Format, Channels          uint16
+		Rate, ByteRate            uint32
+		BlockAlign, BitsPerSample uint16
+	}{16, 1, 1, rate, rate * 2, 2, 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(n))
+	b.Write(make([]byte, n))
+	return b.Bytes()
+}
+
+func TestHandleUpload_AuthBeforeBody(t *testing.T) {
+	// An anonymous upload is turned away before the body is even parsed.
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader("not a form"))
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an anonymous upload, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleUpload_AudioTooLarge(t *testing.T) {
+	cfg.MaxMediaUploadBytes = 1024
+	defer func() { cfg.MaxMediaUploadBytes = config.Default().MaxMediaUploadBytes }()
+	req := multipartUpload(t, "file", testWAV())
+	req = withAuthUser(req, 1)
+	w := httptest.NewRecorder()
+	handleUpload(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized recording, got %d", w.Code)
+	}
+}
+
+// ---------- Sighting Media ----------
+
+func TestHandleSightings_MediaInvalidSightingID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/sightings/abc/media", nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid sighting id, got %d", w.Code)
+	}
+}
+
+func TestHandleSightings_MediaMethodNotAllowed(t *testing.T) {
+	cases := []struct{ method, path string }{
+		{http.MethodPut, "/api/sightings/1/media"},
+		{http.MethodPost, "/api/sightings/1/media/order"},
+		{http.MethodGet, "/api/sightings/1/media/3"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		handleSightings(w, req)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: expected 405, got %d", c.method, c.path, w.Code)
+		}
+	}
+}
+
+func TestHandleSightings_MediaInvalidMediaID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/sightings/1/media/abc", nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid media id, got %d", w.Code)
+	}
+}
+
+func TestHandleAddSightingMedia_MissingUploadID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings/1/media", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without upload_id, got %d", w.Code)
+	}
+}
+
+func TestHandleAddSightingMedia_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/sightings/1/media", strings.NewReader(`{"upload_id":5}`))
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
+
+func TestHandleReorderSightingMedia_Validation(t *testing.T) {
+	for _, body := range []string{`{}`, `{"media_ids":[]}`, `{"media_ids":[1,2,1]}`, `not json`} {
+		req := httptest.NewRequest(http.MethodPut, "/api/sightings/1/media/order", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleSightings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHandleRemoveSightingMedia_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/sightings/1/media/2", nil)
+	w := httptest.NewRecorder()
+	handleSightings(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an authenticated user, got %d", w.Code)
+	}
+}
+
+func TestLoadSightingMedia_Empty(t *testing.T) {
+	// Must not touch the database when there is nothing to load.
+	loadSightingMedia(nil)
+}
+
+// ---------- Image Variants ----------
+
+func TestVariantKey(t *testing.T) {
+	if got := variantKey("sightings/2026/01/ab12.png", "thumb", "jpeg"); got != "sightings/2026/01/ab12_thumb.jpg" {
+		t.Errorf("unexpected jpeg key: %s", got)
+	}
+	if got := variantKey("sightings/2026/01/ab12.jpg", "full", "webp"); got != "sightings/2026/01/ab12_full.webp" {
+		t.Errorf("unexpected webp key: %s", got)
+	}
+}
+
+func TestLoadImageVariants_Empty(t *testing.T) {
+	// Must not touch the database when there is nothing to load.
+	loadImageVariants(nil)
+}
+
+func TestEnqueueVariants_FullQueueDoesNotBlock(t *testing.T) {
+	for i := 0; i < cap(variantQueue); i++ {
+		variantQueue <- variantJob{}
+	}
+	defer func() {
+		for len(variantQueue) > 0 {
+			<-variantQueue
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		enqueueVariants(variantJob{uploadID: 1})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected enqueue to return when the queue is full")
+	}
+}
+
+// ---------- Photo Suggestions ----------
+
+func TestSuggestFromExif(t *testing.T) {
+	s := suggestFromExif(exif.Info{
+		DateTimeOriginal:   "2026:03:14 13:26:53",
+		OffsetTimeOriginal: "+00:00",
+		HasGPS:             true,
+		Latitude:           29.6436,
+		Longitude:          -82.3549,
+	})
+	if s.Latitude == nil || *s.Latitude != 29.6436 || *s.Longitude != -82.3549 {
+		t.Errorf("unexpected location suggestion: %+v", s)
+	}
+	// 13:26 UTC is 09:26 in Gainesville (EDT).
+	if s.Date != "2026-03-14" || s.Time != "09:26" {
+		t.Errorf("expected local date/time 2026-03-14 09:26, got %s %s", s.Date, s.Time)
+	}
+
+	if empty := suggestFromExif(exif.Info{}); empty.Latitude != nil || empty.Date != "" {
+		t.Errorf("expected no suggestions without EXIF, got %+v", empty)
+	}
+}
+
+func TestApplyPhotoSuggestion_FillsDefaults(t *testing.T) {
+	lat, lng := 29.6436, -82.3549
+	req := models.CreateSightingRequest{Species: "Deer"}
+	defaulted, distance := applyPhotoSuggestion(&req, photoSuggestion{Latitude: &lat, Longitude: &lng, Date: "2026-03-14", Time: "09:26"})
+	if req.Latitude != lat || req.Longitude != lng || req.Date != "2026-03-14" || req.Time != "09:26" {
+		t.Errorf("expected EXIF defaults to be applied, got %+v", req)
+	}
+	if len(defaulted) != 4 || distance != 0 {
+		t.Errorf("unexpected result: %v, %f", defaulted, distance)
+	}
+}
+
+func TestApplyPhotoSuggestion_KeepsClientValues(t *testing.T) {
+	lat, lng := 29.6436, -82.3549
+	req := models.CreateSightingRequest{Latitude: 29.6516, Longitude: -82.3248, Date: "2026-03-15"}
+	defaulted, distance := applyPhotoSuggestion(&req, photoSuggestion{Latitude: &lat, Longitude: &lng, Date: "2026-03-14", Time: "09:26"})
+	if req.Latitude != 29.6516 || req.Date != "2026-03-15" || req.Time != "09:26" {
+		t.Errorf("expected client values to win, got %+v", req)
+	}
+	if len(defaulted) != 1 || defaulted[0] != "time" {
+		t.Errorf("expected only time to be defaulted, got %v", defaulted)
+	}
+	if distance < 3000 || distance > 3300 {
+		t.Errorf("expected about 3.1 km between pin and photo, got %f", distance)
+	}
+}
+
+func TestHaversineMeters(t *testing.T) {
+	// One degree of latitude is about 111.2 km.
+	if d := haversineMeters(29, -82, 30, -82); math.Abs(d-111195) > 100 {
+		t.Errorf("unexpected distance: %f", d)
+	}
+	if d := haversineMeters(29.6, -82.3, 29.6, -82.3); d != 0 {
+		t.Errorf("expected 0 for identical points, got %f", d)
+	}
+}
@@ -0,0 +1,163 @@
+// Package mapcache keeps rendered map responses, such as vector tiles, in
+// memory. Entries are evicted least recently used first once the cache
+// holds more than its byte budget, and expire after a fixed age; expired
+// entries are swept out whenever another is added. Purge drops every entry
+// when the underlying sightings change, and PurgeFunc only the ones a
+// smaller change affects.
+package mapcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Entry is a cached response body with its ETag.
+type Entry struct {
+	Data []byte
+	ETag string
+}
+
+// entryOverhead approximates the memory an entry takes beyond its key and
+// body: the ETag, the bookkeeping structs and the map slot. Counting it
+// keeps a flood of empty tiles from filling memory for free.
+const entryOverhead = 256
+
+type item struct {
+	key     string
+	entry   Entry
+	expires time.Time
+	used    *list.Element // in Cache.order
+	added   *list.Element // in Cache.byAge
+}
+
+func (it *item) size() int {
+	return len(it.key) + len(it.entry.Data) + entryOverhead
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int
+	ttl      time.Duration
+	size     int
+	gen      uint64
+	order    *list.List // front is most recently used
+	byAge    *list.List // front was added first, so expires first
+	items    map[string]*item
+
+	// Now is overridable for tests.
+	Now func() time.Time
+}
+
+// New returns a cache holding up to maxBytes of response bodies, each for
+// at most ttl.
+func New(maxBytes int, ttl time.Duration) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		order:    list.New(),
+		byAge:    list.New(),
+		items:    map[string]*item{},
+		Now:      time.Now,
+	}
+}
+
+// NewEntry wraps data with a strong ETag derived from its contents.
+func NewEntry(data []byte) Entry {
+	sum := sha256.Sum256(data)
+	return Entry{Data: data, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// Get returns the entry for key if it is cached and fresh.
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	it, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	if !c.Now().Before(it.expires) {
+		c.remove(it)
+		return Entry{}, false
+	}
+	c.order.MoveToFront(it.used)
+	return it.entry, true
+}
+
+// Generation identifies the current contents. Read it before building a
+// response and pass it to Put, so a response built from data that changed
+// in the meantime is not cached.
+func (c *Cache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// Put caches e under key unless the cache was purged since gen was read.
+// Entries larger than the whole budget are not cached.
+func (c *Cache) Put(key string, gen uint64, e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.Now()
+	// Every entry lives for the same ttl, so the expired ones are all at
+	// the front of byAge.
+	for el := c.byAge.Front(); el != nil && !now.Before(el.Value.(*item).expires); el = c.byAge.Front() {
+		c.remove(el.Value.(*item))
+	}
+
+	it := &item{key: key, entry: e, expires: now.Add(c.ttl)}
+	if gen != c.gen || it.size() > c.maxBytes {
+		return
+	}
+	if old, ok := c.items[key]; ok {
+		c.remove(old)
+	}
+	it.used = c.order.PushFront(it)
+	it.added = c.byAge.PushBack(it)
+	c.items[key] = it
+	c.size += it.size()
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back().Value.(*item))
+	}
+}
+
+// Purge drops every entry and invalidates responses still being built.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.order.Init()
+	c.byAge.Init()
+	clear(c.items)
+	c.size = 0
+}
+
+// PurgeFunc drops the entries whose keys match. Like Purge it invalidates
+// every response still being built, since any of them may hold old data.
+func (c *Cache) PurgeFunc(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key, it := range c.items {
+		if match(key) {
+			c.remove(it)
+		}
+	}
+}
+
+// Len is the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache) remove(it *item) {
+	c.order.Remove(it.used)
+	c.byAge.Remove(it.added)
+	delete(c.items, it.key)
+	c.size -= it.size()
+}
@@ -0,0 +1,137 @@
+package mapcache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCache(maxBytes int) (*Cache, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New(maxBytes, time.Minute)
+	c.Now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c, _ := newTestCache(10 * entryOverhead)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	e := NewEntry([]byte("tile"))
+	c.Put("a", c.Generation(), e)
+	got, ok := c.Get("a")
+	if !ok || string(got.Data) != "tile" || got.ETag != e.ETag {
+		t.Errorf("expected the cached entry, got %+v %v", got, ok)
+	}
+}
+
+func TestNewEntry_ETag(t *testing.T) {
+	a, b := NewEntry([]byte("one")), NewEntry([]byte("two"))
+	if a.ETag == b.ETag || a.ETag != NewEntry([]byte("one")).ETag {
+		t.Errorf("expected ETags to follow the contents, got %s %s", a.ETag, b.ETag)
+	}
+	if a.ETag[0] != '"' || a.ETag[len(a.ETag)-1] != '"' {
+		t.Errorf("expected a quoted ETag, got %s", a.ETag)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Room for two of the 1-byte-key, 4-byte-body entries below.
+	c, _ := newTestCache(2*(1+4+entryOverhead) + 4)
+	gen := c.Generation()
+	c.Put("a", gen, NewEntry([]byte("aaaa")))
+	c.Put("b", gen, NewEntry([]byte("bbbb")))
+	c.Get("a")
+	c.Put("c", gen, NewEntry([]byte("cccc")))
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b, the least recently used, to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to stay cached")
+	}
+	c.Put("huge", gen, NewEntry(make([]byte, 2*(1+4+entryOverhead)+4)))
+	if _, ok := c.Get("huge"); ok || c.Len() != 2 {
+		t.Errorf("expected an entry over the budget not to be cached, have %d entries", c.Len())
+	}
+}
+
+func TestCache_CountsKeysAndOverhead(t *testing.T) {
+	c, _ := newTestCache(10 * entryOverhead)
+	gen := c.Generation()
+	for i := 0; i < 100; i++ {
+		c.Put(strconv.Itoa(i), gen, NewEntry(nil))
+	}
+	if n := c.Len(); n >= 10 {
+		t.Errorf("expected empty entries to use up the budget, have %d cached", n)
+	}
+	c.Put(strings.Repeat("k", 10*entryOverhead), gen, NewEntry(nil))
+	if _, ok := c.Get(strings.Repeat("k", 10*entryOverhead)); ok {
+		t.Error("expected an entry whose key alone is over the budget not to be cached")
+	}
+}
+
+func TestCache_Expires(t *testing.T) {
+	c, now := newTestCache(10 * entryOverhead)
+	c.Put("a", c.Generation(), NewEntry([]byte("tile")))
+	*now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("expected the entry to expire after the TTL")
+	}
+}
+
+func TestCache_PutSweepsExpired(t *testing.T) {
+	c, now := newTestCache(100 * entryOverhead)
+	gen := c.Generation()
+	c.Put("a", gen, NewEntry([]byte("old")))
+	c.Put("b", gen, NewEntry([]byte("old")))
+	*now = now.Add(30 * time.Second)
+	c.Put("c", gen, NewEntry([]byte("newer")))
+	c.Get("a") // recently used, but still as old as b
+	*now = now.Add(30 * time.Second)
+	c.Put("d", gen, NewEntry([]byte("newest")))
+	if n := c.Len(); n != 2 {
+		t.Errorf("expected the expired entries to be dropped without being read, have %d", n)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("expected c to stay cached until its own TTL")
+	}
+}
+
+func TestCache_PurgeDiscardsStalePuts(t *testing.T) {
+	c, _ := newTestCache(10 * entryOverhead)
+	gen := c.Generation()
+	c.Put("a", gen, NewEntry([]byte("old")))
+	c.Purge()
+	if c.Len() != 0 {
+		t.Fatal("expected purge to empty the cache")
+	}
+	// A response built before the purge must not be cached after it.
+	c.Put("b", gen, NewEntry([]byte("stale")))
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected a put from before the purge to be ignored")
+	}
+	c.Put("b", c.Generation(), NewEntry([]byte("fresh")))
+	if _, ok := c.Get("b"); !ok {
+		t.Error("expected a put after the purge to be cached")
+	}
+}
+
+func TestCache_PurgeFunc(t *testing.T) {
+	c, _ := newTestCache(10 * entryOverhead)
+	gen := c.Generation()
+	c.Put("tile:a", gen, NewEntry([]byte("a")))
+	c.Put("heatmap:b", gen, NewEntry([]byte("b")))
+	c.PurgeFunc(func(key string) bool { return strings.HasPrefix(key, "tile:") })
+	if _, ok := c.Get("tile:a"); ok {
+		t.Error("expected the matching entry to be dropped")
+	}
+	if _, ok := c.Get("heatmap:b"); !ok {
+		t.Error("expected the other entry to stay cached")
+	}
+	c.Put("tile:c", gen, NewEntry([]byte("stale")))
+	if _, ok := c.Get("tile:c"); ok {
+		t.Error("expected a put from before the purge to be ignored")
+	}
+}
@@ -6,6 +6,8 @@ import "time"
 type Animals struct {
 	ID             int       `json:"id"`
 	Species        string    `json:"species"`
+	TaxonID        *int      `json:"taxon_id"`
+	ScientificName string    `json:"scientific_name,omitempty"`
 	ImageURL       string    `json:"image_url"`
 	Latitude       float64   `json:"latitude"`
 	Longitude      float64   `json:"longitude"`
@@ -19,12 +21,75 @@ type Animals struct {
 	UserID         int       `json:"user_id"`
 	Username       string    `json:"username"`
 	CreateTime     time.Time `json:"created_at"`
+	ObservedAt     time.Time `json:"observed_at"`
 	LikeCount      int       `json:"like_count"`
 	DistanceMeters float64   `json:"distance_meters,omitempty"`
+	// ImageVariants holds resized copies of the photo keyed by variant name
+	// ("thumb", "medium", "full") once they have been generated.
+	ImageVariants map[string]ImageVariant `json:"image_variants,omitempty"`
+	// Media lists the sighting's photos, audio and video in display order.
+	Media []Media `json:"media"`
+}
+
+// Media is one photo, audio clip or video attached to a sighting.
+type Media struct {
+	ID         int    `json:"id"`
+	Kind       string `json:"kind"`
+	URL        string `json:"url"`
+	MimeType   string `json:"mime_type"`
+	DurationMs *int   `json:"duration_ms,omitempty"`
+	Position   int    `json:"position"`
+}
+
+// Category is an entry in the managed list of sighting categories. Name is
+// the value stored on sightings; DisplayName, Icon and Color are for clients.
+type Category struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Icon        string `json:"icon"`
+	Color       string `json:"color"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+// Cluster is a map marker standing for the sightings in one grid cell.
+// Bounds is the cell as [minLng, minLat, maxLng, maxLat]; SightingID is set
+// when the cell holds a single sighting.
+type Cluster struct {
+	Latitude   float64        `json:"latitude"`
+	Longitude  float64        `json:"longitude"`
+	Count      int            `json:"count"`
+	SightingID *int           `json:"sighting_id,omitempty"`
+	Bounds     [4]float64     `json:"bounds"`
+	TopSpecies []SpeciesCount `json:"top_species"`
+}
+
+// SpeciesCount is how many sightings of a species a cluster holds.
+type SpeciesCount struct {
+	Species string `json:"species"`
+	Count   int    `json:"count"`
+}
+
+// HeatCell is one grid cell of a heatmap, placed at the centroid of its
+// sightings. Weight is their count or their total quantity.
+type HeatCell struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Weight    int     `json:"weight"`
+	Count     int     `json:"count"`
+}
+
+// ImageVariant is one resized copy of a sighting photo in each available format.
+type ImageVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	JPEG   string `json:"jpeg,omitempty"`
+	WebP   string `json:"webp,omitempty"`
 }
 
 type CreateSightingRequest struct {
 	Species     string  `json:"species"`
+	TaxonID     *int    `json:"taxon_id,omitempty"`
 	ImageURL    string  `json:"image_url"`
 	Latitude    float64 `json:"latitude"`
 	Longitude   float64 `json:"longitude"`
@@ -35,6 +100,7 @@ type CreateSightingRequest struct {
 	Description string  `json:"description"`
 	Date        string  `json:"date"`
 	Time        string  `json:"time"`
+	ObservedAt  string  `json:"observed_at,omitempty"`
 	UserID      string  `json:"userId"`
 	Username    string  `json:"username"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SearchResult is one full-text search match. Type is "sighting", "comment"
+// or "channel_message"; SightingID or ChannelID says where the match lives.
+// Highlight is an HTML-escaped snippet with matched words in <mark> tags.
+type SearchResult struct {
+	Type       string    `json:"type"`
+	ID         int       `json:"id"`
+	SightingID *int      `json:"sighting_id,omitempty"`
+	ChannelID  *int      `json:"channel_id,omitempty"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	Highlight  string    `json:"highlight"`
+	Rank       float64   `json:"rank"`
+	CreatedAt  time.Time `json:"created_at"`
+}
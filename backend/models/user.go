@@ -1,10 +1,21 @@
 package models
 
+// Roles stored in users.role.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
-	ID       int
-	Username string
-	Password string
-	Email    string
+	ID            int
+	Username      string
+	Password      string
+	Email         string
+	Role          string
+	EmailVerified bool
+	InstitutionID *int
+	InviteID      *int
 }
 
 type RegisterRequest struct {
@@ -12,6 +23,13 @@ type RegisterRequest struct {
 	Email           string
 	Password        string
 	ConfirmPassword string
+	InviteCode      string `json:"invite_code"`
+}
+
+type Institution struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
 }
 
 type Login struct {
@@ -23,3 +41,8 @@ type Login struct {
 type ForgetPassword struct {
 	Email string
 }
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
@@ -0,0 +1,214 @@
+// Package mvt encodes point features as Mapbox Vector Tiles (version 2 of
+// the specification) and converts coordinates to tile space.
+package mvt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ContentType is the media type of an encoded tile.
+const ContentType = "application/vnd.mapbox-vector-tile"
+
+// DefaultExtent is the number of units across a tile.
+const DefaultExtent = 4096
+
+// MaxLatitude is the latitude where Web Mercator tiles end.
+const MaxLatitude = 85.05112878
+
+// Property is one attribute of a feature. Value must be a string, bool,
+// int, int64, uint64 or float64.
+type Property struct {
+	Key   string
+	Value any
+}
+
+type feature struct {
+	id   uint64
+	x, y int
+	tags []uint32
+}
+
+// Layer collects point features for one named layer of a tile. Keys and
+// values shared between features are stored once.
+type Layer struct {
+	Name   string
+	Extent uint32
+
+	keys     []string
+	keyIdx   map[string]uint32
+	values   []any
+	valueIdx map[any]uint32
+	features []feature
+}
+
+// NewLayer returns an empty layer.
+func NewLayer(name string, extent uint32) *Layer {
+	return &Layer{Name: name, Extent: extent, keyIdx: map[string]uint32{}, valueIdx: map[any]uint32{}}
+}
+
+// Len is the number of features in the layer.
+func (l *Layer) Len() int { return len(l.features) }
+
+// AddPoint adds a point at x, y in tile units, measured from the tile's
+// top-left corner. Points slightly outside 0..Extent are kept so symbols
+// near an edge render on both tiles.
+func (l *Layer) AddPoint(id uint64, x, y int, props ...Property) error {
+	f := feature{id: id, x: x, y: y, tags: make([]uint32, 0, 2*len(props))}
+	for _, p := range props {
+		v, err := normalize(p.Value)
+		if err != nil {
+			return fmt.Errorf("mvt: property %s: %w", p.Key, err)
+		}
+		k, ok := l.keyIdx[p.Key]
+		if !ok {
+			k = uint32(len(l.keys))
+			l.keys = append(l.keys, p.Key)
+			l.keyIdx[p.Key] = k
+		}
+		vi, ok := l.valueIdx[v]
+		if !ok {
+			vi = uint32(len(l.values))
+			l.values = append(l.values, v)
+			l.valueIdx[v] = vi
+		}
+		f.tags = append(f.tags, k, vi)
+	}
+	l.features = append(l.features, f)
+	return nil
+}
+
+// normalize maps values onto the types a tile can hold, so equal values
+// share an entry in the layer's value table.
+func normalize(v any) (any, error) {
+	switch v := v.(type) {
+	case string, bool, int64, uint64, float64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// Protobuf wire types.
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+)
+
+// Geometry commands and types from the specification.
+const (
+	cmdMoveTo = 1
+	geomPoint = 1
+)
+
+type buffer []byte
+
+func (b *buffer) key(field, wire int) { b.varint(uint64(field<<3 | wire)) }
+
+func (b *buffer) varint(v uint64) { *b = binary.AppendUvarint(*b, v) }
+
+func (b *buffer) bytes(field int, p []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(p)))
+	*b = append(*b, p...)
+}
+
+func (b *buffer) uint(field int, v uint64) {
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *buffer) packed(field int, vs []uint32) {
+	var p buffer
+	for _, v := range vs {
+		p.varint(uint64(v))
+	}
+	b.bytes(field, p)
+}
+
+func zigzag(n int) uint32 { return uint32((int32(n) << 1) ^ (int32(n) >> 31)) }
+
+func encodeValue(v any) []byte {
+	var b buffer
+	switch v := v.(type) {
+	case string:
+		b.bytes(1, []byte(v))
+	case float64:
+		b.key(3, wire64)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	case int64:
+		if v < 0 {
+			// sint64 keeps small negative numbers small.
+			b.uint(6, uint64(v<<1^v>>63))
+		} else {
+			b.uint(4, uint64(v))
+		}
+	case uint64:
+		b.uint(5, v)
+	case bool:
+		var n uint64
+		if v {
+			n = 1
+		}
+		b.uint(7, n)
+	}
+	return b
+}
+
+func (l *Layer) encode() []byte {
+	var b buffer
+	b.bytes(1, []byte(l.Name))
+	for _, f := range l.features {
+		var fb buffer
+		fb.uint(1, f.id)
+		if len(f.tags) > 0 {
+			fb.packed(2, f.tags)
+		}
+		fb.uint(3, geomPoint)
+		fb.packed(4, []uint32{cmdMoveTo | 1<<3, zigzag(f.x), zigzag(f.y)})
+		b.bytes(2, fb)
+	}
+	for _, k := range l.keys {
+		b.bytes(3, []byte(k))
+	}
+	for _, v := range l.values {
+		b.bytes(4, encodeValue(v))
+	}
+	b.uint(5, uint64(l.Extent))
+	b.uint(15, 2)
+	return b
+}
+
+// Encode returns the tile holding the given layers. Empty layers are left
+// out, so a tile with no features encodes to no bytes.
+func Encode(layers ...*Layer) []byte {
+	var b buffer
+	for _, l := range layers {
+		if l.Len() > 0 {
+			b.bytes(3, l.encode())
+		}
+	}
+	return b
+}
+
+// TileBounds returns the area covered by tile z/x/y as minLng, minLat,
+// maxLng, maxLat in degrees.
+func TileBounds(z, x, y int) (minLng, minLat, maxLng, maxLat float64) {
+	n := math.Exp2(float64(z))
+	lng := func(x float64) float64 { return x/n*360 - 180 }
+	lat := func(y float64) float64 { return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi }
+	return lng(float64(x)), lat(float64(y + 1)), lng(float64(x + 1)), lat(float64(y))
+}
+
+// Project returns the position of lat, lng within tile z/x/y in tile units.
+func Project(lat, lng float64, z, x, y int, extent uint32) (px, py int) {
+	n := math.Exp2(float64(z))
+	lat = max(-MaxLatitude, min(MaxLatitude, lat))
+	wx := (lng + 180) / 360 * n
+	wy := (1 - math.Asinh(math.Tan(lat*math.Pi/180))/math.Pi) / 2 * n
+	return int(math.Round((wx - float64(x)) * float64(extent))), int(math.Round((wy - float64(y)) * float64(extent)))
+}
@@ -0,0 +1,160 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// field is one decoded protobuf field: a varint, a fixed64 or bytes.
+type field struct {
+	num   int
+	value uint64
+	data  []byte
+}
+
+func decode(t *testing.T, b []byte) []field {
+	t.Helper()
+	var out []field
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad field key in %x", b)
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			b = b[n:]
+		case wire64:
+			f.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			b = b[n:]
+			f.data = b[:l]
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func unpack(t *testing.T, b []byte) []uint64 {
+	var out []uint64
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad packed varint in %x", b)
+		}
+		out = append(out, v)
+		b = b[n:]
+	}
+	return out
+}
+
+func TestEncode(t *testing.T) {
+	l := NewLayer("sightings", DefaultExtent)
+	if err := l.AddPoint(7, 100, -3, Property{"species", "Sandhill Crane"}, Property{"like_count", 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddPoint(8, 4100, 2048, Property{"species", "Sandhill Crane"}, Property{"like_count", -1}, Property{"ratio", 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddPoint(9, 0, 0, Property{"bad", struct{}{}}); err == nil {
+		t.Error("expected an error for an unsupported value type")
+	}
+
+	tile := decode(t, Encode(l, NewLayer("empty", DefaultExtent)))
+	if len(tile) != 1 || tile[0].num != 3 {
+		t.Fatalf("expected one layer, got %+v", tile)
+	}
+
+	var name string
+	var keys []string
+	var values []field
+	var features [][]field
+	var extent, version uint64
+	for _, f := range decode(t, tile[0].data) {
+		switch f.num {
+		case 1:
+			name = string(f.data)
+		case 2:
+			features = append(features, decode(t, f.data))
+		case 3:
+			keys = append(keys, string(f.data))
+		case 4:
+			values = append(values, decode(t, f.data)[0])
+		case 5:
+			extent = f.value
+		case 15:
+			version = f.value
+		}
+	}
+	if name != "sightings" || extent != DefaultExtent || version != 2 {
+		t.Errorf("unexpected layer header %q extent %d version %d", name, extent, version)
+	}
+	if len(keys) != 3 || keys[0] != "species" || keys[1] != "like_count" || keys[2] != "ratio" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+	// The shared species value is stored once.
+	if len(values) != 4 || string(values[0].data) != "Sandhill Crane" {
+		t.Fatalf("unexpected values %+v", values)
+	}
+	if values[1].num != 4 || values[1].value != 2 {
+		t.Errorf("expected int 2, got %+v", values[1])
+	}
+	if values[2].num != 6 || values[2].value != 1 {
+		t.Errorf("expected sint -1 zigzag-encoded as 1, got %+v", values[2])
+	}
+	if values[3].num != 3 || math.Float64frombits(values[3].value) != 0.5 {
+		t.Errorf("expected double 0.5, got %+v", values[3])
+	}
+
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+	f := features[0]
+	if f[0].num != 1 || f[0].value != 7 {
+		t.Errorf("expected id 7, got %+v", f[0])
+	}
+	if tags := unpack(t, f[1].data); len(tags) != 4 || tags[0] != 0 || tags[1] != 0 || tags[2] != 1 || tags[3] != 1 {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if f[2].num != 3 || f[2].value != geomPoint {
+		t.Errorf("expected a point, got %+v", f[2])
+	}
+	// MoveTo(1), x=100, y=-3 zigzag-encoded.
+	if geom := unpack(t, f[3].data); len(geom) != 3 || geom[0] != 9 || geom[1] != 200 || geom[2] != 5 {
+		t.Errorf("unexpected geometry %v", geom)
+	}
+}
+
+func TestEncode_Empty(t *testing.T) {
+	if b := Encode(NewLayer("sightings", DefaultExtent)); len(b) != 0 {
+		t.Errorf("expected an empty tile to encode to no bytes, got %x", b)
+	}
+}
+
+func TestTileBoundsAndProject(t *testing.T) {
+	minLng, minLat, maxLng, maxLat := TileBounds(0, 0, 0)
+	if minLng != -180 || maxLng != 180 || math.Abs(maxLat-MaxLatitude) > 1e-6 || math.Abs(minLat+MaxLatitude) > 1e-6 {
+		t.Errorf("unexpected world tile bounds %v %v %v %v", minLng, minLat, maxLng, maxLat)
+	}
+
+	// Gainesville, FL at zoom 12.
+	const z, x, y = 12, 1110, 1694
+	minLng, minLat, maxLng, maxLat = TileBounds(z, x, y)
+	if px, py := Project(maxLat, minLng, z, x, y, DefaultExtent); px != 0 || py != 0 {
+		t.Errorf("expected the top-left corner at 0,0, got %d,%d", px, py)
+	}
+	if px, py := Project(minLat, maxLng, z, x, y, DefaultExtent); px != DefaultExtent || py != DefaultExtent {
+		t.Errorf("expected the bottom-right corner at the extent, got %d,%d", px, py)
+	}
+	if px, py := Project(29.6436, -82.3549, z, x, y, DefaultExtent); px < 0 || px > DefaultExtent || py < 0 || py > DefaultExtent {
+		t.Errorf("expected the point inside its tile, got %d,%d", px, py)
+	}
+}
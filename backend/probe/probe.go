@@ -0,0 +1,163 @@
+// Package probe identifies uploaded audio and video files and reads their
+// duration where the container makes that cheap (WAV and MP4/M4A/MOV).
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	KindImage = "image"
+	KindAudio = "audio"
+	KindVideo = "video"
+)
+
+// mediaTypes maps accepted MIME types to their kind and file extension.
+var mediaTypes = map[string]struct{ kind, ext string }{
+	"image/jpeg": {KindImage, ".jpg"},
+	"image/png":  {KindImage, ".png"},
+	"image/webp": {KindImage, ".webp"},
+	"audio/mpeg": {KindAudio, ".mp3"},
+	"audio/wav":  {KindAudio, ".wav"},
+	"audio/ogg":  {KindAudio, ".ogg"},
+	"audio/mp4":  {KindAudio, ".m4a"},
+	"audio/webm": {KindAudio, ".weba"},
+	"video/mp4":  {KindVideo, ".mp4"},
+	"video/webm": {KindVideo, ".webm"},
+}
+
+// Sniff returns the MIME type, kind and file extension of data, judged from
+// its bytes. declared (the client's Content-Type) only decides between audio
+// and video for containers that hold either, such as MP4 and WebM.
+func Sniff(data []byte, declared string) (mime, kind, ext string, ok bool) {
+	mime = http.DetectContentType(data)
+	switch mime {
+	case "audio/wave":
+		mime = "audio/wav"
+	case "application/ogg":
+		mime = "audio/ogg"
+	case "application/octet-stream":
+		if isMP3Frame(data) {
+			mime = "audio/mpeg"
+		}
+	}
+
+	audioDeclared := strings.HasPrefix(strings.ToLower(declared), "audio/")
+	switch {
+	case mime == "video/mp4" && audioDeclared:
+		mime = "audio/mp4"
+	case mime == "video/webm" && audioDeclared:
+		mime = "audio/webm"
+	}
+
+	t, ok := mediaTypes[mime]
+	if !ok {
+		return mime, "", "", false
+	}
+	return mime, t.kind, t.ext, true
+}
+
+// isMP3Frame reports whether data starts with an MPEG audio frame header
+// (files without an ID3 tag are not recognised by http.DetectContentType).
+func isMP3Frame(b []byte) bool {
+	return len(b) >= 4 && b[0] == 0xFF && b[1]&0xE0 == 0xE0 &&
+		b[1]&0x18 != 0x08 && // version not reserved
+		b[1]&0x06 != 0 && // layer not reserved
+		b[2]&0xF0 != 0xF0 // bitrate index not invalid
+}
+
+// Duration returns the play time of WAV and MP4-family files.
+func Duration(data []byte, mime string) (time.Duration, bool) {
+	switch mime {
+	case "audio/wav":
+		return wavDuration(data)
+	case "audio/mp4", "video/mp4":
+		return mp4Duration(data)
+	}
+	return 0, false
+}
+
+func wavDuration(b []byte) (time.Duration, bool) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return 0, false
+	}
+	var byteRate uint32
+	for i := 12; i+8 <= len(b); {
+		id := string(b[i : i+4])
+		size := binary.LittleEndian.Uint32(b[i+4:])
+		switch id {
+		case "fmt ":
+			// The byte rate is bytes 8-11 of the chunk body.
+			if i+20 > len(b) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(b[i+16:])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), true
+		}
+		i += 8 + int(size) + int(size%2)
+	}
+	return 0, false
+}
+
+// mp4Duration reads the movie header (moov/mvhd) box.
+func mp4Duration(b []byte) (time.Duration, bool) {
+	moov, ok := findBox(b, "moov")
+	if !ok {
+		return 0, false
+	}
+	mvhd, ok := findBox(moov, "mvhd")
+	if !ok || len(mvhd) < 20 {
+		return 0, false
+	}
+	var timescale, duration uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return 0, false
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:]))
+		duration = binary.BigEndian.Uint64(mvhd[24:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:]))
+	}
+	if timescale == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), true
+}
+
+// findBox returns the payload of the first box of type typ at this level.
+func findBox(b []byte, typ string) ([]byte, bool) {
+	for i := 0; i+8 <= len(b); {
+		size := uint64(binary.BigEndian.Uint32(b[i:]))
+		header := uint64(8)
+		switch size {
+		case 0: // box runs to the end
+			size = uint64(len(b) - i)
+		case 1: // 64-bit size follows the type
+			if i+16 > len(b) {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(b[i+8:])
+			header = 16
+		}
+		// Comparing against the bytes left, rather than adding to i, keeps
+		// a huge 64-bit size from wrapping; what passes fits in an int.
+		if size < header || size > uint64(len(b)-i) {
+			return nil, false
+		}
+		if bytes.Equal(b[i+4:i+8], []byte(typ)) {
+			return b[i+int(header) : i+int(size)], true
+		}
+		i += int(size)
+	}
+	return nil, false
+}
@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// testWAV returns a 16-bit mono 8 kHz WAV file holding d of silence.
+func testWAV(d time.Duration) []byte {
+	const rate, bytesPerSample = 8000, 2
+	n := int(d.Seconds() * rate * bytesPerSample)
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+n))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&b, binary.LittleEndian, uint16(1)) // mono
+	binary.Write(&b, binary.LittleEndian, uint32(rate))
+	binary.Write(&b, binary.LittleEndian, uint32(rate*bytesPerSample))
+	binary.Write(&b, binary.LittleEndian, uint16(bytesPerSample))
+	binary.Write(&b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(n))
+	b.Write(make([]byte, n))
+	return b.Bytes()
+}
+
+func box(typ string, payload []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(8+len(payload)))
+	b.WriteString(typ)
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// testMP4 returns a skeleton MP4 whose movie header says 2.5 seconds.
+func testMP4() []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000) // timescale
+	binary.BigEndian.PutUint32(mvhd[16:], 2500) // duration
+	ftyp := box("ftyp", []byte("mp42\x00\x00\x00\x00mp42isom"))
+	return append(ftyp, box("moov", box("mvhd", mvhd))...)
+}
+
+func TestSniff(t *testing.T) {
+	cases := []struct {
+		name, declared string
+		data           []byte
+		mime, kind     string
+	}{
+		{"wav", "", testWAV(time.Second), "audio/wav", KindAudio},
+		{"mp3 id3", "", []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), "audio/mpeg", KindAudio},
+		{"mp3 frame", "", []byte{0xFF, 0xFB, 0x90, 0x64, 0, 0, 0, 0}, "audio/mpeg", KindAudio},
+		{"ogg", "", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00"), "audio/ogg", KindAudio},
+		{"mp4 video", "video/mp4", testMP4(), "video/mp4", KindVideo},
+		{"m4a", "audio/x-m4a", testMP4(), "audio/mp4", KindAudio},
+	}
+	for _, c := range cases {
+		mime, kind, _, ok := Sniff(c.data, c.declared)
+		if !ok || mime != c.mime || kind != c.kind {
+			t.Errorf("%s: got %s/%s/%v, want %s/%s", c.name, mime, kind, ok, c.mime, c.kind)
+		}
+	}
+
+	if _, _, _, ok := Sniff([]byte("<html><body>hi</body></html>"), "audio/mpeg"); ok {
+		t.Error("expected HTML to be rejected whatever the declared type")
+	}
+}
+
+func TestDuration_WAV(t *testing.T) {
+	d, ok := Duration(testWAV(1500*time.Millisecond), "audio/wav")
+	if !ok || d != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s, got %v (%v)", d, ok)
+	}
+}
+
+func TestDuration_MP4(t *testing.T) {
+	d, ok := Duration(testMP4(), "video/mp4")
+	if !ok || d != 2500*time.Millisecond {
+		t.Errorf("expected 2.5s, got %v (%v)", d, ok)
+	}
+}
+
+func TestDuration_Unknown(t *testing.T) {
+	if _, ok := Duration([]byte("OggS"), "audio/ogg"); ok {
+		t.Error("expected no duration for Ogg")
+	}
+	if _, ok := Duration([]byte("RIFF\x00\x00"), "audio/wav"); ok {
+		t.Error("expected truncated WAV to be rejected")
+	}
+}
+
+func TestDuration_TruncatedWAVFmt(t *testing.T) {
+	// The fmt chunk header is there but its byte rate is cut off.
+	b := testWAV(time.Second)[:28]
+	if _, ok := Duration(b, "audio/wav"); ok {
+		t.Error("expected a truncated fmt chunk to be rejected")
+	}
+}
+
+func TestDuration_MP4HugeLargeSize(t *testing.T) {
+	// A 64-bit box size near 2^64 must not wrap around and loop forever.
+	b := box("ftyp", []byte("mp42\x00\x00\x00\x00mp42isom"))
+	large := make([]byte, 16)
+	binary.BigEndian.PutUint32(large, 1)
+	copy(large[4:], "free")
+	binary.BigEndian.PutUint64(large[8:], 1<<64-16)
+	b = append(b, large...)
+	b = append(b, make([]byte, 64-len(b))...)
+	if _, ok := Duration(b, "video/mp4"); ok {
+		t.Error("expected an oversized box to be rejected")
+	}
+}
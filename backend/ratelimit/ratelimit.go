@@ -0,0 +1,112 @@
+// Package ratelimit tracks failed attempts per key (an account or an IP) and
+// locks the key out with exponential back-off once it fails too often.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// pruneThreshold is the number of tracked keys above which stale entries are
+// dropped, so a flood of distinct keys cannot grow the map without bound.
+const pruneThreshold = 10000
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Limiter is safe for concurrent use.
+type Limiter struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+
+	// Threshold is how many failures are allowed before the first lockout.
+	Threshold int
+	// BaseLockout is the first lockout; each further failure doubles it.
+	BaseLockout time.Duration
+	// MaxLockout caps the back-off.
+	MaxLockout time.Duration
+	// Window forgets failures once this long has passed since the last one.
+	Window time.Duration
+
+	// Now is replaceable in tests.
+	Now func() time.Time
+}
+
+// New returns a Limiter with the given policy.
+func New(threshold int, baseLockout, maxLockout, window time.Duration) *Limiter {
+	return &Limiter{
+		entries:     map[string]*entry{},
+		Threshold:   threshold,
+		BaseLockout: baseLockout,
+		MaxLockout:  maxLockout,
+		Window:      window,
+		Now:         time.Now,
+	}
+}
+
+// RetryAfter reports how long key remains locked out, or 0 if it is not.
+func (l *Limiter) RetryAfter(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	if d := e.lockedUntil.Sub(l.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Fail records a failed attempt for key. When the failure triggers a lockout
+// it returns the lockout duration and the failure count; otherwise 0.
+func (l *Limiter) Fail(key string) (time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	if len(l.entries) > pruneThreshold {
+		l.prune(now)
+	}
+
+	e, ok := l.entries[key]
+	if !ok || now.Sub(e.lastFailure) > l.Window {
+		e = &entry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	if e.failures < l.Threshold {
+		return 0, e.failures
+	}
+
+	lockout := l.BaseLockout
+	for i := l.Threshold; i < e.failures && lockout < l.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > l.MaxLockout {
+		lockout = l.MaxLockout
+	}
+	e.lockedUntil = now.Add(lockout)
+	return lockout, e.failures
+}
+
+// Reset forgets all failures for key, e.g. after a successful login.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+func (l *Limiter) prune(now time.Time) {
+	for k, e := range l.entries {
+		if now.Sub(e.lastFailure) > l.Window && now.After(e.lockedUntil) {
+			delete(l.entries, k)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(3, 30*time.Second, 5*time.Minute, 15*time.Minute)
+	l.Now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_LocksAfterThreshold(t *testing.T) {
+	l, _ := newTestLimiter()
+	for i := 0; i < 2; i++ {
+		if d, _ := l.Fail("k"); d != 0 {
+			t.Fatalf("expected no lockout on failure %d, got %v", i+1, d)
+		}
+	}
+	if l.RetryAfter("k") != 0 {
+		t.Error("expected key unlocked below threshold")
+	}
+	d, n := l.Fail("k")
+	if d != 30*time.Second || n != 3 {
+		t.Errorf("expected 30s lockout after 3 failures, got %v after %d", d, n)
+	}
+	if l.RetryAfter("k") != 30*time.Second {
+		t.Errorf("expected RetryAfter 30s, got %v", l.RetryAfter("k"))
+	}
+}
+
+func TestLimiter_ExponentialBackoffCapped(t *testing.T) {
+	l, _ := newTestLimiter()
+	var d time.Duration
+	for i := 0; i < 4; i++ {
+		d, _ = l.Fail("k")
+	}
+	if d != time.Minute {
+		t.Errorf("expected lockout to double to 1m, got %v", d)
+	}
+	for i := 0; i < 10; i++ {
+		d, _ = l.Fail("k")
+	}
+	if d != 5*time.Minute {
+		t.Errorf("expected lockout capped at 5m, got %v", d)
+	}
+}
+
+func TestLimiter_LockoutExpires(t *testing.T) {
+	l, now := newTestLimiter()
+	for i := 0; i < 3; i++ {
+		l.Fail("k")
+	}
+	*now = now.Add(31 * time.Second)
+	if l.RetryAfter("k") != 0 {
+		t.Error("expected lockout to expire")
+	}
+}
+
+func TestLimiter_WindowForgetsFailures(t *testing.T) {
+	l, now := newTestLimiter()
+	l.Fail("k")
+	l.Fail("k")
+	*now = now.Add(16 * time.Minute)
+	if d, n := l.Fail("k"); d != 0 || n != 1 {
+		t.Errorf("expected count to restart after window, got %v after %d", d, n)
+	}
+}
+
+func TestLimiter_ResetAndIsolation(t *testing.T) {
+	l, _ := newTestLimiter()
+	for i := 0; i < 3; i++ {
+		l.Fail("a")
+	}
+	if l.RetryAfter("b") != 0 {
+		t.Error("expected other keys to be unaffected")
+	}
+	l.Reset("a")
+	if l.RetryAfter("a") != 0 {
+		t.Error("expected Reset to clear the lockout")
+	}
+}
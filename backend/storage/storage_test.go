@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocal_PutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	l := &Local{Dir: dir, BaseURL: "http://localhost:8080/uploads/"}
+
+	url, err := l.Put(context.Background(), "sightings/2026/01/a.jpg", []byte("jpeg"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if url != "http://localhost:8080/uploads/sightings/2026/01/a.jpg" {
+		t.Errorf("unexpected url: %s", url)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sightings", "2026", "01", "a.jpg"))
+	if err != nil || string(data) != "jpeg" {
+		t.Fatalf("expected file to be written, got %q, %v", data, err)
+	}
+
+	if got, err := l.Get(context.Background(), "sightings/2026/01/a.jpg"); err != nil || string(got) != "jpeg" {
+		t.Errorf("expected to read the object back, got %q, %v", got, err)
+	}
+
+	if err := l.Delete(context.Background(), "sightings/2026/01/a.jpg"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := l.Delete(context.Background(), "sightings/2026/01/a.jpg"); err != nil {
+		t.Errorf("expected deleting a missing object to succeed, got %v", err)
+	}
+}
+
+func TestLocal_RejectsEscapingKeys(t *testing.T) {
+	l := &Local{Dir: t.TempDir()}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "a//b", `a\b`} {
+		if _, err := l.Put(context.Background(), key, nil, "image/png"); err != ErrInvalidKey {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+}
+
+// Example from the AWS Signature Version 4 documentation.
+func TestSigningKey(t *testing.T) {
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signing key = %s, want %s", got, want)
+	}
+}
+
+// fakeS3 is a minimal stand-in for an S3-compatible server.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3_PutAndDelete(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s := &S3{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		Bucket:    "wildlife",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		PublicURL: "https://cdn.example.com",
+		Now:       func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+
+	url, err := s.Put(context.Background(), "sightings/a.png", []byte("png"), "image/png")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if url != "https://cdn.example.com/sightings/a.png" {
+		t.Errorf("unexpected url: %s", url)
+	}
+	if string(fake.objects["/wildlife/sightings/a.png"]) != "png" {
+		t.Errorf("expected object to be stored, got %v", fake.objects)
+	}
+	auth := fake.auth[0]
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20260102/us-east-1/s3/aws4_request") ||
+		!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("unexpected Authorization header: %s", auth)
+	}
+
+	if got, err := s.Get(context.Background(), "sightings/a.png"); err != nil || string(got) != "png" {
+		t.Errorf("expected to read the object back, got %q, %v", got, err)
+	}
+
+	if err := s.Delete(context.Background(), "sightings/a.png"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := fake.objects["/wildlife/sightings/a.png"]; ok {
+		t.Error("expected object to be deleted")
+	}
+}
+
+func TestS3_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	s := &S3{Endpoint: srv.URL, Region: "us-east-1", Bucket: "b"}
+	if _, err := s.Put(context.Background(), "k.jpg", []byte("x"), "image/jpeg"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected AccessDenied error, got %v", err)
+	}
+	if got := s.URL("k.jpg"); got != srv.URL+"/b/k.jpg" {
+		t.Errorf("expected default public URL, got %s", got)
+	}
+}
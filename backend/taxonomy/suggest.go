@@ -0,0 +1,183 @@
+package taxonomy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Candidate is one name a suggestion can match. Names of the same species,
+// whether a taxon's scientific and common names or spelling variants of a
+// free-text species, share a Key so the species is suggested once.
+type Candidate struct {
+	Key       string
+	Name      string
+	Canonical string
+	Sightings int
+}
+
+// Suggestion is a matched species with the name that matched the query.
+type Suggestion struct {
+	Key       string
+	Canonical string
+	Matched   string
+	Sightings int
+	// Distance is 0 for exact and prefix matches and otherwise the number
+	// of typos forgiven.
+	Distance int
+	tier     int
+}
+
+// Match tiers, best first.
+const (
+	tierExact = iota
+	tierPrefix
+	tierWordPrefix
+	tierFuzzy
+)
+
+// Suggest ranks the candidates matching query: exact names first, then
+// names or words starting with the query, then names within a few typos.
+// Within a tier more frequently sighted species come first. At most limit
+// suggestions are returned, one per Key.
+func Suggest(query string, candidates []Candidate, limit int) []Suggestion {
+	q := Normalize(query)
+	if q == "" || limit <= 0 {
+		return nil
+	}
+
+	best := map[string]Suggestion{}
+	for _, c := range candidates {
+		tier, dist, ok := match(q, Normalize(c.Name))
+		if !ok {
+			continue
+		}
+		s := Suggestion{Key: c.Key, Canonical: c.Canonical, Matched: c.Name, Sightings: c.Sightings, Distance: dist, tier: tier}
+		if prev, seen := best[c.Key]; !seen || better(s, prev) {
+			best[c.Key] = s
+		}
+	}
+
+	out := make([]Suggestion, 0, len(best))
+	for _, s := range best {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.tier != b.tier {
+			return a.tier < b.tier
+		}
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Sightings != b.Sightings {
+			return a.Sightings > b.Sightings
+		}
+		return a.Canonical < b.Canonical
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func better(a, b Suggestion) bool {
+	if a.tier != b.tier {
+		return a.tier < b.tier
+	}
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	// Prefer showing the canonical name when it matched as well as a variant.
+	return a.Matched == a.Canonical && b.Matched != b.Canonical
+}
+
+// maxTypos is how many edits a query of n letters may be from a name.
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// match compares a normalized query with a normalized name.
+func match(q, name string) (tier, dist int, ok bool) {
+	if name == "" {
+		return 0, 0, false
+	}
+	if q == name {
+		return tierExact, 0, true
+	}
+	if strings.HasPrefix(name, q) {
+		return tierPrefix, 0, true
+	}
+	// "crane" and "blue heron" both match "great blue heron".
+	for i := 0; i < len(name); i++ {
+		if name[i] == ' ' && strings.HasPrefix(name[i+1:], q) {
+			return tierWordPrefix, 0, true
+		}
+	}
+	words := strings.Fields(name)
+
+	limit := maxTypos(len([]rune(q)))
+	if limit == 0 {
+		return 0, 0, false
+	}
+	// The query may be a partial name, so compare it with prefixes of
+	// about its own length as well as the whole name and each word.
+	best := limit + 1
+	for _, target := range append([]string{name}, words...) {
+		if d := prefixDistance(q, target, limit); d < best {
+			best = d
+		}
+	}
+	if best > limit {
+		return 0, 0, false
+	}
+	return tierFuzzy, best, true
+}
+
+// prefixDistance is the smallest edit distance between q and a prefix of
+// target within limit characters of q's length.
+func prefixDistance(q, target string, limit int) int {
+	qr, tr := []rune(q), []rune(target)
+	best := limit + 1
+	for n := len(qr) - limit; n <= len(qr)+limit; n++ {
+		if n <= 0 || n > len(tr) {
+			continue
+		}
+		if d := editDistance(qr, tr[:n]); d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and swaps of adjacent letters each cost one.
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,146 @@
+// Package taxonomy holds the bundled checklist of Florida fauna and the name
+// normalization used to match free-text species names against it.
+package taxonomy
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Ranks used in the checklist, from broadest to narrowest.
+const (
+	RankClass      = "class"
+	RankOrder      = "order"
+	RankFamily     = "family"
+	RankGenus      = "genus"
+	RankSpecies    = "species"
+	RankSubspecies = "subspecies"
+)
+
+var validRanks = map[string]bool{
+	RankClass: true, RankOrder: true, RankFamily: true,
+	RankGenus: true, RankSpecies: true, RankSubspecies: true,
+}
+
+//go:embed florida_fauna.csv
+var floridaFauna []byte
+
+// Taxon is one checklist entry. Parent is the scientific name of the nearest
+// listed higher taxon, and CommonNames[0] is the preferred common name.
+type Taxon struct {
+	ScientificName string
+	Rank           string
+	Parent         string
+	Category       string
+	CommonNames    []string
+}
+
+// CommonName returns the preferred common name, or the scientific name when
+// the taxon has none.
+func (t Taxon) CommonName() string {
+	if len(t.CommonNames) > 0 {
+		return t.CommonNames[0]
+	}
+	return t.ScientificName
+}
+
+// Checklist returns the bundled Florida fauna checklist.
+func Checklist() ([]Taxon, error) {
+	return Parse(bytes.NewReader(floridaFauna))
+}
+
+// Parse reads a checklist CSV with the columns scientific_name, rank, parent,
+// category and common_names (";"-separated). Lines starting with # are
+// comments. Parents must appear before their children; an empty category is
+// inherited from the parent.
+func Parse(r io.Reader) ([]Taxon, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 5
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("taxonomy: reading header: %w", err)
+	}
+	if header[0] != "scientific_name" {
+		return nil, fmt.Errorf("taxonomy: unexpected header %q", strings.Join(header, ","))
+	}
+
+	var taxa []Taxon
+	seen := map[string]int{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("taxonomy: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		t := Taxon{
+			ScientificName: strings.TrimSpace(rec[0]),
+			Rank:           strings.TrimSpace(rec[1]),
+			Parent:         strings.TrimSpace(rec[2]),
+			Category:       strings.TrimSpace(rec[3]),
+		}
+		for _, name := range strings.Split(rec[4], ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				t.CommonNames = append(t.CommonNames, name)
+			}
+		}
+
+		if t.ScientificName == "" {
+			return nil, fmt.Errorf("taxonomy: line %d: scientific name is required", line)
+		}
+		if _, dup := seen[t.ScientificName]; dup {
+			return nil, fmt.Errorf("taxonomy: line %d: %s is listed twice", line, t.ScientificName)
+		}
+		if !validRanks[t.Rank] {
+			return nil, fmt.Errorf("taxonomy: line %d: unknown rank %q", line, t.Rank)
+		}
+		if t.Parent != "" {
+			i, ok := seen[t.Parent]
+			if !ok {
+				return nil, fmt.Errorf("taxonomy: line %d: parent %s must be listed first", line, t.Parent)
+			}
+			if t.Category == "" {
+				t.Category = taxa[i].Category
+			}
+		}
+		if t.Category == "" {
+			return nil, fmt.Errorf("taxonomy: line %d: %s has no category", line, t.ScientificName)
+		}
+
+		seen[t.ScientificName] = len(taxa)
+		taxa = append(taxa, t)
+	}
+	return taxa, nil
+}
+
+// Normalize folds a species name for matching: lower case, hyphens and
+// underscores read as spaces, other punctuation dropped and runs of
+// whitespace collapsed. "Red-shouldered  Hawk" and "red shouldered hawk"
+// normalize to the same string.
+func Normalize(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			space = true
+		}
+	}
+	return b.String()
+}
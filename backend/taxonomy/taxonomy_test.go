@@ -0,0 +1,163 @@
+package taxonomy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChecklist_Valid(t *testing.T) {
+	taxa, err := Checklist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taxa) < 100 {
+		t.Fatalf("expected a sizeable checklist, got %d entries", len(taxa))
+	}
+
+	byName := map[string]Taxon{}
+	names := map[string]string{}
+	for _, tx := range taxa {
+		byName[tx.ScientificName] = tx
+		if tx.Rank != RankSpecies && tx.Rank != RankSubspecies {
+			// Orders and families may share a group name such as "Storks".
+			continue
+		}
+		for _, n := range append([]string{tx.ScientificName}, tx.CommonNames...) {
+			key := Normalize(n)
+			if other, ok := names[key]; ok && other != tx.ScientificName {
+				t.Errorf("%q names both %s and %s", n, other, tx.ScientificName)
+			}
+			names[key] = tx.ScientificName
+		}
+	}
+
+	crane := byName["Antigone canadensis"]
+	if crane.CommonName() != "Sandhill Crane" || crane.Category != "Bird" || crane.Parent != "Gruidae" {
+		t.Errorf("unexpected crane entry: %+v", crane)
+	}
+	if names[Normalize("Grus canadensis")] != "Antigone canadensis" {
+		t.Error("expected the older scientific name to map to the crane")
+	}
+	if bear := byName["Ursus americanus floridanus"]; bear.Parent != "Ursus americanus" || bear.Category != "Mammal" {
+		t.Errorf("unexpected subspecies entry: %+v", bear)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	const header = "scientific_name,rank,parent,category,common_names\n"
+	cases := map[string]string{
+		"unknown rank":   "Aves,kingdom,,Bird,Birds\n",
+		"missing parent": "Gruidae,family,Gruiformes,,Cranes\n",
+		"no category":    "Aves,class,,,Birds\n",
+		"duplicate":      "Aves,class,,Bird,Birds\nAves,class,,Bird,Birds\n",
+		"missing name":   ",class,,Bird,Birds\n",
+	}
+	for name, body := range cases {
+		if _, err := Parse(strings.NewReader(header + body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParse_InheritsCategory(t *testing.T) {
+	in := "# comment\nscientific_name,rank,parent,category,common_names\n" +
+		"Aves,class,,Bird,Birds\nGruidae,family,Aves,,Cranes\n" +
+		"Antigone canadensis,species,Gruidae,, Sandhill Crane ; Grus canadensis \n"
+	taxa, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crane := taxa[2]
+	if crane.Category != "Bird" {
+		t.Errorf("expected inherited category Bird, got %q", crane.Category)
+	}
+	if len(crane.CommonNames) != 2 || crane.CommonNames[1] != "Grus canadensis" {
+		t.Errorf("unexpected common names: %q", crane.CommonNames)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"Sandhill Crane":      "sandhill crane",
+		"  sandhill   CRANE ": "sandhill crane",
+		"Red-shouldered Hawk": "red shouldered hawk",
+		"Eastern Screech-Owl": "eastern screech owl",
+		"Cooper's Hawk":       "coopers hawk",
+		"Grus canadensis":     "grus canadensis",
+		"":                    "",
+		"--":                  "",
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []Candidate{
+		{Key: "t1", Name: "Sandhill Crane", Canonical: "Sandhill Crane", Sightings: 40},
+		{Key: "t1", Name: "Grus canadensis", Canonical: "Sandhill Crane", Sightings: 40},
+		{Key: "t2", Name: "Great Blue Heron", Canonical: "Great Blue Heron", Sightings: 12},
+		{Key: "t3", Name: "Great Egret", Canonical: "Great Egret", Sightings: 30},
+		{Key: "t4", Name: "Whooping Crane", Canonical: "Whooping Crane", Sightings: 0},
+		{Key: "n:gator", Name: "gator", Canonical: "Gator", Sightings: 2},
+	}
+	names := func(ss []Suggestion) []string {
+		var out []string
+		for _, s := range ss {
+			out = append(out, s.Canonical)
+		}
+		return out
+	}
+
+	cases := []struct {
+		q    string
+		want []string
+	}{
+		// Prefix matches rank by sightings.
+		{"great", []string{"Great Egret", "Great Blue Heron"}},
+		// Word prefixes come after name prefixes.
+		{"crane", []string{"Sandhill Crane", "Whooping Crane"}},
+		// A typo in a partial name.
+		{"sandhil cr", []string{"Sandhill Crane"}},
+		// A swapped pair of letters.
+		{"gerat egret", []string{"Great Egret"}},
+		// Scientific names resolve to the common name, once.
+		{"grus", []string{"Sandhill Crane"}},
+		// Short queries are not fuzzy-matched.
+		{"gta", nil},
+		{"", nil},
+	}
+	for _, c := range cases {
+		got := names(Suggest(c.q, candidates, 10))
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("Suggest(%q) = %q, want %q", c.q, got, c.want)
+		}
+	}
+
+	if got := Suggest("great", candidates, 1); len(got) != 1 {
+		t.Errorf("expected limit to cap results, got %d", len(got))
+	}
+	if s := Suggest("grus canadensis", candidates, 10); len(s) != 1 || s[0].Matched != "Grus canadensis" {
+		t.Errorf("expected the scientific name as the matched name, got %+v", s)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"crane", "crane", 0},
+		{"crane", "crone", 1},
+		{"crane", "carne", 1},
+		{"crane", "cran", 1},
+		{"", "abc", 3},
+	}
+	for _, c := range cases {
+		if got := editDistance([]rune(c.a), []rune(c.b)); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+import { vi, describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { SESSION_EXPIRED_EVENT, apiFetch, storeTokens } from './api';
+
+function response(status: number, body: unknown = {}) {
+  return {
+    ok: status >= 200 && status < 300,
+    status,
+    json: () => Promise.resolve(body),
+  } as unknown as Response;
+}
+
+function authHeader(call: unknown[]): string | null {
+  const init = call[1] as RequestInit | undefined;
+  return new Headers(init?.headers).get('Authorization');
+}
+
+describe('apiFetch', () => {
+  beforeEach(() => localStorage.clear());
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+    localStorage.clear();
+  });
+
+  it('sends the stored access token as a Bearer header', async () => {
+    storeTokens('access-1', 'refresh-1');
+    const fetchSpy = vi.fn().mockResolvedValue(response(200));
+    globalThis.fetch = fetchSpy;
+
+    await apiFetch('http://localhost:8080/api/dm', { method: 'POST', headers: { 'Content-Type': 'application/json' } });
+
+    expect(authHeader(fetchSpy.mock.calls[0])).toBe('Bearer access-1');
+    const headers = new Headers((fetchSpy.mock.calls[0][1] as RequestInit).headers);
+    expect(headers.get('Content-Type')).toBe('application/json');
+  });
+
+  it('sends no Authorization header when logged out', async () => {
+    const fetchSpy = vi.fn().mockResolvedValue(response(200));
+    globalThis.fetch = fetchSpy;
+
+    await apiFetch('http://localhost:8080/api/sightings');
+
+    expect(authHeader(fetchSpy.mock.calls[0])).toBeNull();
+  });
+
+  it('refreshes an expired access token and retries once', async () => {
+    storeTokens('expired', 'refresh-1');
+    const fetchSpy = vi.fn()
+      .mockResolvedValueOnce(response(401))
+      .mockResolvedValueOnce(response(200, { token: 'access-2', refresh_token: 'refresh-2' }))
+      .mockResolvedValueOnce(response(201));
+    globalThis.fetch = fetchSpy;
+
+    const res = await apiFetch('http://localhost:8080/api/sightings', { method: 'POST' });
+
+    expect(res.status).toBe(201);
+    expect(fetchSpy.mock.calls[1][0]).toContain('/token/refresh');
+    expect(JSON.parse((fetchSpy.mock.calls[1][1] as RequestInit).body as string)).toEqual({ refresh_token: 'refresh-1' });
+    expect(authHeader(fetchSpy.mock.calls[2])).toBe('Bearer access-2');
+    expect(localStorage.getItem('refresh_token')).toBe('refresh-2');
+  });
+
+  it('shares one refresh between concurrent requests', async () => {
+    storeTokens('expired', 'refresh-1');
+    const fetchSpy = vi.fn((url: string) =>
+      Promise.resolve(url.endsWith('/token/refresh')
+        ? response(200, { token: 'access-2', refresh_token: 'refresh-2' })
+        : response(localStorage.getItem('token') === 'expired' ? 401 : 200)));
+    globalThis.fetch = fetchSpy as unknown as typeof fetch;
+
+    await Promise.all([apiFetch('http://localhost:8080/api/a'), apiFetch('http://localhost:8080/api/b')]);
+
+    expect(fetchSpy.mock.calls.filter(([url]) => url.endsWith('/token/refresh'))).toHaveLength(1);
+  });
+
+  it('ends the session when the refresh token is rejected', async () => {
+    storeTokens('expired', 'revoked');
+    globalThis.fetch = vi.fn()
+      .mockResolvedValueOnce(response(401))
+      .mockResolvedValueOnce(response(401, { error: 'Invalid or expired refresh token' }));
+    const expired = vi.fn();
+    window.addEventListener(SESSION_EXPIRED_EVENT, expired);
+
+    const res = await apiFetch('http://localhost:8080/api/dm');
+
+    window.removeEventListener(SESSION_EXPIRED_EVENT, expired);
+    expect(res.status).toBe(401);
+    expect(expired).toHaveBeenCalledTimes(1);
+    expect(localStorage.getItem('token')).toBeNull();
+    expect(localStorage.getItem('refresh_token')).toBeNull();
+  });
+});
@@ -0,0 +1,71 @@
+const API_ROOT = 'http://localhost:8080/api';
+
+/** Fired on window when the session can no longer be refreshed. */
+export const SESSION_EXPIRED_EVENT = 'session-expired';
+
+function storage(): Storage | null {
+  return typeof localStorage === 'undefined' ? null : localStorage;
+}
+
+/** Saves the tokens returned by login, signup and token refresh. */
+export function storeTokens(token: string, refreshToken?: string) {
+  storage()?.setItem('token', token);
+  if (refreshToken) storage()?.setItem('refresh_token', refreshToken);
+}
+
+export function clearTokens() {
+  storage()?.removeItem('token');
+  storage()?.removeItem('refresh_token');
+}
+
+// Refresh tokens are single-use, so concurrent 401s share one refresh
+// instead of each replaying the same token (which ends every session).
+let refreshing: Promise<boolean> | null = null;
+
+async function refreshTokens(): Promise<boolean> {
+  const refreshToken = storage()?.getItem('refresh_token');
+  if (!refreshToken) return false;
+  try {
+    const res = await fetch(`${API_ROOT}/token/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+    if (!res.ok) return false;
+    const data = await res.json();
+    if (!data?.token) return false;
+    storeTokens(data.token, data.refresh_token);
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+function withAuth(init: RequestInit): RequestInit {
+  const token = storage()?.getItem('token');
+  if (!token) return init;
+  const headers = new Headers(init.headers);
+  headers.set('Authorization', `Bearer ${token}`);
+  return { ...init, headers };
+}
+
+/**
+ * fetch for backend calls. It sends the stored access token as a Bearer
+ * header and, when the backend answers 401, trades the refresh token for a
+ * new pair and retries once. If that fails the session is over:
+ * the tokens are cleared and SESSION_EXPIRED_EVENT is fired.
+ */
+export async function apiFetch(url: string, init: RequestInit = {}): Promise<Response> {
+  const sent = storage()?.getItem('token');
+  const res = await fetch(url, withAuth(init));
+  if (res.status !== 401 || !storage()?.getItem('refresh_token')) return res;
+
+  // Another request may have refreshed the tokens while this one was out.
+  if (storage()?.getItem('token') !== sent) return fetch(url, withAuth(init));
+  refreshing ??= refreshTokens().finally(() => (refreshing = null));
+  if (await refreshing) return fetch(url, withAuth(init));
+
+  clearTokens();
+  if (typeof window !== 'undefined') window.dispatchEvent(new Event(SESSION_EXPIRED_EVENT));
+  return res;
+}
@@ -5,7 +5,8 @@ import { AuthService } from './auth.service';
 
 // ── Backend response shapes ───────────────────────────────────────────────────
 // POST /api/login and POST /api/signup both return:
-//   { "token": "...", "user": { "id": 1, "username": "...", "email": "..." } }
+//   { "token": "...", "refresh_token": "...", "user": { "id": 1, "username": "...", "email": "..." } }
+// except that signup returns no tokens while email verification is required.
 // Error responses return:
 //   { "error": "..." }
 
@@ -47,6 +48,7 @@ describe('AuthService', () => {
     // Matches exact backend response: handleLogin → writeJSON(200, { token, user })
     globalThis.fetch = mockFetch({
       token: 'eyJhbGciOiJIUzI1NiJ9.test',
+      refresh_token: 'refresh-1',
       user: { id: 1, username: 'GatorFan', email: 'fan@ufl.edu' },
     });
 
@@ -58,6 +60,7 @@ describe('AuthService', () => {
     expect(service.currentUser()?.id).toBe('1'); // String(data.user.id)
     expect(service.isLoggedIn()).toBe(true);
     expect(localStorage.getItem('token')).toBe('eyJhbGciOiJIUzI1NiJ9.test');
+    expect(localStorage.getItem('refresh_token')).toBe('refresh-1');
   });
 
   it('login() throws when backend returns 401 Unauthorized', async () => {
@@ -80,7 +83,7 @@ describe('AuthService', () => {
       user: { id: 5, username: 'NewGator', email: 'new@ufl.edu' },
     });
 
-    await service.signup('NewGator', 'new@ufl.edu', 'Password1', 'Password1');
+    await expect(service.signup('NewGator', 'new@ufl.edu', 'Password1', 'Password1')).resolves.toBe(true);
 
     expect(service.currentUser()?.username).toBe('NewGator');
     expect(service.currentUser()?.id).toBe('5');
@@ -88,6 +91,18 @@ describe('AuthService', () => {
     expect(localStorage.getItem('token')).toBe('newuser-jwt-token');
   });
 
+  it('signup() does not log in while the email is unverified', async () => {
+    globalThis.fetch = mockFetch({
+      message: 'Check your inbox to verify your email before logging in',
+      user: { id: 6, username: 'Pending', email: 'pending@ufl.edu' },
+    });
+
+    await expect(service.signup('Pending', 'pending@ufl.edu', 'Password1', 'Password1')).resolves.toBe(false);
+
+    expect(service.isLoggedIn()).toBe(false);
+    expect(localStorage.getItem('token')).toBeNull();
+  });
+
   it('signup() throws when backend returns 409 Conflict (duplicate email)', async () => {
     // Matches: writeJSON(409, { "error": "Username or email already exists" })
     globalThis.fetch = mockFetch({ error: 'Username or email already exists' }, false);
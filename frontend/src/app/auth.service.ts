@@ -1,5 +1,6 @@
 import { Injectable, signal, computed, inject } from '@angular/core';
 import { Router } from '@angular/router';
+import { SESSION_EXPIRED_EVENT, clearTokens, storeTokens } from './api';
 
 export interface User {
   id: string;
@@ -11,7 +12,9 @@ export interface User {
 }
 
 interface AuthResponse {
-  token: string;
+  token?: string;
+  refresh_token?: string;
+  message?: string;
   user: { id: number; username: string; email: string };
 }
 
@@ -29,6 +32,13 @@ export class AuthService {
   readonly currentUser = this._currentUser.asReadonly();
   readonly isLoggedIn = computed(() => this._currentUser() !== null);
 
+  constructor() {
+    // apiFetch fires this when the refresh token has expired or been revoked.
+    if (typeof window !== 'undefined') {
+      window.addEventListener(SESSION_EXPIRED_EVENT, () => this.logout());
+    }
+  }
+
   private restoreUser(): User | null {
     if (typeof localStorage === 'undefined') return null;
     const raw = localStorage.getItem('user');
@@ -57,26 +67,19 @@ export class AuthService {
     }
 
     const data: AuthResponse = await res.json();
-    localStorage.setItem('token', data.token);
-
-    const user: User = {
-      id: String(data.user.id),
-      username: data.user.username,
-      email: data.user.email,
-      role: 'Student',
-      joinDate: new Date().toISOString().split('T')[0],
-      avatarUrl: null,
-    };
-    this._currentUser.set(user);
-    this.persistUser(user);
+    this.startSession(data);
   }
 
+  /**
+   * Creates an account. Resolves to true when the user is now logged in,
+   * or false when they must verify their email and then log in.
+   */
   async signup(
     username: string,
     email: string,
     password: string,
     confirmPassword: string
-  ): Promise<void> {
+  ): Promise<boolean> {
     const res = await fetch(`${API_BASE}/signup`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
@@ -89,7 +92,14 @@ export class AuthService {
     }
 
     const data: AuthResponse = await res.json();
-    localStorage.setItem('token', data.token);
+    // No token is issued until the email address has been verified.
+    if (!data.token) return false;
+    this.startSession(data);
+    return true;
+  }
+
+  private startSession(data: AuthResponse) {
+    storeTokens(data.token!, data.refresh_token);
 
     const user: User = {
       id: String(data.user.id),
@@ -112,7 +122,16 @@ export class AuthService {
   }
 
   logout() {
-    localStorage.removeItem('token');
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (refreshToken) {
+      // Best effort: end this device's session on the server too.
+      fetch(`${API_BASE}/logout`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      }).catch(() => {});
+    }
+    clearTokens();
     localStorage.removeItem('user');
     this._currentUser.set(null);
     this.router.navigate(['/login']);
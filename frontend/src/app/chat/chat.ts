@@ -71,7 +71,9 @@ export class ChatComponent implements OnInit, OnDestroy, AfterViewChecked {
   private async pollMessages() {
     const msgs = await this.friendService.getMessages(this.currentUserId, this.friendId);
     const prev = this.messages();
-    if (msgs.length !== prev.length) {
+    // The conversation is capped at its latest page, so compare the newest
+    // message rather than the count.
+    if (msgs.length !== prev.length || msgs.at(-1)?.id !== prev.at(-1)?.id) {
       this.messages.set(msgs);
       this.shouldScrollToBottom = true;
     }
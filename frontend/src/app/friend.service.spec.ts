@@ -59,7 +59,7 @@ describe('FriendService', () => {
       { friendship_id: 1, friend_id: 2, username: 'Bob' },
       { friendship_id: 2, friend_id: 3, username: 'Alice' },
     ];
-    globalThis.fetch = mockFetch(friends);
+    globalThis.fetch = mockFetch({ data: friends, next_cursor: null, limit: 100 });
     const result = await service.getFriends('1');
     expect(result).toHaveLength(2);
     expect(result[0].username).toBe('Bob');
@@ -78,7 +78,7 @@ describe('FriendService', () => {
     const requests = [
       { id: 10, requester_id: 5, username: 'Charlie', created_at: '2025-01-01T00:00:00Z' },
     ];
-    globalThis.fetch = mockFetch(requests);
+    globalThis.fetch = mockFetch({ data: requests, next_cursor: null, limit: 100 });
     const result = await service.getFriendRequests('1');
     expect(result).toHaveLength(1);
     expect(result[0].username).toBe('Charlie');
@@ -134,12 +134,12 @@ describe('FriendService', () => {
 
   // ── getMessages ──────────────────────────────────────────────────────────
 
-  it('getMessages() returns messages array on success', async () => {
+  it('getMessages() returns the newest-first page oldest first', async () => {
     const msgs = [
-      { id: 1, sender_id: 1, sender_name: 'Me', receiver_id: 2, content: 'Hello', created_at: '2025-01-01T10:00:00Z' },
       { id: 2, sender_id: 2, sender_name: 'Bob', receiver_id: 1, content: 'Hi!', created_at: '2025-01-01T10:01:00Z' },
+      { id: 1, sender_id: 1, sender_name: 'Me', receiver_id: 2, content: 'Hello', created_at: '2025-01-01T10:00:00Z' },
     ];
-    globalThis.fetch = mockFetch(msgs);
+    globalThis.fetch = mockFetch({ data: msgs, next_cursor: null, limit: 100 });
     const result = await service.getMessages('1', 2);
     expect(result).toHaveLength(2);
     expect(result[0].content).toBe('Hello');
@@ -1,4 +1,5 @@
 import { Injectable } from '@angular/core';
+import { apiFetch } from './api';
 
 const API = 'http://localhost:8080/api';
 
@@ -30,7 +31,7 @@ export class FriendService {
   async sendFriendRequest(requesterId: string, receiverUsername: string): Promise<{ status: string }> {
     let res: Response;
     try {
-      res = await fetch(`${API}/friends/request`, {
+      res = await apiFetch(`${API}/friends/request`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ requester_id: parseInt(requesterId, 10), receiver_username: receiverUsername }),
@@ -46,20 +47,30 @@ export class FriendService {
     return data;
   }
 
+  /** Follows next_cursor through every page of a paged list endpoint. */
+  private async fetchAllPages<T>(url: string): Promise<T[]> {
+    const rows: T[] = [];
+    let cursor = '';
+    do {
+      const res = await apiFetch(`${url}&cursor=${encodeURIComponent(cursor)}`);
+      if (!res.ok) return rows;
+      const page = await res.json();
+      rows.push(...(Array.isArray(page.data) ? page.data : []));
+      cursor = page.next_cursor || '';
+    } while (cursor);
+    return rows;
+  }
+
   async getFriends(userId: string): Promise<Friend[]> {
-    const res = await fetch(`${API}/friends?user_id=${userId}`);
-    if (!res.ok) return [];
-    return res.json();
+    return this.fetchAllPages<Friend>(`${API}/friends?user_id=${userId}&limit=100`);
   }
 
   async getFriendRequests(userId: string): Promise<FriendRequest[]> {
-    const res = await fetch(`${API}/friends/requests?user_id=${userId}`);
-    if (!res.ok) return [];
-    return res.json();
+    return this.fetchAllPages<FriendRequest>(`${API}/friends/requests?user_id=${userId}&limit=100`);
   }
 
   async acceptRequest(friendshipId: number, userId: string): Promise<void> {
-    await fetch(`${API}/friends/accept`, {
+    await apiFetch(`${API}/friends/accept`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ friendship_id: friendshipId, user_id: parseInt(userId, 10) }),
@@ -67,7 +78,7 @@ export class FriendService {
   }
 
   async declineRequest(friendshipId: number, userId: string): Promise<void> {
-    await fetch(`${API}/friends/decline`, {
+    await apiFetch(`${API}/friends/decline`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ friendship_id: friendshipId, user_id: parseInt(userId, 10) }),
@@ -75,22 +86,23 @@ export class FriendService {
   }
 
   async removeFriend(userId: string, friendId: number): Promise<void> {
-    await fetch(`${API}/friends/remove`, {
+    await apiFetch(`${API}/friends/remove`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ friendship_id: friendId, user_id: parseInt(userId, 10) }),
     });
   }
 
+  /** Returns the latest messages of a conversation, oldest first. */
   async getMessages(user1: string, user2: number): Promise<DirectMessage[]> {
-    const res = await fetch(`${API}/dm?user1=${user1}&user2=${user2}`);
+    const res = await apiFetch(`${API}/dm?user1=${user1}&user2=${user2}&limit=100`);
     if (!res.ok) return [];
-    const data = await res.json();
-    return Array.isArray(data) ? data : [];
+    const page = await res.json();
+    return Array.isArray(page?.data) ? [...page.data].reverse() : [];
   }
 
   async sendMessage(senderId: string, receiverId: number, content: string): Promise<void> {
-    await fetch(`${API}/dm`, {
+    await apiFetch(`${API}/dm`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ sender_id: parseInt(senderId, 10), receiver_id: receiverId, content }),
@@ -1,6 +1,6 @@
 import { Component, signal, inject } from '@angular/core';
 import { FormsModule } from '@angular/forms';
-import { Router, RouterLink } from '@angular/router';
+import { ActivatedRoute, Router, RouterLink } from '@angular/router';
 import { AuthService } from '../auth.service';
 
 @Component({
@@ -12,12 +12,19 @@ import { AuthService } from '../auth.service';
 })
 export class LoginComponent {
   private authService = inject(AuthService);
+  private route = inject(ActivatedRoute);
 
   email = signal('');
   password = signal('');
   showPassword = signal(false);
   errorMessage = signal('');
   isLoading = signal(false);
+  // Set after signing up while email verification is required.
+  noticeMessage = signal(
+    this.route.snapshot.queryParamMap.get('verify') === 'sent'
+      ? 'Account created. Check your inbox to verify your email, then log in.'
+      : ''
+  );
 
   constructor(public router: Router) {}
 
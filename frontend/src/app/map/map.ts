@@ -14,6 +14,7 @@ import { SightingService, Sighting, CATEGORY_COLORS, SPECIES_BY_CATEGORY, Leader
 import { AuthService } from '../auth.service';
 import { UploadService } from '../upload.service';
 import { FriendService } from '../friend.service';
+import { apiFetch } from '../api';
 import type * as L from 'leaflet';
 
 // Module-level reference survives HMR cycles
@@ -836,9 +837,9 @@ export class MapComponent implements AfterViewInit, OnDestroy {
   async loadComments(sightingId: string) {
     this.isLoadingComments.set(true);
     try {
-      const res = await fetch(`http://localhost:8080/api/sightings/${sightingId}/messages`);
-      const data = await res.json();
-      this.comments.set(Array.isArray(data) ? data : []);
+      const res = await apiFetch(`http://localhost:8080/api/sightings/${sightingId}/messages?limit=100`);
+      const page = await res.json();
+      this.comments.set(Array.isArray(page?.data) ? page.data : []);
     } catch {
       this.comments.set([]);
     } finally {
@@ -863,7 +864,7 @@ export class MapComponent implements AfterViewInit, OnDestroy {
 
     this.isSubmittingComment.set(true);
     try {
-      await fetch(`http://localhost:8080/api/sightings/${s.id}/messages`, {
+      await apiFetch(`http://localhost:8080/api/sightings/${s.id}/messages`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
@@ -886,7 +887,7 @@ export class MapComponent implements AfterViewInit, OnDestroy {
 
   async deleteComment(commentId: number) {
     try {
-      await fetch(`http://localhost:8080/api/messages/${commentId}`, { method: 'DELETE' });
+      await apiFetch(`http://localhost:8080/api/messages/${commentId}`, { method: 'DELETE' });
       this.comments.update(list => list.filter(c => c.ID !== commentId));
     } catch (err) {
       console.error('Failed to delete comment:', err);
@@ -105,7 +105,7 @@ describe('SightingService', () => {
       makeBackendSighting({ id: 3, category: 'Bird', species: 'Osprey' }),
     ];
 
-    globalThis.fetch = mockFetch(rows);
+    globalThis.fetch = mockFetch({ data: rows, next_cursor: null, limit: 100 });
     await service.loadAll();
 
     const grouped = service.groupedByCategory();
@@ -127,7 +127,7 @@ describe('SightingService', () => {
       username: 'BiologyStudent',
     });
 
-    globalThis.fetch = mockFetch([row]);
+    globalThis.fetch = mockFetch({ data: [row], next_cursor: null, limit: 100 });
     await service.loadAll();
 
     const [s] = service.sightings();
@@ -139,6 +139,24 @@ describe('SightingService', () => {
     expect(s.quantity).toBe(3);
   });
 
+  it('loadAll() follows next_cursor through every page', async () => {
+    const fetchMock = vi.fn()
+      .mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: [makeBackendSighting({ id: 1 })], next_cursor: 'abc', limit: 100 }),
+      })
+      .mockResolvedValueOnce({
+        ok: true,
+        json: () => Promise.resolve({ data: [makeBackendSighting({ id: 2 })], next_cursor: null, limit: 100 }),
+      });
+    globalThis.fetch = fetchMock;
+    await service.loadAll('Bird');
+
+    expect(service.sightings().map((s) => s.id)).toEqual(['1', '2']);
+    expect(fetchMock.mock.calls[1][0]).toContain('category=Bird');
+    expect(fetchMock.mock.calls[1][0]).toContain('cursor=abc');
+  });
+
   // ── add() ────────────────────────────────────────────────────────────────
 
   it('add() uses the server-assigned integer id from POST response', async () => {
@@ -312,14 +330,17 @@ describe('SightingService', () => {
 
   // ── getChannelMessages() ──────────────────────────────────────────────────
 
-  it('getChannelMessages() returns message array', async () => {
-    const msgs = [{ id: 1, channel_id: 1, sender_name: 'Alice', content: 'Hi' }];
-    globalThis.fetch = mockFetch(msgs);
+  it('getChannelMessages() returns the page oldest first', async () => {
+    const msgs = [
+      { id: 2, channel_id: 1, sender_name: 'Bob', content: 'Hello' },
+      { id: 1, channel_id: 1, sender_name: 'Alice', content: 'Hi' },
+    ];
+    globalThis.fetch = mockFetch({ data: msgs, next_cursor: null, limit: 100 });
     const result = await service.getChannelMessages(1);
-    expect(result).toEqual(msgs);
+    expect(result.map((m) => m.id)).toEqual([1, 2]);
   });
 
-  it('getChannelMessages() returns empty array for non-array response', async () => {
+  it('getChannelMessages() returns empty array for a response without data', async () => {
     globalThis.fetch = mockFetch(null);
     const result = await service.getChannelMessages(1);
     expect(result).toEqual([]);
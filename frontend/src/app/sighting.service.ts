@@ -1,4 +1,5 @@
 import { Injectable, signal, computed } from '@angular/core';
+import { apiFetch } from './api';
 
 export interface Sighting {
   id: string;
@@ -431,10 +432,18 @@ export class SightingService {
 
   async loadAll(category?: string): Promise<void> {
     try {
-      const url = category ? `${API_BASE}?category=${encodeURIComponent(category)}` : API_BASE;
-      const res = await fetch(url);
-      if (!res.ok) throw new Error('Failed to load sightings');
-      const data: any[] = await res.json();
+      // The list is paged; follow next_cursor so the map gets every sighting.
+      let url = `${API_BASE}?limit=100`;
+      if (category) url += `&category=${encodeURIComponent(category)}`;
+      const data: any[] = [];
+      let cursor = '';
+      do {
+        const res = await apiFetch(`${url}&cursor=${encodeURIComponent(cursor)}`);
+        if (!res.ok) throw new Error('Failed to load sightings');
+        const page = await res.json();
+        data.push(...(Array.isArray(page?.data) ? page.data : []));
+        cursor = page?.next_cursor || '';
+      } while (cursor);
       this._loaded = true;
       const sightings: Sighting[] = data.map((row) => ({
         id: String(row.id),
@@ -461,7 +470,7 @@ export class SightingService {
 
   async add(sighting: Sighting): Promise<void> {
     try {
-      const res = await fetch(API_BASE, {
+      const res = await apiFetch(API_BASE, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
@@ -496,7 +505,7 @@ export class SightingService {
 
   async remove(id: string): Promise<void> {
     try {
-      const res = await fetch(`${API_BASE}/${id}`, { method: 'DELETE' });
+      const res = await apiFetch(`${API_BASE}/${id}`, { method: 'DELETE' });
       if (!res.ok) throw new Error('Failed to delete sighting');
     } catch (err) {
       console.error('Failed to remove sighting:', err);
@@ -510,7 +519,7 @@ export class SightingService {
 
     const merged = { ...current, ...data };
     try {
-      const res = await fetch(`${API_BASE}/${id}`, {
+      const res = await apiFetch(`${API_BASE}/${id}`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
@@ -544,14 +553,14 @@ export class SightingService {
     const url = userId
       ? `${API_BASE}/${sightingId}/likes?user_id=${encodeURIComponent(userId)}`
       : `${API_BASE}/${sightingId}/likes`;
-    const res = await fetch(url);
+    const res = await apiFetch(url);
     if (!res.ok) return { count: 0, likedByMe: false };
     const data = await res.json();
     return { count: data.count || 0, likedByMe: !!data.liked_by_me };
   }
 
   async toggleLike(sightingId: string, userId: string): Promise<{ liked: boolean; count: number }> {
-    const res = await fetch(`${API_BASE}/${sightingId}/like`, {
+    const res = await apiFetch(`${API_BASE}/${sightingId}/like`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ user_id: parseInt(userId, 10) }),
@@ -566,7 +575,7 @@ export class SightingService {
   }
 
   async getStats(): Promise<{ totalSightings: number; totalUsers: number; byCategory: Record<string, number> }> {
-    const res = await fetch('http://localhost:8080/api/stats');
+    const res = await apiFetch('http://localhost:8080/api/stats');
     if (!res.ok) throw new Error('Failed to load stats');
     const data = await res.json();
     return {
@@ -581,10 +590,12 @@ export class SightingService {
       lat: String(lat),
       lng: String(lng),
       radius: String(radius),
+      limit: '100',
     });
-    const res = await fetch(`${API_BASE}/nearby?${params}`);
+    const res = await apiFetch(`${API_BASE}/nearby?${params}`);
     if (!res.ok) return [];
-    const data: any[] = await res.json();
+    const page = await res.json();
+    const data: any[] = Array.isArray(page?.data) ? page.data : [];
     return data.map((row) => ({
       id: String(row.id),
       userId: String(row.user_id || ''),
@@ -607,14 +618,14 @@ export class SightingService {
 
   async getLeaderboard(sort: string = 'sightings', period: string = 'all'): Promise<LeaderboardEntry[]> {
     const params = new URLSearchParams({ sort, period });
-    const res = await fetch(`${API_ROOT}/leaderboard?${params}`);
+    const res = await apiFetch(`${API_ROOT}/leaderboard?${params}`);
     if (!res.ok) return [];
     const data = await res.json();
     return data.entries || data || [];
   }
 
   async createReport(sightingId: string, reporterId: number, reason: string): Promise<{ success: boolean; message: string }> {
-    const res = await fetch(`${API_ROOT}/reports`, {
+    const res = await apiFetch(`${API_ROOT}/reports`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ sighting_id: parseInt(sightingId, 10), reporter_id: reporterId, reason }),
@@ -625,13 +636,13 @@ export class SightingService {
   }
 
   async getSubscriptions(userId: string): Promise<Subscription[]> {
-    const res = await fetch(`${API_ROOT}/subscriptions?user_id=${userId}`);
+    const res = await apiFetch(`${API_ROOT}/subscriptions?user_id=${userId}`);
     if (!res.ok) return [];
     return await res.json();
   }
 
   async createSubscription(userId: number, type: string, value: string): Promise<{ success: boolean; id?: number }> {
-    const res = await fetch(`${API_ROOT}/subscriptions`, {
+    const res = await apiFetch(`${API_ROOT}/subscriptions`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ user_id: userId, type, value }),
@@ -642,32 +653,32 @@ export class SightingService {
   }
 
   async deleteSubscription(id: number): Promise<boolean> {
-    const res = await fetch(`${API_ROOT}/subscriptions/${id}`, { method: 'DELETE' });
+    const res = await apiFetch(`${API_ROOT}/subscriptions/${id}`, { method: 'DELETE' });
     return res.ok;
   }
 
   async getNotifications(userId: string, unreadOnly: boolean = false): Promise<Notification[]> {
     const params = new URLSearchParams({ user_id: userId });
     if (unreadOnly) params.set('unread', 'true');
-    const res = await fetch(`${API_ROOT}/notifications?${params}`);
+    const res = await apiFetch(`${API_ROOT}/notifications?${params}`);
     if (!res.ok) return [];
     const data = await res.json();
     return Array.isArray(data) ? data : [];
   }
 
   async markNotificationRead(id: number): Promise<boolean> {
-    const res = await fetch(`${API_ROOT}/notifications/${id}/read`, { method: 'PUT' });
+    const res = await apiFetch(`${API_ROOT}/notifications/${id}/read`, { method: 'PUT' });
     return res.ok;
   }
 
   async getChannels(): Promise<Channel[]> {
-    const res = await fetch(`${API_ROOT}/channels`);
+    const res = await apiFetch(`${API_ROOT}/channels`);
     if (!res.ok) return [];
     return await res.json();
   }
 
   async createChannel(name: string, description: string, creatorId: number): Promise<{ success: boolean; id?: number }> {
-    const res = await fetch(`${API_ROOT}/channels`, {
+    const res = await apiFetch(`${API_ROOT}/channels`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ name, description, creator_id: creatorId }),
@@ -677,15 +688,16 @@ export class SightingService {
     return { success: true, id: data.id };
   }
 
+  /** Returns the latest messages of a channel, oldest first. */
   async getChannelMessages(channelId: number): Promise<ChannelMessage[]> {
-    const res = await fetch(`${API_ROOT}/channels/${channelId}/messages`);
+    const res = await apiFetch(`${API_ROOT}/channels/${channelId}/messages?limit=100`);
     if (!res.ok) return [];
-    const data = await res.json();
-    return Array.isArray(data) ? data : [];
+    const page = await res.json();
+    return Array.isArray(page?.data) ? [...page.data].reverse() : [];
   }
 
   async sendChannelMessage(channelId: number, senderId: number, senderName: string, content: string): Promise<boolean> {
-    const res = await fetch(`${API_ROOT}/channels/${channelId}/messages`, {
+    const res = await apiFetch(`${API_ROOT}/channels/${channelId}/messages`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ sender_id: senderId, sender_name: senderName, content }),
@@ -694,7 +706,7 @@ export class SightingService {
   }
 
   async changePassword(userId: number, oldPassword: string, newPassword: string): Promise<{ success: boolean; message: string }> {
-    const res = await fetch(`${API_ROOT}/users/password`, {
+    const res = await apiFetch(`${API_ROOT}/users/password`, {
       method: 'PUT',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ user_id: userId, old_password: oldPassword, new_password: newPassword }),
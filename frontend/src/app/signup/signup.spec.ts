@@ -5,7 +5,7 @@ import { SignupComponent } from './signup';
 import { AuthService } from '../auth.service';
 
 const authServiceStub = {
-  signup: vi.fn().mockResolvedValue(undefined),
+  signup: vi.fn().mockResolvedValue(true),
 };
 
 describe('SignupComponent', () => {
@@ -16,7 +16,7 @@ describe('SignupComponent', () => {
     vi.spyOn(console, 'error').mockImplementation(() => {});
     (authServiceStub.signup as ReturnType<typeof vi.fn>)
       .mockClear()
-      .mockResolvedValue(undefined);
+      .mockResolvedValue(true);
 
     await TestBed.configureTestingModule({
       imports: [SignupComponent],
@@ -161,6 +161,16 @@ describe('SignupComponent', () => {
     expect(router.navigate).toHaveBeenCalledWith(['/home']);
   });
 
+  it('onSubmit() sends the user to log in when email verification is required', async () => {
+    (authServiceStub.signup as ReturnType<typeof vi.fn>).mockResolvedValue(false);
+    component.username.set('NewGator');
+    component.email.set('new@ufl.edu');
+    component.password.set('Password1');
+    component.confirmPassword.set('Password1');
+    await component.onSubmit();
+    expect(router.navigate).toHaveBeenCalledWith(['/login'], { queryParams: { verify: 'sent' } });
+  });
+
   // ── onSubmit() error path ────────────────────────────────────────────────
 
   it('onSubmit() shows error message from rejected signup', async () => {
@@ -72,13 +72,17 @@ export class SignupComponent {
     this.isLoading.set(true);
 
     try {
-      await this.authService.signup(
+      const loggedIn = await this.authService.signup(
         usernameValue,
         emailValue,
         this.password(),
         this.confirmPassword()
       );
-      this.router.navigate(['/home']);
+      if (loggedIn) {
+        this.router.navigate(['/home']);
+      } else {
+        this.router.navigate(['/login'], { queryParams: { verify: 'sent' } });
+      }
     } catch (err: any) {
       this.errorMessage.set(err.message || 'Signup failed. Please try again.');
     } finally {